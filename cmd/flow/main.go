@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -13,9 +15,10 @@ import (
 	"time"
 
 	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miaccount"
-	"github.com/zeusro/miflow/internal/miioservice"
 	"github.com/zeusro/miflow/internal/miiocommand"
+	"github.com/zeusro/miflow/internal/miioservice"
 	"github.com/zeusro/miflow/internal/minaservice"
 )
 
@@ -26,8 +29,9 @@ import (
 // Currently supported types:
 //   - "tts"       : 小爱播报一段文字
 //   - "play_url"  : 播放一个音频 URL
-//   - "miio"      : 发送一条 miio/miot 文本命令（等价于 `m` 的参数）
+//   - "miio"      : 发送一条 miio/miot 文本命令（等价于 `m` 的参数），可用 room/model 对房间内多个设备执行
 //   - "delay"     : 等待一段时间（毫秒）
+//   - "scene"     : 执行一个米家手动场景（ID 或名称）
 type FlowStepType string
 
 const (
@@ -35,6 +39,7 @@ const (
 	StepTypePlayURL FlowStepType = "play_url"
 	StepTypeMiIO    FlowStepType = "miio"
 	StepTypeDelay   FlowStepType = "delay"
+	StepTypeScene   FlowStepType = "scene"
 
 	defaultPrefix = "flow "
 )
@@ -43,10 +48,13 @@ const (
 type FlowStep struct {
 	Type       FlowStepType `json:"type"`
 	Label      string       `json:"label,omitempty"`       // 简要说明，展示在 UI 上
-	Device     string       `json:"device,omitempty"`      // 可选，覆盖环境变量 MI_DID
+	Device     string       `json:"device,omitempty"`      // 可选，覆盖环境变量 MI_DID；@群组名 时对每个成员执行
+	Room       string       `json:"room,omitempty"`        // 用于 miio：对该房间内设备逐个执行，如 "客厅"
+	Model      string       `json:"model,omitempty"`       // 用于 miio：按型号关键字筛选房间内设备，如 "light"
 	Text       string       `json:"text,omitempty"`        // 用于 TTS
 	URL        string       `json:"url,omitempty"`         // 用于 play_url
 	MiIOText   string       `json:"miio_text,omitempty"`   // 用于 miio：等价于 `m` 的参数，如 "1,1-2=#60"
+	Scene      string       `json:"scene,omitempty"`       // 用于 scene：场景 ID 或名称，如 "回家"
 	DurationMS int          `json:"duration_ms,omitempty"` // 用于 delay
 }
 
@@ -206,8 +214,17 @@ func sanitizeID(s string) string {
 type app struct {
 	store      *FlowStore
 	mina       *minaservice.Service
-	miio       *miioservice.Service
+	devices    *device.API
 	defaultDID string
+
+	runsMu sync.Mutex
+	runs   map[string]*flowRun
+}
+
+// flowRun is one execution of a flow; the pointer identifies the run so a
+// finished run does not remove a newer run of the same flow.
+type flowRun struct {
+	cancel context.CancelFunc
 }
 
 func main() {
@@ -231,23 +248,25 @@ func main() {
 	}
 
 	var (
-		minaSvc *minaservice.Service
-		miioSvc *miioservice.Service
+		minaSvc   *minaservice.Service
+		deviceAPI *device.API
 	)
 	if token != nil && token.IsValid() {
-		var err error
-		miioSvc, err = miioservice.New(token, tokenPath)
+		miioSvc, err := miioservice.New(token, tokenPath)
 		if err != nil {
 			log.Printf("MiIO 初始化失败: %v", err)
 		} else {
 			minaSvc = minaservice.NewWithMinaAPI(miioSvc, token, tokenPath)
+			if deviceAPI, err = device.NewAPIFromConfig(miioSvc); err != nil {
+				log.Fatalf("设备通道配置错误: %v", err)
+			}
 		}
 	}
 
 	a := &app{
 		store:      store,
 		mina:       minaSvc,
-		miio:       miioSvc,
+		devices:    deviceAPI,
 		defaultDID: did,
 	}
 
@@ -256,7 +275,7 @@ func main() {
 	mux.HandleFunc("/", a.handleIndex)
 	// RESTful API
 	mux.HandleFunc("/api/flows", a.handleFlows)
-	mux.HandleFunc("/api/flows/", a.handleFlowByID) // /api/flows/{id} 和 /api/flows/{id}/run
+	mux.HandleFunc("/api/flows/", a.handleFlowByID) // /api/flows/{id}、/api/flows/{id}/run 和 /api/flows/{id}/cancel
 
 	log.Printf("Flow server listening on %s\n", *addr)
 	log.Fatal(http.ListenAndServe(*addr, logRequest(mux)))
@@ -314,7 +333,7 @@ func (a *app) handleFlows(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// /api/flows/{id} or /api/flows/{id}/run
+// /api/flows/{id}, /api/flows/{id}/run or /api/flows/{id}/cancel
 func (a *app) handleFlowByID(w http.ResponseWriter, r *http.Request) {
 	trimmed := strings.TrimPrefix(r.URL.Path, "/api/flows/")
 	if trimmed == "" {
@@ -370,22 +389,65 @@ func (a *app) handleFlowByID(w http.ResponseWriter, r *http.Request) {
 		go a.runFlow(f)
 		w.WriteHeader(http.StatusAccepted)
 		fmt.Fprintf(w, `{"status":"started","id":%q}`, f.ID)
+	case "cancel":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !a.cancelFlow(id) {
+			http.Error(w, "flow not running", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "cancelled", "id": id})
 	default:
 		http.NotFound(w, r)
 	}
 }
 
-// runFlow executes a flow sequentially.
+// runFlow executes a flow sequentially until it finishes or is cancelled.
 func (a *app) runFlow(f Flow) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run := &flowRun{cancel: cancel}
+	a.runsMu.Lock()
+	if a.runs == nil {
+		a.runs = make(map[string]*flowRun)
+	}
+	a.runs[f.ID] = run
+	a.runsMu.Unlock()
+	defer func() {
+		a.runsMu.Lock()
+		if a.runs[f.ID] == run {
+			delete(a.runs, f.ID)
+		}
+		a.runsMu.Unlock()
+	}()
+
 	log.Printf("Running flow %s (%s) with %d steps\n", f.ID, f.Name, len(f.Steps))
 	for i, step := range f.Steps {
-		if err := a.runStep(step); err != nil {
+		if ctx.Err() != nil {
+			log.Printf("flow %s cancelled before step %d", f.ID, i)
+			return
+		}
+		if err := a.runStep(ctx, step); err != nil {
 			log.Printf("flow %s step %d (%s) error: %v", f.ID, i, step.Label, err)
 			// 这里简单记录错误并继续后续步骤；也可以在未来支持“出错即停止”的选项
 		}
 	}
 }
 
+// cancelFlow cancels a running flow; returns false if it is not running.
+func (a *app) cancelFlow(id string) bool {
+	a.runsMu.Lock()
+	run, ok := a.runs[id]
+	a.runsMu.Unlock()
+	if ok {
+		run.cancel()
+	}
+	return ok
+}
+
 func (a *app) resolveDID(step FlowStep) string {
 	if strings.TrimSpace(step.Device) != "" {
 		return step.Device
@@ -393,14 +455,23 @@ func (a *app) resolveDID(step FlowStep) string {
 	return a.defaultDID
 }
 
-func (a *app) runStep(step FlowStep) error {
+func (a *app) runStep(ctx context.Context, step FlowStep) error {
+	if name, ok := device.GroupRef(a.resolveDID(step)); ok && (step.Type == StepTypeTTS || step.Type == StepTypePlayURL) {
+		return a.runGroupStep(ctx, name, step)
+	}
 	switch step.Type {
 	case StepTypeDelay:
 		if step.DurationMS <= 0 {
 			return nil
 		}
-		time.Sleep(time.Duration(step.DurationMS) * time.Millisecond)
-		return nil
+		t := time.NewTimer(time.Duration(step.DurationMS) * time.Millisecond)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	case StepTypeTTS:
 		if a.mina == nil {
 			return fmt.Errorf("mina service not initialized (run 'm login' first)")
@@ -409,11 +480,11 @@ func (a *app) runStep(step FlowStep) error {
 		if did == "" {
 			return fmt.Errorf("no device ID configured for TTS step")
 		}
-		deviceID, err := a.mina.GetMinaDeviceID(did)
+		deviceID, err := a.mina.GetMinaDeviceIDContext(ctx, did)
 		if err != nil {
 			return err
 		}
-		_, err = a.mina.TextToSpeech(deviceID, step.Text)
+		_, err = a.mina.TextToSpeechContext(ctx, deviceID, step.Text)
 		return err
 	case StepTypePlayURL:
 		if a.mina == nil {
@@ -423,32 +494,75 @@ func (a *app) runStep(step FlowStep) error {
 		if did == "" {
 			return fmt.Errorf("no device ID configured for play_url step")
 		}
-		deviceID, err := a.mina.GetMinaDeviceID(did)
+		deviceID, err := a.mina.GetMinaDeviceIDContext(ctx, did)
 		if err != nil {
 			return err
 		}
-		_, err = a.mina.PlayByURL(deviceID, step.URL, 2)
+		_, err = a.mina.PlayByURLContext(ctx, deviceID, step.URL, 2)
 		return err
 	case StepTypeMiIO:
-		if a.miio == nil {
+		if a.devices == nil {
 			return fmt.Errorf("miio service not initialized (run 'm login' first)")
 		}
 		text := strings.TrimSpace(step.MiIOText)
 		if text == "" {
 			return nil
 		}
+		if step.Room != "" || step.Model != "" {
+			return a.runMiIOEach(ctx, step, text)
+		}
 		did := a.resolveDID(step)
 		if did == "" {
 			// 对于 list/spec 等命令可以为空，保持与 m 一致的行为
 			did = ""
 		}
-		_, err := miiocommand.Run(a.miio, did, text, defaultPrefix)
+		_, err := miiocommand.RunContext(ctx, a.devices, did, text, defaultPrefix)
+		return err
+	case StepTypeScene:
+		if a.devices == nil {
+			return fmt.Errorf("miio service not initialized (run 'm login' first)")
+		}
+		if strings.TrimSpace(step.Scene) == "" {
+			return fmt.Errorf("scene step requires scene id or name")
+		}
+		_, err := a.devices.RunSceneContext(ctx, step.Scene)
 		return err
 	default:
 		return fmt.Errorf("unsupported step type: %s", step.Type)
 	}
 }
 
+// runGroupStep 对群组成员并发执行同一步骤（Device 替换为成员 did）；miio 步骤由 miiocommand 处理群组。
+func (a *app) runGroupStep(ctx context.Context, name string, step FlowStep) error {
+	if a.devices == nil {
+		return fmt.Errorf("miio service not initialized (run 'm login' first)")
+	}
+	_, err := a.devices.FanOutContext(ctx, name, func(ctx context.Context, d *device.Device) (interface{}, error) {
+		s := step
+		s.Device = d.DID
+		return nil, a.runStep(ctx, s)
+	})
+	return err
+}
+
+// runMiIOEach 对 step.Room/step.Model 匹配的每个设备执行 miio 命令，如“客厅所有灯”。
+func (a *app) runMiIOEach(ctx context.Context, step FlowStep, text string) error {
+	list, err := a.devices.FindContext(ctx, device.Filter{Room: step.Room, Model: step.Model})
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no device matches room=%q model=%q", step.Room, step.Model)
+	}
+	var errs []error
+	for _, d := range list {
+		if _, err := miiocommand.RunContext(ctx, a.devices, d.DID, text, defaultPrefix); err != nil {
+			errs = append(errs, fmt.Errorf("%s(%s): %w", d.Name, d.DID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	enc := json.NewEncoder(w)
@@ -682,7 +796,7 @@ const indexHTML = `<!DOCTYPE html>
 
         const tdType = document.createElement('td');
         const sel = document.createElement('select');
-        ['delay','tts','play_url','miio'].forEach(t => {
+        ['delay','tts','play_url','miio','scene'].forEach(t => {
           const opt = document.createElement('option');
           opt.value = t;
           opt.textContent = t;
@@ -730,6 +844,12 @@ const indexHTML = `<!DOCTYPE html>
             input.value = st.miio_text || '';
             input.addEventListener('input', () => { st.miio_text = input.value; });
             cell.appendChild(input);
+          } else if (st.type === 'scene') {
+            const input = document.createElement('input');
+            input.placeholder = '米家手动场景名称或 ID，例如: 回家';
+            input.value = st.scene || '';
+            input.addEventListener('input', () => { st.scene = input.value; });
+            cell.appendChild(input);
           }
         }
         // 将 JS 字段映射到 JSON 字段名
@@ -739,6 +859,7 @@ const indexHTML = `<!DOCTYPE html>
         step.text = step.text || step.Text || '';
         step.url = step.url || step.URL || '';
         step.miio_text = step.miio_text || step.MiIOText || '';
+        step.scene = step.scene || step.Scene || '';
 
         renderParamsCell(step, tdParams);
 
@@ -862,4 +983,3 @@ const indexHTML = `<!DOCTYPE html>
   </script>
 </body>
 </html>`
-
@@ -0,0 +1,213 @@
+// Command miiot-gen - 根据 MIoT SPEC 生成 miiot/<vendor>/<category>/<model>.go 常量包与测试，
+// 并更新 miiot.Models、ctrl.Specs 与离线种子 miiot/specs/fixture.json。可在 go:generate 中使用：
+//
+//	//go:generate go run github.com/zeusro/miflow/cmd/miiot-gen -root .. -model linp.sensor_occupy.hb01
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/miioservice"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/gen"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+func main() {
+	flagModel := flag.String("model", "", "型号，多个以逗号分隔，如 linp.sensor_occupy.hb01")
+	flagAll := flag.Bool("all", false, "生成 m list 中的全部型号（需先 m login）")
+	flagSpec := flag.String("spec", "", "SPEC instance JSON 文件，仅单个型号时可用；默认从 miot-spec.org 获取")
+	flagURN := flag.String("urn", "", "指定 SPEC 类型 URN，默认按型号查找")
+	flagRoot := flag.String("root", ".", "仓库根目录")
+	flagForce := flag.Bool("force", false, "覆盖手写的型号文件（首行不是生成标记）")
+	flagDry := flag.Bool("n", false, "只打印生成内容，不写文件")
+	flagLocale := flag.String("locale", config.Get().MiIO.SpecLocale, "注释附加的翻译语言：zh_CN、zh_TW、en（不附加）")
+	flag.Parse()
+
+	var models []string
+	for _, m := range strings.Split(*flagModel, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	if *flagAll {
+		listed, err := listModels()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		models = append(models, listed...)
+	}
+	if len(models) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: miiot-gen -model <model>[,<model>...] | -all [-spec file.json] [-locale zh_CN] [-root dir] [-force] [-n]")
+		os.Exit(2)
+	}
+	if (*flagSpec != "" || *flagURN != "") && len(models) > 1 {
+		fmt.Fprintln(os.Stderr, "-spec and -urn require a single model")
+		os.Exit(2)
+	}
+	failed := 0
+	for _, model := range models {
+		if err := generate(*flagRoot, model, *flagURN, *flagSpec, *flagLocale, *flagForce, *flagDry); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", model, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func generate(root, model, urn, specFile, locale string, force, dry bool) error {
+	raw, urn, err := loadInstance(model, urn, specFile)
+	if err != nil {
+		return err
+	}
+	spec, err := device.ParseModelSpec(raw)
+	if err != nil {
+		return err
+	}
+	// 翻译表同时写入种子；获取失败时注释保持英文
+	tr, err := specs.Translate(urn)
+	if err != nil && specs.NormalizeLocale(locale) != "en" {
+		fmt.Fprintf(os.Stderr, "%s: %v, comments stay English\n", model, err)
+	}
+	if specs.NormalizeLocale(locale) != "en" {
+		spec.Localize(tr, locale)
+	}
+	cs, err := ctrl.SpecFromInstance(raw)
+	if err != nil {
+		return err
+	}
+	m, err := gen.New(model, urn, spec, cs)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, m.Path())
+	pkg := gen.PackageName(filepath.Dir(path))
+	src, err := m.Source(pkg)
+	if err != nil {
+		return err
+	}
+	test, err := m.TestSource(pkg)
+	if err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(path); err == nil && !strings.HasPrefix(string(old), gen.Header) && !force && !dry {
+		return fmt.Errorf("%s is hand-written, use -force to overwrite", path)
+	}
+	// 先在内存中生成全部输出，所有修改都成功后再写文件，避免留下半生成的目录
+	files := []output{{path, src}}
+	if test != nil {
+		files = append(files, output{filepath.Join(root, m.TestPath()), test})
+	}
+	for _, u := range []struct {
+		path string
+		fn   func([]byte) ([]byte, bool, error)
+	}{
+		{filepath.Join(root, "miiot", "registry.go"), func(b []byte) ([]byte, bool, error) { return gen.AddModel(b, model) }},
+		{filepath.Join(root, "miiot", "ctrl", "constants.go"), func(b []byte) ([]byte, bool, error) { return gen.AddCtrlSpec(b, m) }},
+		{filepath.Join(root, "miiot", "specs", "fixture.json"), func(b []byte) ([]byte, bool, error) { return gen.AddSeed(b, m, raw, tr) }},
+	} {
+		old, err := os.ReadFile(u.path)
+		if err != nil {
+			return err
+		}
+		out, changed, err := u.fn(old)
+		if err != nil {
+			return fmt.Errorf("%s: %w", u.path, err)
+		}
+		if changed {
+			files = append(files, output{u.path, out})
+		}
+	}
+	if dry {
+		fmt.Printf("// %s\n%s\n", m.Path(), src)
+		if test != nil {
+			fmt.Printf("// %s\n%s\n// ctrl.Specs\n%s", m.TestPath(), test, m.CtrlEntry())
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.path, f.data, 0644); err != nil {
+			return err
+		}
+	}
+	fmt.Println(path)
+	return nil
+}
+
+// output 为待写入的文件。
+type output struct {
+	path string
+	data []byte
+}
+
+// loadInstance 读取 SPEC：指定文件时从文件读取，否则按 URN（或型号查找 URN）从 miot-spec.org 获取。
+func loadInstance(model, urn, specFile string) (map[string]interface{}, string, error) {
+	var raw map[string]interface{}
+	if specFile != "" {
+		data, err := os.ReadFile(specFile)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, "", fmt.Errorf("%s: %w", specFile, err)
+		}
+		if urn == "" {
+			urn, _ = raw["type"].(string)
+		}
+		return raw, urn, nil
+	}
+	if urn == "" {
+		var err error
+		if urn, err = specs.URNWithScrape(model); err != nil {
+			return nil, "", err
+		}
+	}
+	raw, err := specs.FetchInstance(urn)
+	return raw, urn, err
+}
+
+func listModels() ([]string, error) {
+	cfg := config.Get()
+	tokenPath := cfg.TokenPath
+	if tokenPath == "" {
+		tokenPath = os.ExpandEnv("$HOME/.mi.token")
+	}
+	store := &miaccount.TokenStore{Path: tokenPath}
+	token := store.LoadOAuth()
+	if token == nil || !token.IsValid() {
+		return nil, fmt.Errorf("no valid token, run 'm login' first")
+	}
+	ioSvc, err := miioservice.New(token, tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	devs, err := device.NewAPI(ioSvc).List("", false, 0)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var models []string
+	for _, d := range devs {
+		if d != nil && d.Model != "" && !seen[d.Model] {
+			seen[d.Model] = true
+			models = append(models, d.Model)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
@@ -57,9 +57,45 @@ func main() {
 		group.GET("/", func(r *ghttp.Request) { api.DevicesList(a, r) })
 		group.GET("/{id}", func(r *ghttp.Request) { api.DeviceGet(a, r) })
 		group.GET("/{id}/spec", func(r *ghttp.Request) { api.DeviceSpec(a, r) })
+		group.GET("/{id}/props", func(r *ghttp.Request) { api.DeviceProps(a, r) })
+		group.GET("/{id}/capabilities", func(r *ghttp.Request) { api.DeviceCapabilities(a, r) })
+		group.GET("/{id}/vacuum", func(r *ghttp.Request) { api.DeviceVacuum(a, r) })
+		group.POST("/{id}/vacuum", func(r *ghttp.Request) { api.DeviceVacuum(a, r) })
 		group.POST("/{id}/control", func(r *ghttp.Request) { api.DeviceControl(a, r) })
 	})
 
+	// API: homes and rooms
+	s.Group("/api/homes", func(group *ghttp.RouterGroup) {
+		group.GET("/", func(r *ghttp.Request) { api.HomesList(a, r) })
+	})
+
+	// API: device groups (use @name as device id to fan out)
+	s.Group("/api/groups", func(group *ghttp.RouterGroup) {
+		group.GET("/", func(r *ghttp.Request) { api.GroupsList(a, r) })
+		group.POST("/", func(r *ghttp.Request) { api.GroupSave(a, r) })
+		group.GET("/{name}/members", func(r *ghttp.Request) { api.GroupMembers(a, r) })
+		group.DELETE("/{name}", func(r *ghttp.Request) { api.GroupDelete(a, r) })
+	})
+
+	// API: device registry
+	s.Group("/api/registry", func(group *ghttp.RouterGroup) {
+		group.GET("/", func(r *ghttp.Request) { api.RegistryList(a, r) })
+		group.GET("/history", func(r *ghttp.Request) { api.RegistryHistory(a, r) })
+		group.POST("/refresh", func(r *ghttp.Request) { api.RegistryRefresh(a, r) })
+	})
+
+	// API: scenes
+	s.Group("/api/scenes", func(group *ghttp.RouterGroup) {
+		group.GET("/", func(r *ghttp.Request) { api.ScenesList(a, r) })
+		group.POST("/{id}/run", func(r *ghttp.Request) { api.SceneRun(a, r) })
+	})
+
+	// API: LAN discovery
+	s.Group("/api/discovery", func(group *ghttp.RouterGroup) {
+		group.GET("/", func(r *ghttp.Request) { api.DiscoveryList(a, r) })
+		group.POST("/scan", func(r *ghttp.Request) { api.DiscoveryScan(a, r) })
+	})
+
 	// API: workflows (DDD - workflow domain)
 	s.Group("/api/workflows", func(group *ghttp.RouterGroup) {
 		group.GET("/", func(r *ghttp.Request) { api.WorkflowsList(a, r) })
@@ -68,6 +104,7 @@ func main() {
 		group.PUT("/{id}", func(r *ghttp.Request) { api.WorkflowUpdate(a, r) })
 		group.DELETE("/{id}", func(r *ghttp.Request) { api.WorkflowDelete(a, r) })
 		group.POST("/{id}/run", func(r *ghttp.Request) { api.WorkflowRun(a, r) })
+		group.POST("/{id}/cancel", func(r *ghttp.Request) { api.WorkflowCancel(a, r) })
 	})
 
 	s.Group("/dist", func(group *ghttp.RouterGroup) {
@@ -71,9 +71,10 @@ type OAuthConfig struct {
 	ClientID    string `yaml:"client_id"`
 	RedirectURI string `yaml:"redirect_uri"`
 	CloudServer string `yaml:"cloud_server"` // cn, de, i2, ru, sg, us
-	DeviceID    string `yaml:"device_id"`   // 可选，用于 OAuth device_id
+	DeviceID    string `yaml:"device_id"`    // 可选，用于 OAuth device_id
 	APIHost     string `yaml:"api_host"`
-	TokenPath   string `yaml:"token_path"`   // API path
+	MQTTHost    string `yaml:"mqtt_host"`  // 推送 MQTT 主机，实际连接 {cloud_server}-{mqtt_host}:8883
+	TokenPath   string `yaml:"token_path"` // API path
 	AuthURL     string `yaml:"auth_url"`
 	// TokenExpireRatio 过期前多少比例时刷新，0-1
 	TokenExpireRatio float64 `yaml:"token_expire_ratio"`
@@ -82,6 +83,17 @@ type OAuthConfig struct {
 // HTTPConfig for HTTP client timeouts etc.
 type HTTPConfig struct {
 	TimeoutSeconds int `yaml:"timeout_seconds"`
+	// 云端请求重试：总尝试次数、退避起始与上限（毫秒）
+	RetryMaxAttempts int `yaml:"retry_max_attempts"`
+	RetryBaseDelayMS int `yaml:"retry_base_delay_ms"`
+	RetryMaxDelayMS  int `yaml:"retry_max_delay_ms"`
+	// 云端限速（每秒请求数，负数关闭）：按账号、按设备
+	RateLimitPerSecond       float64 `yaml:"rate_limit_per_second"`
+	RateLimitBurst           int     `yaml:"rate_limit_burst"`
+	DeviceRateLimitPerSecond float64 `yaml:"device_rate_limit_per_second"`
+	DeviceRateLimitBurst     int     `yaml:"device_rate_limit_burst"`
+	// 并发 prop/get 合并窗口（毫秒，负数关闭）
+	CoalesceWindowMS int `yaml:"coalesce_window_ms"`
 }
 
 // FlowConfig for flow server.
@@ -92,7 +104,7 @@ type FlowConfig struct {
 
 // WebConfig for web server (OAuth login UI + device management).
 type WebConfig struct {
-	Addr    string `yaml:"addr"`    // 默认 :8123，与 oauth.redirect_uri 一致
+	Addr    string `yaml:"addr"`     // 默认 :8123，与 oauth.redirect_uri 一致
 	DataDir string `yaml:"data_dir"` // SQLite 等数据目录，默认 ./webdata
 }
 
@@ -105,8 +117,30 @@ type XiaomusicConfig struct {
 
 // MiIOConfig for MiIO service.
 type MiIOConfig struct {
-	SpecsCachePath string `yaml:"specs_cache_path"`
-	CallbackPort   int    `yaml:"callback_port"` // OAuth 回调端口
+	SpecsCachePath string `yaml:"specs_cache_path"` // model→URN 映射缓存文件，留空为 specs_dir/instances.json
+	// SpecsDir MIoT SPEC 缓存目录（model→URN 与各版本 instance JSON），留空为用户缓存目录下 miflow/specs
+	SpecsDir string `yaml:"specs_dir"`
+	// SpecsTTLHours SPEC 缓存刷新间隔（小时），过期后联网刷新，失败时仍用旧数据；负数永不过期
+	SpecsTTLHours int `yaml:"specs_ttl_hours"`
+	// SpecsOffline 只用本地缓存与内置种子，不访问 miot-spec.org（MI_SPECS_OFFLINE=1 或 m --offline 同效）
+	SpecsOffline bool `yaml:"specs_offline"`
+	// SpecLocale SPEC 描述语言：zh_CN、zh_TW、en（默认），取自 miot-spec.org 翻译表，缺失时回退英文（MI_LOCALE 同效）
+	SpecLocale   string `yaml:"spec_locale"`
+	CallbackPort int    `yaml:"callback_port"` // OAuth 回调端口
+	// Transport 设备通道策略：cloud（默认）、local、auto（优先局域网，失败回退云端）
+	Transport string `yaml:"transport"`
+	// LocalAddrs 局域网设备地址 did → IP，token 取自云端设备列表
+	LocalAddrs map[string]string `yaml:"local_addrs"`
+	// DiscoveryTimeoutMS 局域网发现（hello 广播 + mDNS）等待时间；local/auto 下未配置地址的设备按需扫描一次，负数关闭
+	DiscoveryTimeoutMS int `yaml:"discovery_timeout_ms"`
+	// RegistryDir 设备注册表 miflow.db（设备索引、别名、群组）所在目录，留空为用户配置目录下 miflow，与工作目录无关
+	RegistryDir string `yaml:"registry_dir"`
+	// RegistryTTLMinutes 设备注册表刷新间隔，名称解析走本地索引；负数关闭
+	RegistryTTLMinutes int `yaml:"registry_ttl_minutes"`
+	// Aliases 设备别名 → did 或设备名称，m alias 设置的别名存于 miflow.db
+	Aliases map[string]string `yaml:"aliases"`
+	// ValidateSpec 写属性/执行动作前按型号 SPEC 校验格式、范围、步长、枚举与可写性（MI_VALIDATE=1 同效）
+	ValidateSpec bool `yaml:"validate_spec"`
 }
 
 // Load reads config from file. If file not found, returns config with defaults.
@@ -143,12 +177,22 @@ func defaultConfig() *Config {
 			RedirectURI:      "http://homeassistant.local:8123/callback",
 			CloudServer:      "cn",
 			APIHost:          "ha.api.io.mi.com",
+			MQTTHost:         "ha.mqtt.io.mi.com",
 			TokenPath:        "/app/v2/ha/oauth/get_token",
 			AuthURL:          "https://account.xiaomi.com/oauth2/authorize",
 			TokenExpireRatio: 0.7,
 		},
 		HTTP: HTTPConfig{
-			TimeoutSeconds: 30,
+			TimeoutSeconds:   30,
+			RetryMaxAttempts: 3,
+			RetryBaseDelayMS: 200,
+			RetryMaxDelayMS:  5000,
+
+			RateLimitPerSecond:       10,
+			RateLimitBurst:           20,
+			DeviceRateLimitPerSecond: 2,
+			DeviceRateLimitBurst:     5,
+			CoalesceWindowMS:         20,
 		},
 		Flow: FlowConfig{
 			Addr:    ":18090",
@@ -163,8 +207,13 @@ func defaultConfig() *Config {
 			Addr:     ":8090",
 		},
 		MiIO: MiIOConfig{
-			SpecsCachePath: "",
-			CallbackPort:   8123,
+			SpecsCachePath:     "",
+			SpecsTTLHours:      168,
+			SpecLocale:         "en",
+			CallbackPort:       8123,
+			Transport:          "cloud",
+			DiscoveryTimeoutMS: 2000,
+			RegistryTTLMinutes: 10,
 		},
 	}
 }
@@ -203,6 +252,9 @@ func mergeOAuth(dst, src *OAuthConfig) {
 	if src.APIHost != "" {
 		dst.APIHost = src.APIHost
 	}
+	if src.MQTTHost != "" {
+		dst.MQTTHost = src.MQTTHost
+	}
 	if src.TokenPath != "" {
 		dst.TokenPath = src.TokenPath
 	}
@@ -218,6 +270,31 @@ func mergeHTTP(dst, src *HTTPConfig) {
 	if src.TimeoutSeconds > 0 {
 		dst.TimeoutSeconds = src.TimeoutSeconds
 	}
+	if src.RetryMaxAttempts > 0 {
+		dst.RetryMaxAttempts = src.RetryMaxAttempts
+	}
+	if src.RetryBaseDelayMS > 0 {
+		dst.RetryBaseDelayMS = src.RetryBaseDelayMS
+	}
+	if src.RetryMaxDelayMS > 0 {
+		dst.RetryMaxDelayMS = src.RetryMaxDelayMS
+	}
+	// 负数表示关闭，因此用 != 0 判断
+	if src.RateLimitPerSecond != 0 {
+		dst.RateLimitPerSecond = src.RateLimitPerSecond
+	}
+	if src.RateLimitBurst > 0 {
+		dst.RateLimitBurst = src.RateLimitBurst
+	}
+	if src.DeviceRateLimitPerSecond != 0 {
+		dst.DeviceRateLimitPerSecond = src.DeviceRateLimitPerSecond
+	}
+	if src.DeviceRateLimitBurst > 0 {
+		dst.DeviceRateLimitBurst = src.DeviceRateLimitBurst
+	}
+	if src.CoalesceWindowMS != 0 {
+		dst.CoalesceWindowMS = src.CoalesceWindowMS
+	}
 }
 
 func mergeFlow(dst, src *FlowConfig) {
@@ -254,9 +331,42 @@ func mergeMiIO(dst, src *MiIOConfig) {
 	if src.SpecsCachePath != "" {
 		dst.SpecsCachePath = src.SpecsCachePath
 	}
+	if src.SpecsDir != "" {
+		dst.SpecsDir = src.SpecsDir
+	}
+	if src.SpecsTTLHours != 0 {
+		dst.SpecsTTLHours = src.SpecsTTLHours
+	}
+	if src.SpecsOffline {
+		dst.SpecsOffline = true
+	}
+	if src.SpecLocale != "" {
+		dst.SpecLocale = src.SpecLocale
+	}
 	if src.CallbackPort > 0 {
 		dst.CallbackPort = src.CallbackPort
 	}
+	if src.Transport != "" {
+		dst.Transport = src.Transport
+	}
+	if len(src.LocalAddrs) > 0 {
+		dst.LocalAddrs = src.LocalAddrs
+	}
+	if src.DiscoveryTimeoutMS != 0 {
+		dst.DiscoveryTimeoutMS = src.DiscoveryTimeoutMS
+	}
+	if src.RegistryDir != "" {
+		dst.RegistryDir = src.RegistryDir
+	}
+	if src.RegistryTTLMinutes != 0 {
+		dst.RegistryTTLMinutes = src.RegistryTTLMinutes
+	}
+	if len(src.Aliases) > 0 {
+		dst.Aliases = src.Aliases
+	}
+	if src.ValidateSpec {
+		dst.ValidateSpec = true
+	}
 }
 
 // expandPath expands ~ to user home directory.
@@ -296,4 +406,16 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("MI_TOKEN_PATH"); v != "" {
 		cfg.TokenPath = v
 	}
+	if v := os.Getenv("MI_TRANSPORT"); v != "" {
+		cfg.MiIO.Transport = v
+	}
+	if v := os.Getenv("MI_SPECS_OFFLINE"); v == "1" || v == "true" {
+		cfg.MiIO.SpecsOffline = true
+	}
+	if v := os.Getenv("MI_VALIDATE"); v == "1" || v == "true" {
+		cfg.MiIO.ValidateSpec = true
+	}
+	if v := os.Getenv("MI_LOCALE"); v != "" {
+		cfg.MiIO.SpecLocale = v
+	}
 }
@@ -1,46 +1,79 @@
 package device
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/zeusro/miflow/internal/miioservice"
 )
 
-// NewAPI 创建设备 API，需要已初始化的 miioservice.Service。
+// NewAPI 创建设备 API，需要已初始化的 miioservice.Service，只使用云端通道。
 func NewAPI(io *miioservice.Service) *API {
-	return &API{io: io}
+	a := &API{io: io, policy: PolicyCloud, routes: make(map[string]Route)}
+	if io != nil {
+		a.transports = []Transport{NewMiotTransport(TransportCloud, io)}
+	}
+	return a
 }
 
 // List 列出设备，name 为空时返回全部，否则按 did/name 模糊匹配。
 // getVirtualModel、getHuamiDevices 与 m list 参数一致。
 func (a *API) List(name string, getVirtualModel bool, getHuamiDevices int) ([]*Device, error) {
-	raw, err := a.io.DeviceList(name, getVirtualModel, getHuamiDevices)
+	return a.ListContext(context.Background(), name, getVirtualModel, getHuamiDevices)
+}
+
+// ListContext 同 List，支持 ctx 取消。
+func (a *API) ListContext(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]*Device, error) {
+	t, err := a.listTransport()
 	if err != nil {
 		return nil, err
 	}
+	raw, err := t.DeviceList(ctx, name, getVirtualModel, getHuamiDevices)
+	if err != nil {
+		return nil, err
+	}
+	if a.registry != nil && name == "" && !getVirtualModel && getHuamiDevices == 0 {
+		// 已拿到完整列表，顺便刷新注册表
+		_, _ = a.registry.Sync(raw)
+	}
 	out := make([]*Device, 0, len(raw))
 	for _, m := range raw {
 		if d := FromMap(m); d != nil && d.DID != "" {
+			a.annotate(d)
 			out = append(out, d)
 		}
 	}
+	a.annotateRooms(ctx, out)
+	a.rememberModels(out)
 	return out, nil
 }
 
-// Get 按 did 或 name 获取单个设备，未找到返回 nil 和错误。
+// annotate 用局域网发现表填充 LocalIP 与 LastSeen。
+func (a *API) annotate(d *Device) {
+	tbl := a.Discovery()
+	if tbl == nil {
+		return
+	}
+	if e, ok := tbl.Lookup(d.DID); ok {
+		d.LocalIP = e.IP
+		seen := e.LastSeen
+		d.LastSeen = &seen
+	}
+}
+
+// Get 按 did 或 name 获取单个设备，匹配规则见 Resolve；未找到或有歧义时返回错误。
 func (a *API) Get(didOrName string) (*Device, error) {
+	return a.GetContext(context.Background(), didOrName)
+}
+
+// GetContext 同 Get，支持 ctx 取消。
+func (a *API) GetContext(ctx context.Context, didOrName string) (*Device, error) {
 	if didOrName == "" {
 		return nil, fmt.Errorf("device: did or name required")
 	}
-	list, err := a.List(didOrName, false, 0)
-	if err != nil {
-		return nil, err
-	}
-	if len(list) == 0 {
-		return nil, fmt.Errorf("device not found: %s", didOrName)
-	}
-	return list[0], nil
+	return a.ResolveContext(ctx, didOrName)
 }
 
 // Spec 按设备型号查询 MIoT SPEC，遵循 docs/spec.md 流程：
@@ -48,47 +81,106 @@ func (a *API) Get(didOrName string) (*Device, error) {
 // 2. 用 URN 请求 miot-spec.org/instance 获取完整 SPEC
 // typ 可为 model 关键词或完整 URN；format 为 text|python|json。
 func (a *API) Spec(typ, format string) (interface{}, error) {
-	return a.io.MiotSpec(typ, format)
+	return a.SpecContext(context.Background(), typ, format)
+}
+
+// SpecContext 同 Spec，支持 ctx 取消。SPEC 为公开数据，不需要登录。
+func (a *API) SpecContext(ctx context.Context, typ, format string) (interface{}, error) {
+	return miioservice.FetchMiotSpec(ctx, typ, format)
 }
 
 // SpecForDevice 获取指定设备的 SPEC，使用其 model。
 func (a *API) SpecForDevice(d *Device, format string) (interface{}, error) {
+	return a.SpecForDeviceContext(context.Background(), d, format)
+}
+
+// SpecForDeviceContext 同 SpecForDevice，支持 ctx 取消。
+func (a *API) SpecForDeviceContext(ctx context.Context, d *Device, format string) (interface{}, error) {
 	if d == nil || d.Model == "" {
 		return nil, fmt.Errorf("device: model required")
 	}
-	return a.Spec(d.Model, format)
+	return a.SpecContext(ctx, d.Model, format)
 }
 
 // GetProps 获取 MIoT 属性，iids 为 [siid, piid] 对。
 func (a *API) GetProps(did string, iids [][2]int) ([]interface{}, error) {
-	return a.io.MiotGetProps(did, iids)
+	return a.GetPropsContext(context.Background(), did, iids)
+}
+
+// GetPropsContext 同 GetProps，支持 ctx 取消。
+func (a *API) GetPropsContext(ctx context.Context, did string, iids [][2]int) (vals []interface{}, err error) {
+	err = a.route(ctx, did, "get_props", func(t Transport) error {
+		vals, err = t.GetProps(ctx, did, iids)
+		return err
+	})
+	return vals, err
 }
 
 // SetProps 设置 MIoT 属性，props 为 [siid, piid, value] 三元组。
 func (a *API) SetProps(did string, props [][3]interface{}) ([]int, error) {
-	return a.io.MiotSetProps(did, props)
+	return a.SetPropsContext(context.Background(), did, props)
+}
+
+// SetPropsContext 同 SetProps，支持 ctx 取消。开启校验时先按 SPEC 检查并转换取值。
+func (a *API) SetPropsContext(ctx context.Context, did string, props [][3]interface{}) (codes []int, err error) {
+	if props, err = a.checkProps(ctx, did, props); err != nil {
+		return nil, err
+	}
+	err = a.route(ctx, did, "set_props", func(t Transport) error {
+		codes, err = t.SetProps(ctx, did, props)
+		return err
+	})
+	return codes, err
 }
 
 // Action 执行 MIoT 动作。
 func (a *API) Action(did string, siid, aiid int, in []interface{}) (int, error) {
-	return a.io.MiotAction(did, siid, aiid, in)
+	return a.ActionContext(context.Background(), did, siid, aiid, in)
+}
+
+// ActionContext 同 Action，支持 ctx 取消。开启校验时先按 SPEC 检查参数。
+func (a *API) ActionContext(ctx context.Context, did string, siid, aiid int, in []interface{}) (code int, err error) {
+	if in, err = a.checkAction(ctx, did, siid, aiid, in); err != nil {
+		return 0, err
+	}
+	err = a.route(ctx, did, "action", func(t Transport) error {
+		code, err = t.Action(ctx, did, siid, aiid, in)
+		return err
+	})
+	return code, err
 }
 
 // ResolveDID 将 name 解析为 did，若已是纯数字 did 则原样返回。
 func (a *API) ResolveDID(didOrName string) (string, error) {
+	return a.ResolveDIDContext(context.Background(), didOrName)
+}
+
+// ResolveDIDContext 同 ResolveDID，支持 ctx 取消。
+func (a *API) ResolveDIDContext(ctx context.Context, didOrName string) (string, error) {
 	if didOrName == "" {
 		return "", fmt.Errorf("device: did or name required")
 	}
 	if isDigits(didOrName) {
 		return didOrName, nil
 	}
-	d, err := a.Get(didOrName)
+	d, err := a.GetContext(ctx, didOrName)
 	if err != nil {
 		return "", err
 	}
 	return d.DID, nil
 }
 
+// listTransport 设备列表优先使用云端，其次为第一个通道。
+func (a *API) listTransport() (Transport, error) {
+	if t := a.transport(TransportCloud); t != nil {
+		return t, nil
+	}
+	if len(a.transports) > 0 {
+		return a.transports[0], nil
+	}
+	return nil, errors.New("device: no transport configured")
+}
+
 func isDigits(s string) bool {
 	for _, r := range s {
 		if r < '0' || r > '9' {
@@ -9,11 +9,17 @@ func FromMap(m map[string]interface{}) *Device {
 	model, _ := m["model"].(string)
 	name, _ := m["name"].(string)
 	token, _ := m["token"].(string)
+	localIP, _ := m["localip"].(string)
+	online, _ := m["isOnline"].(bool)
+	parent, _ := m["parent_id"].(string)
 	return &Device{
-		DID:   did,
-		Model: model,
-		Name:  name,
-		Token: token,
+		DID:       did,
+		Model:     model,
+		Name:      name,
+		Token:     token,
+		IsOnline:  online,
+		ParentDID: parent,
+		LocalIP:   localIP,
 	}
 }
 
@@ -22,10 +28,22 @@ func (d *Device) ToMap() map[string]interface{} {
 	if d == nil {
 		return nil
 	}
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"did":   d.DID,
 		"model": d.Model,
 		"name":  d.Name,
 		"token": d.Token,
 	}
+	if d.LocalIP != "" {
+		m["localip"] = d.LocalIP
+	}
+	if d.ParentDID != "" {
+		m["parent_id"] = d.ParentDID
+	}
+	if d.RoomName != "" {
+		m["home_id"] = d.HomeID
+		m["room_id"] = d.RoomID
+		m["room_name"] = d.RoomName
+	}
+	return m
 }
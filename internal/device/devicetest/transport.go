@@ -0,0 +1,80 @@
+// Package devicetest 提供内存中的设备通道，供 device、ctrl、miiocommand 等包的测试共用。
+package devicetest
+
+import (
+	"context"
+	"sync"
+)
+
+// Transport 实现 device.Transport 与 device.Reacher：属性存于 Props（键为 [siid, piid]），
+// 记录调用次数与已执行的动作。Fail 非 nil 时属性、动作与设备列表都返回该错误。
+type Transport struct {
+	Kind    string          // Name 的返回值，如 device.TransportCloud
+	Reach   map[string]bool // nil 表示全部可达
+	Fail    error
+	Props   map[[2]int]interface{}
+	Devices []map[string]interface{}
+	Calls   int             // GetProps、SetProps、Action 的调用次数
+	Lists   int             // DeviceList 的调用次数
+	Actions [][2]int        // 成功执行的 [siid, aiid]
+	Ins     [][]interface{} // 与 Actions 对应的参数
+
+	mu sync.Mutex
+}
+
+// New 创建名为 kind 的通道，设备列表为 devices。
+func New(kind string, devices ...map[string]interface{}) *Transport {
+	return &Transport{Kind: kind, Props: make(map[[2]int]interface{}), Devices: devices}
+}
+
+func (t *Transport) Name() string { return t.Kind }
+
+func (t *Transport) Reaches(ctx context.Context, did string) bool {
+	return t.Reach == nil || t.Reach[did]
+}
+
+func (t *Transport) GetProps(ctx context.Context, did string, iids [][2]int) ([]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Calls++
+	if t.Fail != nil {
+		return nil, t.Fail
+	}
+	out := make([]interface{}, len(iids))
+	for i, iid := range iids {
+		out[i] = t.Props[iid]
+	}
+	return out, nil
+}
+
+func (t *Transport) SetProps(ctx context.Context, did string, props [][3]interface{}) ([]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Calls++
+	if t.Fail != nil {
+		return nil, t.Fail
+	}
+	for _, p := range props {
+		t.Props[[2]int{p[0].(int), p[1].(int)}] = p[2]
+	}
+	return make([]int, len(props)), nil
+}
+
+func (t *Transport) Action(ctx context.Context, did string, siid, aiid int, in []interface{}) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Calls++
+	if t.Fail != nil {
+		return 0, t.Fail
+	}
+	t.Actions = append(t.Actions, [2]int{siid, aiid})
+	t.Ins = append(t.Ins, in)
+	return 0, nil
+}
+
+func (t *Transport) DeviceList(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Lists++
+	return t.Devices, t.Fail
+}
@@ -0,0 +1,247 @@
+package device
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SpecChange 为两个 SPEC 版本间的一处差异。
+type SpecChange struct {
+	Kind    string `json:"kind"`    // added、removed、renumbered、changed
+	Element string `json:"element"` // service、property、action、event
+	Name    string `json:"name"`    // 如 light.brightness
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"` // iid，如 2、2.3
+	Detail  string `json:"detail,omitempty"`
+}
+
+// SpecDiff 为 DiffSpecs 的结果。
+type SpecDiff struct {
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Changes []SpecChange `json:"changes"`
+}
+
+// DiffSpecs 比较两个 SPEC：服务按 URN 名称配对（同名服务按出现顺序），
+// 属性、动作、事件在配对的服务内按名称配对；iid 不同记为 renumbered，
+// 格式、权限、单位、范围、枚举或参数不同记为 changed。
+func DiffSpecs(a, b *ModelSpec) *SpecDiff {
+	d := &SpecDiff{From: a.Type, To: b.Type}
+	pairs, removed, added := pairByName(len(a.Services), len(b.Services),
+		func(i int) string { return specName(a.Services[i].Type, a.Services[i].Description) },
+		func(i int) string { return specName(b.Services[i].Type, b.Services[i].Description) })
+	for _, i := range removed {
+		s := &a.Services[i]
+		d.add("removed", "service", specName(s.Type, s.Description), strconv.Itoa(s.IID), "", "")
+	}
+	for _, i := range added {
+		s := &b.Services[i]
+		d.add("added", "service", specName(s.Type, s.Description), "", strconv.Itoa(s.IID), "")
+	}
+	for _, p := range pairs {
+		d.diffService(&a.Services[p[0]], &b.Services[p[1]])
+	}
+	return d
+}
+
+func (d *SpecDiff) diffService(sa, sb *ServiceSpec) {
+	svc := specName(sa.Type, sa.Description)
+	if sa.IID != sb.IID {
+		d.add("renumbered", "service", svc, strconv.Itoa(sa.IID), strconv.Itoa(sb.IID), "")
+	}
+	path := func(siid, iid int) string { return fmt.Sprintf("%d.%d", siid, iid) }
+	elem := func(kind, element, name string, ia, ib int) {
+		from, to := "", ""
+		if ia > 0 {
+			from = path(sa.IID, ia)
+		}
+		if ib > 0 {
+			to = path(sb.IID, ib)
+		}
+		d.add(kind, element, svc+"."+name, from, to, "")
+	}
+
+	pa, pb := sa.Properties, sb.Properties
+	pairs, removed, added := pairByName(len(pa), len(pb),
+		func(i int) string { return specName(pa[i].Type, pa[i].Description) },
+		func(i int) string { return specName(pb[i].Type, pb[i].Description) })
+	for _, i := range removed {
+		elem("removed", "property", specName(pa[i].Type, pa[i].Description), pa[i].IID, 0)
+	}
+	for _, i := range added {
+		elem("added", "property", specName(pb[i].Type, pb[i].Description), 0, pb[i].IID)
+	}
+	for _, p := range pairs {
+		x, y := &pa[p[0]], &pb[p[1]]
+		name := svc + "." + specName(x.Type, x.Description)
+		if x.IID != y.IID {
+			d.add("renumbered", "property", name, path(sa.IID, x.IID), path(sb.IID, y.IID), "")
+		}
+		if detail := propChanges(x, y); detail != "" {
+			d.add("changed", "property", name, path(sa.IID, x.IID), path(sb.IID, y.IID), detail)
+		}
+	}
+
+	aa, ab := sa.Actions, sb.Actions
+	pairs, removed, added = pairByName(len(aa), len(ab),
+		func(i int) string { return specName(aa[i].Type, aa[i].Description) },
+		func(i int) string { return specName(ab[i].Type, ab[i].Description) })
+	for _, i := range removed {
+		elem("removed", "action", specName(aa[i].Type, aa[i].Description), aa[i].IID, 0)
+	}
+	for _, i := range added {
+		elem("added", "action", specName(ab[i].Type, ab[i].Description), 0, ab[i].IID)
+	}
+	for _, p := range pairs {
+		x, y := &aa[p[0]], &ab[p[1]]
+		name := svc + "." + specName(x.Type, x.Description)
+		if x.IID != y.IID {
+			d.add("renumbered", "action", name, path(sa.IID, x.IID), path(sb.IID, y.IID), "")
+		}
+		var parts []string
+		if !sameInts(x.In, y.In) {
+			parts = append(parts, fmt.Sprintf("in %v → %v", x.In, y.In))
+		}
+		if !sameInts(x.Out, y.Out) {
+			parts = append(parts, fmt.Sprintf("out %v → %v", x.Out, y.Out))
+		}
+		if len(parts) > 0 {
+			d.add("changed", "action", name, path(sa.IID, x.IID), path(sb.IID, y.IID), strings.Join(parts, "; "))
+		}
+	}
+
+	ea, eb := sa.Events, sb.Events
+	pairs, removed, added = pairByName(len(ea), len(eb),
+		func(i int) string { return specName(ea[i].Type, ea[i].Description) },
+		func(i int) string { return specName(eb[i].Type, eb[i].Description) })
+	for _, i := range removed {
+		elem("removed", "event", specName(ea[i].Type, ea[i].Description), ea[i].IID, 0)
+	}
+	for _, i := range added {
+		elem("added", "event", specName(eb[i].Type, eb[i].Description), 0, eb[i].IID)
+	}
+	for _, p := range pairs {
+		x, y := &ea[p[0]], &eb[p[1]]
+		name := svc + "." + specName(x.Type, x.Description)
+		if x.IID != y.IID {
+			d.add("renumbered", "event", name, path(sa.IID, x.IID), path(sb.IID, y.IID), "")
+		}
+		if !sameInts(x.Arguments, y.Arguments) {
+			d.add("changed", "event", name, path(sa.IID, x.IID), path(sb.IID, y.IID), fmt.Sprintf("arguments %v → %v", x.Arguments, y.Arguments))
+		}
+	}
+}
+
+func (d *SpecDiff) add(kind, element, name, from, to, detail string) {
+	d.Changes = append(d.Changes, SpecChange{Kind: kind, Element: element, Name: name, From: from, To: to, Detail: detail})
+}
+
+// Breaking 返回会使已有 siid/piid/aiid 失效的差异（removed、renumbered、changed）。
+func (d *SpecDiff) Breaking() []SpecChange {
+	var out []SpecChange
+	for _, c := range d.Changes {
+		if c.Kind != "added" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// String 以每行一处差异输出，如 "renumbered property light.brightness 2.3 → 2.4"。
+func (d *SpecDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s → %s\n", d.From, d.To)
+	if len(d.Changes) == 0 {
+		b.WriteString("no changes\n")
+	}
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "%-10s %-8s %s", c.Kind, c.Element, c.Name)
+		switch {
+		case c.From != "" && c.To != "" && c.From != c.To:
+			fmt.Fprintf(&b, " %s → %s", c.From, c.To)
+		case c.From != "":
+			fmt.Fprintf(&b, " %s", c.From)
+		case c.To != "":
+			fmt.Fprintf(&b, " %s", c.To)
+		}
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// propChanges 描述属性格式、权限、单位、范围与枚举的变化，无变化时为空。
+func propChanges(x, y *PropSpec) string {
+	var parts []string
+	if x.Format != y.Format {
+		parts = append(parts, fmt.Sprintf("format %s → %s", x.Format, y.Format))
+	}
+	if ax, ay := strings.Join(x.Access, ","), strings.Join(y.Access, ","); ax != ay {
+		parts = append(parts, fmt.Sprintf("access %s → %s", ax, ay))
+	}
+	if x.Unit != y.Unit {
+		parts = append(parts, fmt.Sprintf("unit %q → %q", x.Unit, y.Unit))
+	}
+	if rx, ry := rangeString(x.ValueRange), rangeString(y.ValueRange); rx != ry {
+		parts = append(parts, fmt.Sprintf("range %s → %s", rx, ry))
+	}
+	if lx, ly := x.listString(), y.listString(); lx != ly {
+		parts = append(parts, fmt.Sprintf("value-list %s → %s", lx, ly))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func rangeString(r *ValueRange) string {
+	if r == nil {
+		return "none"
+	}
+	return fmt.Sprintf("[%g, %g] step %g", r.Min, r.Max, r.Step)
+}
+
+// pairByName 按名称配对两组元素，同名元素按出现顺序一一对应。
+func pairByName(na, nb int, nameA, nameB func(int) string) (pairs [][2]int, removed, added []int) {
+	pending := make(map[string][]int)
+	for j := 0; j < nb; j++ {
+		pending[nameB(j)] = append(pending[nameB(j)], j)
+	}
+	matched := make([]bool, nb)
+	for i := 0; i < na; i++ {
+		name := nameA(i)
+		if js := pending[name]; len(js) > 0 {
+			pairs = append(pairs, [2]int{i, js[0]})
+			matched[js[0]] = true
+			pending[name] = js[1:]
+		} else {
+			removed = append(removed, i)
+		}
+	}
+	for j, ok := range matched {
+		if !ok {
+			added = append(added, j)
+		}
+	}
+	return pairs, removed, added
+}
+
+// specName 取 URN 名称，非标准 URN 时用小写描述。
+func specName(urn, description string) string {
+	if n := urnName(urn); n != "" {
+		return n
+	}
+	return strings.ToLower(description)
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
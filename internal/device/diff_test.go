@@ -0,0 +1,45 @@
+package device
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffSpecs(t *testing.T) {
+	a := testLightSpec()
+	a.Type = "urn:miot-spec-v2:device:light:0000A001:test-v1:1"
+	b := testLightSpec()
+	b.Type = "urn:miot-spec-v2:device:light:0000A001:test-v1:2"
+	svc := &b.Services[0]
+	svc.Properties[2].IID = 7 // brightness 2.3 → 2.7
+	svc.Properties[3].ValueRange = &ValueRange{Min: 3000, Max: 6500, Step: 100}
+	svc.Properties = svc.Properties[:5] // 移除 text-content
+	svc.Actions[1].In = nil
+	b.Services = append(b.Services, ServiceSpec{IID: 3, Type: "urn:miot-spec-v2:service:indicator-light:00007803:test:1"})
+
+	d := DiffSpecs(a, b)
+	want := []SpecChange{
+		{Kind: "added", Element: "service", Name: "indicator-light", To: "3"},
+		{Kind: "removed", Element: "property", Name: "light.text-content", From: "2.6"},
+		{Kind: "renumbered", Element: "property", Name: "light.brightness", From: "2.3", To: "2.7"},
+		{Kind: "changed", Element: "property", Name: "light.color-temperature", From: "2.4", To: "2.4", Detail: "range [2700, 6500] step 100 → [3000, 6500] step 100"},
+		{Kind: "changed", Element: "action", Name: "light.play-text", From: "2.2", To: "2.2", Detail: "in [6] → []"},
+	}
+	if len(d.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d:\n%s", len(d.Changes), len(want), d)
+	}
+	for i, w := range want {
+		if d.Changes[i] != w {
+			t.Errorf("change %d = %+v, want %+v", i, d.Changes[i], w)
+		}
+	}
+	if n := len(d.Breaking()); n != 4 {
+		t.Errorf("Breaking() = %d, want 4", n)
+	}
+	if s := d.String(); !strings.Contains(s, "renumbered property light.brightness 2.3 → 2.7") {
+		t.Errorf("String():\n%s", s)
+	}
+	if d := DiffSpecs(a, a); len(d.Changes) != 0 {
+		t.Errorf("identical specs: %+v", d.Changes)
+	}
+}
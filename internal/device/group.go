@@ -0,0 +1,205 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+// Group 为设备群组，保存在设备注册表（miflow.db）中。
+type Group = registry.Group
+
+// GroupPrefix 目标以此开头时表示群组，如 @卧室插座。
+const GroupPrefix = "@"
+
+// groupWorkers 群组并发执行的设备数上限。
+const groupWorkers = 8
+
+// GroupRef 报告 target 是否为群组引用并返回群组名。
+func GroupRef(target string) (string, bool) {
+	if strings.HasPrefix(target, GroupPrefix) && len(target) > len(GroupPrefix) {
+		return strings.TrimPrefix(target, GroupPrefix), true
+	}
+	return "", false
+}
+
+// MemberResult 为群组中单个设备的执行结果。
+type MemberResult struct {
+	DID    string      `json:"did"`
+	Name   string      `json:"name"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// GroupError 表示群组中部分设备执行失败，Results 含全部成员的结果。
+type GroupError struct {
+	Group   string
+	Results []MemberResult
+}
+
+func (e *GroupError) Error() string {
+	var failed []string
+	for _, r := range e.Results {
+		if r.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s(%s): %s", r.Name, r.DID, r.Error))
+		}
+	}
+	return fmt.Sprintf("device: group %s: %d/%d members failed: %s", e.Group, len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+// Groups 返回全部群组。
+func (a *API) Groups() ([]Group, error) {
+	if a.registry == nil {
+		return nil, errors.New("device: groups require device registry")
+	}
+	return a.registry.Groups()
+}
+
+// SaveGroup 创建或替换群组。
+func (a *API) SaveGroup(g *Group) error {
+	if a.registry == nil {
+		return errors.New("device: groups require device registry")
+	}
+	return a.registry.SaveGroup(g)
+}
+
+// DeleteGroup 删除群组。
+func (a *API) DeleteGroup(name string) error {
+	if a.registry == nil {
+		return errors.New("device: groups require device registry")
+	}
+	return a.registry.DeleteGroup(name)
+}
+
+// GroupMembers 返回群组当前成员：静态成员与动态条件匹配设备的并集。
+func (a *API) GroupMembers(name string) ([]*Device, error) {
+	return a.GroupMembersContext(context.Background(), name)
+}
+
+// GroupMembersContext 同 GroupMembers，支持 ctx 取消。
+func (a *API) GroupMembersContext(ctx context.Context, name string) ([]*Device, error) {
+	if a.registry == nil {
+		return nil, errors.New("device: groups require device registry")
+	}
+	g, err := a.registry.Group(name)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, fmt.Errorf("device: group %s not found", name)
+	}
+	var out []*Device
+	seen := make(map[string]bool)
+	add := func(d *Device) {
+		if !seen[d.DID] {
+			seen[d.DID] = true
+			out = append(out, d)
+		}
+	}
+	for _, m := range g.Members {
+		d, err := a.ResolveContext(ctx, m)
+		if err != nil {
+			return nil, fmt.Errorf("device: group %s member %s: %w", name, m, err)
+		}
+		add(d)
+	}
+	if !g.Dynamic() {
+		return out, nil
+	}
+	list, err := a.FindContext(ctx, Filter{Home: g.Home, Room: g.Room})
+	if err != nil {
+		return nil, err
+	}
+	caps := make(map[string]bool) // model → 是否具备 Capability
+	for _, d := range list {
+		if !modelMatch(g.Model, d.Model) {
+			continue
+		}
+		if g.Capability != "" {
+			ok, cached := caps[d.Model]
+			if !cached {
+				ok = a.hasCapability(d.Model, g.Capability)
+				caps[d.Model] = ok
+			}
+			if !ok {
+				continue
+			}
+		}
+		add(d)
+	}
+	return out, nil
+}
+
+// modelMatch 按通配（含 * ? [ 时用 path.Match）或关键字匹配型号，pattern 为空时总是匹配。
+func modelMatch(pattern, model string) bool {
+	if pattern == "" {
+		return true
+	}
+	pattern, model = strings.ToLower(pattern), strings.ToLower(model)
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, _ := path.Match(pattern, model)
+		return ok
+	}
+	return strings.Contains(model, pattern)
+}
+
+// hasCapability 报告型号 SPEC 中是否有指定类型的服务，如 urn:miot-spec-v2:service:switch:... 对应 switch。
+func (a *API) hasCapability(model, capability string) bool {
+	spec, err := a.LoadSpec(model)
+	if err != nil {
+		return false
+	}
+	for _, s := range spec.Services {
+		if s.Name() == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// FanOut 对群组成员并发执行 fn，返回每个成员的结果；有成员失败时返回 *GroupError。
+func (a *API) FanOut(name string, fn func(ctx context.Context, d *Device) (interface{}, error)) ([]MemberResult, error) {
+	return a.FanOutContext(context.Background(), name, fn)
+}
+
+// FanOutContext 同 FanOut，支持 ctx 取消。
+func (a *API) FanOutContext(ctx context.Context, name string, fn func(ctx context.Context, d *Device) (interface{}, error)) ([]MemberResult, error) {
+	members, err := a.GroupMembersContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("device: group %s has no members", name)
+	}
+	results := make([]MemberResult, len(members))
+	sem := make(chan struct{}, groupWorkers)
+	var wg sync.WaitGroup
+	for i, d := range members {
+		wg.Add(1)
+		go func(i int, d *Device) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r := MemberResult{DID: d.DID, Name: d.Name}
+			v, err := fn(ctx, d)
+			if err != nil {
+				r.Error = err.Error()
+			} else {
+				r.Result = v
+			}
+			results[i] = r
+		}(i, d)
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r.Error != "" {
+			return results, &GroupError{Group: name, Results: results}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,77 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device/devicetest"
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+func TestGroupFanOut(t *testing.T) {
+	reg, err := registry.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Close()
+	cloud := &homeFake{Transport: devicetest.New(TransportCloud)}
+	cloud.Devices = []map[string]interface{}{
+		{"did": "1", "name": "床头插座", "model": "chuangmi.plug.m3"},
+		{"did": "2", "name": "电热毯", "model": "cuco.plug.v3"},
+		{"did": "3", "name": "台灯", "model": "yeelink.light.lamp1"},
+		{"did": "4", "name": "客厅插座", "model": "chuangmi.plug.v3"},
+	}
+	cloud.homes = []Home{{ID: "100", Name: "我的家", Rooms: []Room{
+		{ID: "101", Name: "卧室", HomeID: "100", DIDs: []string{"1", "2", "3"}},
+		{ID: "102", Name: "客厅", HomeID: "100", DIDs: []string{"4"}},
+	}}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	api.SetRegistry(reg)
+
+	if err := api.SaveGroup(&Group{Name: "空"}); err == nil {
+		t.Error("SaveGroup without members or selector should fail")
+	}
+	if err := api.SaveGroup(&Group{Name: "卧室插座", Room: "卧室", Model: "*.plug.*", Members: []string{"客厅插座"}}); err != nil {
+		t.Fatal(err)
+	}
+	members, err := api.GroupMembers("卧室插座")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dids []string
+	for _, d := range members {
+		dids = append(dids, d.DID)
+	}
+	sort.Strings(dids)
+	if len(dids) != 3 || dids[0] != "1" || dids[1] != "2" || dids[2] != "4" {
+		t.Errorf("members = %v, want [1 2 4]", dids)
+	}
+
+	results, err := api.FanOut("卧室插座", func(ctx context.Context, d *Device) (interface{}, error) {
+		if d.DID == "2" {
+			return nil, errors.New("offline")
+		}
+		return d.Model, nil
+	})
+	var ge *GroupError
+	if !errors.As(err, &ge) || len(results) != 3 {
+		t.Fatalf("FanOut = %v, %v; want GroupError with 3 results", results, err)
+	}
+	for _, r := range results {
+		if (r.DID == "2") != (r.Error != "") {
+			t.Errorf("result %+v", r)
+		}
+	}
+
+	if name, ok := GroupRef("@卧室插座"); !ok || name != "卧室插座" {
+		t.Errorf("GroupRef = %q, %v", name, ok)
+	}
+	if _, ok := GroupRef("卧室插座"); ok {
+		t.Error("plain name is not a group ref")
+	}
+	if _, err := api.GroupMembers("不存在"); err == nil {
+		t.Error("unknown group should fail")
+	}
+}
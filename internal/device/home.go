@@ -0,0 +1,191 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/zeusro/miflow/internal/mihomeapi"
+)
+
+// Home 与 Room 为米家家庭与房间，来自云端 /app/v2/homeroom/gethome；该接口不返回楼层，没有楼层筛选。
+type (
+	Home = mihomeapi.Home
+	Room = mihomeapi.Room
+)
+
+// HomeLister 可选接口：提供家庭、房间与设备分配（云端通道实现）。
+type HomeLister interface {
+	HomeListContext(ctx context.Context) ([]Home, error)
+}
+
+// homesTTL 家庭/房间缓存时间，房间分配很少变化。
+const homesTTL = 5 * time.Minute
+
+// homesRetry 获取家庭失败后的重试间隔，期间 List 等调用不再请求 /gethome。
+const homesRetry = 30 * time.Second
+
+// Filter 为设备筛选条件，零值字段不参与筛选。
+type Filter struct {
+	Name   string // did 或名称包含
+	Home   string // 家庭 ID 或名称
+	Room   string // 房间名称或 ID
+	Model  string // 型号包含，如 light、airpurifier
+	Online *bool  // 在线状态
+}
+
+// Match 报告设备是否满足条件；Home 按 ID 比较（名称由 FindContext 解析为 ID）。
+func (f Filter) Match(d *Device) bool {
+	if d == nil {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(d.DID, f.Name) && !strings.Contains(d.Name, f.Name) {
+		return false
+	}
+	if f.Home != "" && d.HomeID != f.Home {
+		return false
+	}
+	if f.Room != "" && !strings.EqualFold(d.RoomName, f.Room) && d.RoomID != f.Room {
+		return false
+	}
+	if f.Model != "" && !strings.Contains(strings.ToLower(d.Model), strings.ToLower(f.Model)) {
+		return false
+	}
+	if f.Online != nil && d.IsOnline != *f.Online {
+		return false
+	}
+	return true
+}
+
+// homeLister 返回可提供家庭信息的通道，优先云端。
+func (a *API) homeLister() HomeLister {
+	ts := a.transports
+	if c := a.transport(TransportCloud); c != nil {
+		ts = append([]Transport{c}, ts...)
+	}
+	for _, t := range ts {
+		if hl, ok := t.(HomeLister); ok {
+			return hl
+		}
+		if mt, ok := t.(*miotTransport); ok {
+			if hl, ok := mt.svc.(HomeLister); ok {
+				return hl
+			}
+		}
+	}
+	return nil
+}
+
+// Homes 返回家庭、房间与设备分配。
+func (a *API) Homes() ([]Home, error) {
+	return a.HomesContext(context.Background())
+}
+
+// HomesContext 同 Homes，支持 ctx 取消；结果缓存 homesTTL，失败缓存 homesRetry。
+// 请求不持有 homesMu；同时只有一个请求在途，其他调用等待其结果。
+func (a *API) HomesContext(ctx context.Context) ([]Home, error) {
+	hl := a.homeLister()
+	if hl == nil {
+		return nil, errors.New("device: home list requires cloud service")
+	}
+	for {
+		a.homesMu.Lock()
+		if a.homes != nil && time.Since(a.homesAt) < homesTTL {
+			homes := a.homes
+			a.homesMu.Unlock()
+			return homes, nil
+		}
+		if a.homesErr != nil && time.Since(a.homesErrAt) < homesRetry {
+			err := a.homesErr
+			a.homesMu.Unlock()
+			return nil, err
+		}
+		wait := a.homesLoad
+		if wait == nil {
+			break
+		}
+		a.homesMu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	a.homesLoad = done
+	a.homesMu.Unlock()
+
+	homes, err := hl.HomeListContext(ctx)
+
+	a.homesMu.Lock()
+	defer a.homesMu.Unlock()
+	a.homesLoad = nil
+	close(done)
+	// ctx 取消导致的失败不记录，等待者会重新请求
+	switch {
+	case err == nil:
+		a.homes, a.homesAt, a.homesErr = homes, time.Now(), nil
+	case ctx.Err() == nil:
+		a.homesErr, a.homesErrAt = err, time.Now()
+	}
+	return homes, err
+}
+
+// annotateRooms 按家庭信息填充 HomeID、RoomID、RoomName；获取失败时保持为空。
+func (a *API) annotateRooms(ctx context.Context, list []*Device) {
+	if len(list) == 0 || a.homeLister() == nil {
+		return
+	}
+	homes, err := a.HomesContext(ctx)
+	if err != nil {
+		return
+	}
+	type place struct{ home, room, roomName string }
+	where := make(map[string]place)
+	for _, h := range homes {
+		for _, did := range h.DIDs {
+			where[did] = place{home: h.ID}
+		}
+		for _, r := range h.Rooms {
+			for _, did := range r.DIDs {
+				where[did] = place{home: h.ID, room: r.ID, roomName: r.Name}
+			}
+		}
+	}
+	for _, d := range list {
+		if p, ok := where[d.DID]; ok {
+			d.HomeID, d.RoomID, d.RoomName = p.home, p.room, p.roomName
+		}
+	}
+}
+
+// Find 按条件筛选设备。
+func (a *API) Find(f Filter) ([]*Device, error) {
+	return a.FindContext(context.Background(), f)
+}
+
+// FindContext 同 Find，支持 ctx 取消。f.Home 可为家庭名称。
+func (a *API) FindContext(ctx context.Context, f Filter) ([]*Device, error) {
+	if f.Home != "" {
+		if homes, err := a.HomesContext(ctx); err == nil {
+			for _, h := range homes {
+				if h.Name == f.Home {
+					f.Home = h.ID
+					break
+				}
+			}
+		}
+	}
+	list, err := a.ListContext(ctx, "", false, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Device, 0, len(list))
+	for _, d := range list {
+		if f.Match(d) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
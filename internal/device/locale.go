@@ -0,0 +1,42 @@
+package device
+
+import (
+	"context"
+
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// SetLocale 设置 SPEC 描述语言（zh_CN、zh_TW、en），默认取配置 miio.spec_locale，对之后加载的 SPEC 生效。
+func (a *API) SetLocale(locale string) {
+	a.locale = locale
+}
+
+// localize 按 API 的语言为 spec 加载翻译表；翻译不可用时保持英文。
+func (a *API) localize(ctx context.Context, spec *ModelSpec) {
+	if specs.NormalizeLocale(a.locale) == "en" || spec.Type == "" {
+		return
+	}
+	if t, err := specs.TranslateContext(ctx, spec.Type); err == nil {
+		spec.Localize(t, a.locale)
+	}
+}
+
+// Localize 设置 locale 下的翻译描述，en 时清除。
+func (s *ModelSpec) Localize(t specs.Translation, locale string) {
+	l := specs.NormalizeLocale(locale)
+	if l == "en" {
+		s.Labels = nil
+		return
+	}
+	s.Labels = t[l]
+}
+
+// Text 返回 key 的翻译描述，无翻译时返回 english。
+func (s *ModelSpec) Text(key, english string) string {
+	if s != nil {
+		if t := s.Labels[key]; t != "" {
+			return t
+		}
+	}
+	return english
+}
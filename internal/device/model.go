@@ -1,7 +1,12 @@
 package device
 
 import (
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/discovery"
 	"github.com/zeusro/miflow/internal/miioservice"
+	"github.com/zeusro/miflow/internal/registry"
 )
 
 // Device 表示 m list 导出的接入设备。
@@ -11,11 +16,45 @@ type Device struct {
 	Model string `json:"model"` // 型号，用于查询 miot-spec.org SPEC
 	Name  string `json:"name"`  // 设备名称
 	Token string `json:"token"` // 设备 token
+
+	HomeID    string `json:"home_id,omitempty"`    // 所属家庭
+	RoomID    string `json:"room_id,omitempty"`    // 所属房间
+	RoomName  string `json:"room_name,omitempty"`  // 房间名称，如 客厅
+	IsOnline  bool   `json:"is_online"`            // 云端在线状态
+	ParentDID string `json:"parent_did,omitempty"` // 网关子设备的父设备
+
+	LocalIP  string     `json:"local_ip,omitempty"`  // 局域网 IP（云端上报或局域网发现）
+	LastSeen *time.Time `json:"last_seen,omitempty"` // 局域网最后发现时间
 }
 
 // API 封装接入设备的操作，基于 m list 设备列表与 docs/spec.md 的 SPEC 查询流程。
+// 属性、动作与设备列表经 Transport 按 policy 路由；SPEC 为公开数据，查询不需要登录。
 type API struct {
-	io *miioservice.Service
+	io         *miioservice.Service
+	transports []Transport
+	policy     Policy
+
+	routesMu  sync.Mutex
+	routes    map[string]Route
+	lastRoute Route
+
+	discoveryMu sync.Mutex
+	discovery   *discovery.Table // 局域网发现表，可为 nil
+	registry    *registry.Store  // 设备注册表（miflow.db），可为 nil
+
+	homesMu    sync.Mutex
+	homes      []Home
+	homesAt    time.Time
+	homesErr   error // 最近一次获取失败，homesRetry 内直接返回
+	homesErrAt time.Time
+	homesLoad  chan struct{} // 在途请求完成时关闭，为 nil 时没有在途请求
+
+	validate bool   // 写属性/动作前按 SPEC 校验取值
+	locale   string // SPEC 描述语言，空或 en 时不加载翻译
+
+	specsMu sync.Mutex
+	specs   map[string]*ModelSpec // model → 已解析的 SPEC
+	models  map[string]string     // did → model，来自最近的设备列表，写入校验时不必每次拉取列表
 }
 
 // ModelSpec 表示单个型号的 MIoT SPEC，按 docs/spec.md 从 miot-spec.org 获取。
@@ -23,6 +62,9 @@ type ModelSpec struct {
 	Type        string        `json:"type"`
 	Description string        `json:"description"`
 	Services    []ServiceSpec `json:"services"`
+	// Labels 为 Localize 设置的翻译描述，键同 specs.Translation（如 service:002:property:001）；
+	// 各 Description 保持英文，名称解析与取值转换不受影响
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // ServiceSpec 表示 SPEC 中的服务（siid）。
@@ -30,30 +72,49 @@ type ServiceSpec struct {
 	IID         int          `json:"iid"`
 	Description string       `json:"description"`
 	Type        string       `json:"type"`
-	Properties  []PropSpec    `json:"properties"`
+	Properties  []PropSpec   `json:"properties"`
 	Actions     []ActionSpec `json:"actions"`
 	Events      []EventSpec  `json:"events"`
 }
 
 // PropSpec 表示属性（piid）。
 type PropSpec struct {
-	IID         int      `json:"iid"`
-	Description string   `json:"description"`
-	Format      string   `json:"format"`
-	Access      []string `json:"access"`
+	IID         int         `json:"iid"`
+	Type        string      `json:"type"` // URN，如 urn:miot-spec-v2:property:on:00000006:...
+	Description string      `json:"description"`
+	Format      string      `json:"format"` // bool、uint8、uint16、uint32、int8、int16、int32、int64、float、string、hex
+	Access      []string    `json:"access"` // read、write、notify
+	Unit        string      `json:"unit,omitempty"`
+	ValueRange  *ValueRange `json:"value_range,omitempty"`
+	ValueList   []ValueItem `json:"value_list,omitempty"`
+}
+
+// ValueRange 为属性取值范围 [Min, Max]，Step 为步长（0 表示不限制）。
+type ValueRange struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Step float64 `json:"step"`
 }
 
-// ActionSpec 表示动作（aiid）。
+// ValueItem 为枚举属性的一个取值。
+type ValueItem struct {
+	Value       int    `json:"value"`
+	Description string `json:"description"`
+}
+
+// ActionSpec 表示动作（aiid），In/Out 为参数对应的 piid。
 type ActionSpec struct {
-	IID         int           `json:"iid"`
-	Description string        `json:"description"`
-	In          []interface{} `json:"in"`
-	Out         []interface{} `json:"out"`
+	IID         int    `json:"iid"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	In          []int  `json:"in"`
+	Out         []int  `json:"out"`
 }
 
-// EventSpec 表示事件（eid）。
+// EventSpec 表示事件（eiid），Arguments 为参数对应的 piid。
 type EventSpec struct {
-	IID         int           `json:"iid"`
-	Description string        `json:"description"`
-	Arguments   []interface{} `json:"arguments"`
+	IID         int    `json:"iid"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Arguments   []int  `json:"arguments"`
 }
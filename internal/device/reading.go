@@ -0,0 +1,174 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// Reading 为按 SPEC 解读后的属性值。
+type Reading struct {
+	SIID        int         `json:"siid"`
+	PIID        int         `json:"piid"`
+	Name        string      `json:"name,omitempty"` // 如 light.brightness，SPEC 不可用时为空
+	Description string      `json:"description,omitempty"`
+	Value       interface{} `json:"value"`
+	Unit        string      `json:"unit,omitempty"`
+	Label       string      `json:"label,omitempty"` // 枚举描述，位掩码时为多个描述以 | 连接
+	Text        string      `json:"text"`            // 可读文本，如 60%、23.5°C、Occupied
+}
+
+// unitSymbols SPEC unit → 显示符号，未列出的单位原样附加。
+var unitSymbols = map[string]string{
+	"percentage": "%",
+	"celsius":    "°C",
+	"fahrenheit": "°F",
+	"kelvin":     "K",
+	"seconds":    "s",
+	"minutes":    "min",
+	"hours":      "h",
+	"days":       "d",
+	"lux":        "lx",
+	"arcdegrees": "°",
+	"watt":       "W",
+	"kWh":        "kWh",
+	"pascal":     "Pa",
+	"metre":      "m",
+	"rgb":        "",
+}
+
+// ReadProps 获取属性并按设备 SPEC 解读为名称、单位与枚举描述。
+func (a *API) ReadProps(did string, iids [][2]int) ([]Reading, error) {
+	return a.ReadPropsContext(context.Background(), did, iids)
+}
+
+// ReadPropsContext 同 ReadProps，支持 ctx 取消。SPEC 不可用时仅返回原始值。
+func (a *API) ReadPropsContext(ctx context.Context, did string, iids [][2]int) ([]Reading, error) {
+	vals, err := a.GetPropsContext(ctx, did, iids)
+	if err != nil {
+		return nil, err
+	}
+	spec := a.specFor(ctx, did)
+	out := make([]Reading, len(iids))
+	for i, iid := range iids {
+		var v interface{}
+		if i < len(vals) {
+			v = vals[i]
+		}
+		out[i] = spec.Decode(iid[0], iid[1], v)
+	}
+	return out, nil
+}
+
+// ReadableProps 返回 SPEC 中全部可读属性的 [siid, piid]。
+func (s *ModelSpec) ReadableProps() [][2]int {
+	var out [][2]int
+	for _, svc := range s.Services {
+		for i := range svc.Properties {
+			if svc.Properties[i].Readable() {
+				out = append(out, [2]int{svc.IID, svc.Properties[i].IID})
+			}
+		}
+	}
+	return out
+}
+
+// Decode 按 SPEC 解读属性值；s 为 nil 或无此属性时只填写原始值与文本。
+func (s *ModelSpec) Decode(siid, piid int, v interface{}) Reading {
+	r := Reading{SIID: siid, PIID: piid, Value: v, Text: formatValue(v)}
+	if s == nil {
+		return r
+	}
+	p := s.Property(siid, piid)
+	if p == nil {
+		return r
+	}
+	r.Name = p.Name()
+	if svc := s.Service(siid); svc.Name() != "" {
+		r.Name = svc.Name() + "." + r.Name
+	}
+	r.Description = s.Text(specs.PropertyKey(siid, piid), p.Description)
+	r.Unit = p.Unit
+	f, isNum := number(v)
+	if isNum && len(p.ValueList) > 0 && f == float64(int(f)) {
+		text := func(i int) string { return s.Text(specs.ValueKey(siid, piid, i), p.ValueList[i].Description) }
+		if r.Label = p.label(int(f), text); r.Label != "" {
+			r.Text = r.Label
+			return r
+		}
+	}
+	if isNum && r.Unit != "" {
+		sym, known := unitSymbols[r.Unit]
+		switch {
+		case !known:
+			r.Text += " " + r.Unit
+		case sym != "":
+			r.Text += sym
+		}
+	}
+	return r
+}
+
+// label 返回枚举描述，text(i) 为第 i 项的显示文本；值不在列表中且列表为位掩码（各值为不同的 2 的幂）时按位拆分。
+func (p *PropSpec) label(v int, text func(i int) string) string {
+	for i, it := range p.ValueList {
+		if it.Value == v {
+			return text(i)
+		}
+	}
+	if v <= 0 || !p.bitmask() {
+		return ""
+	}
+	var parts []string
+	rest := v
+	for i, it := range p.ValueList {
+		if v&it.Value != 0 {
+			parts = append(parts, text(i))
+			rest &^= it.Value
+		}
+	}
+	if rest != 0 {
+		parts = append(parts, fmt.Sprintf("0x%x", rest))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (p *PropSpec) bitmask() bool {
+	if len(p.ValueList) < 2 {
+		return false
+	}
+	seen := 0
+	for _, it := range p.ValueList {
+		if it.Value == 0 {
+			continue // 0 常表示“无”
+		}
+		if it.Value < 0 || it.Value&(it.Value-1) != 0 || seen&it.Value != 0 {
+			return false
+		}
+		seen |= it.Value
+	}
+	return seen != 0
+}
+
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int, int64, float64:
+		return toFloat(x), true
+	}
+	return 0, false
+}
+
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		return x
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,92 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device/devicetest"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+func TestDecode(t *testing.T) {
+	spec := &ModelSpec{Services: []ServiceSpec{{
+		IID:  2,
+		Type: "urn:miot-spec-v2:service:occupancy-sensor:00007825:test:1",
+		Properties: []PropSpec{
+			{IID: 1, Type: "urn:miot-spec-v2:property:occupancy-status:0000007B:test:1", Description: "Occupancy Status", Format: "uint8", Access: []string{"read", "notify"},
+				ValueList: []ValueItem{{Value: 0, Description: "No One"}, {Value: 1, Description: "Occupied"}}},
+			{IID: 2, Type: "urn:miot-spec-v2:property:illumination:0000004E:test:1", Format: "float", Access: []string{"read"}, Unit: "lux"},
+			{IID: 3, Type: "urn:miot-spec-v2:property:battery-level:00000014:test:1", Format: "uint8", Access: []string{"read"}, Unit: "percentage"},
+			{IID: 4, Type: "urn:miot-spec-v2:property:temperature:00000020:test:1", Format: "float", Access: []string{"read"}, Unit: "celsius"},
+			{IID: 5, Type: "urn:miot-spec-v2:property:fault:00000009:test:1", Format: "uint8", Access: []string{"read"},
+				ValueList: []ValueItem{{Value: 0, Description: "No Faults"}, {Value: 1, Description: "Sensor Fault"}, {Value: 2, Description: "Low Battery"}, {Value: 4, Description: "Offline"}}},
+			{IID: 6, Type: "urn:miot-spec-v2:property:frequency:0000007D:test:1", Format: "uint32", Access: []string{"read"}, Unit: "hertz"},
+		},
+	}}}
+	for _, tc := range []struct {
+		piid        int
+		v           interface{}
+		name, label string
+		text        string
+	}{
+		{1, 1.0, "occupancy-sensor.occupancy-status", "Occupied", "Occupied"},
+		{1, 0, "occupancy-sensor.occupancy-status", "No One", "No One"},
+		{2, 12.5, "occupancy-sensor.illumination", "", "12.5lx"},
+		{3, 60.0, "occupancy-sensor.battery-level", "", "60%"},
+		{4, 23.5, "occupancy-sensor.temperature", "", "23.5°C"},
+		{5, 6.0, "occupancy-sensor.fault", "Low Battery | Offline", "Low Battery | Offline"},
+		{5, 9.0, "occupancy-sensor.fault", "Sensor Fault | 0x8", "Sensor Fault | 0x8"},
+		{6, 3.0, "occupancy-sensor.frequency", "", "3 hertz"},
+		{9, 7.0, "", "", "7"},
+	} {
+		r := spec.Decode(2, tc.piid, tc.v)
+		if r.Name != tc.name || r.Label != tc.label || r.Text != tc.text {
+			t.Errorf("piid %d value %v: got name=%q label=%q text=%q", tc.piid, tc.v, r.Name, r.Label, r.Text)
+		}
+	}
+	var none *ModelSpec
+	if r := none.Decode(2, 1, true); r.Text != "true" || r.Name != "" {
+		t.Errorf("nil spec: %+v", r)
+	}
+	if got := spec.ReadableProps(); len(got) != 6 || got[0] != [2]int{2, 1} {
+		t.Errorf("ReadableProps = %v", got)
+	}
+
+	// 翻译后描述与枚举使用 zh_CN，缺失的回退英文
+	spec.Localize(specs.Translation{"zh_cn": {
+		specs.PropertyKey(2, 1): "有无人状态",
+		specs.ValueKey(2, 1, 1): "有人",
+		specs.ValueKey(2, 5, 2): "电量低",
+	}}, "zh_CN")
+	if r := spec.Decode(2, 1, 1.0); r.Description != "有无人状态" || r.Text != "有人" {
+		t.Errorf("localized: %+v", r)
+	}
+	if r := spec.Decode(2, 5, 6.0); r.Label != "电量低 | Offline" {
+		t.Errorf("localized bitmask: %q", r.Label)
+	}
+	// 英文枚举描述保留，按名称取值不受影响
+	light := testLightSpec()
+	light.Localize(specs.Translation{"zh_cn": {specs.ValueKey(2, 2, 1): "夜间"}}, "zh_CN")
+	if got, err := light.ValidateProps([][3]interface{}{{2, 2, "night"}}); err != nil || got[0][2] != 1 {
+		t.Errorf("localized coercion: %v, %v", got, err)
+	}
+	spec.Localize(nil, "en")
+	if r := spec.Decode(2, 1, 1.0); r.Text != "Occupied" {
+		t.Errorf("en: %+v", r)
+	}
+}
+
+func TestReadProps(t *testing.T) {
+	cloud := devicetest.New(TransportCloud)
+	cloud.Devices = []map[string]interface{}{{"did": "1", "name": "台灯", "model": "test.light.v1"}}
+	cloud.Props[[2]int{2, 2}] = 1.0
+	cloud.Props[[2]int{2, 3}] = 60.0
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	api.specs = map[string]*ModelSpec{"test.light.v1": testLightSpec()}
+	got, err := api.ReadProps("1", [][2]int{{2, 2}, {2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Name != "light.mode" || got[0].Text != "Night" || got[1].Name != "light.brightness" || got[1].Value != 60.0 {
+		t.Errorf("ReadProps = %+v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package device
+
+import (
+	"context"
+	"errors"
+
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+// Registry 返回设备注册表，未启用时为 nil。
+func (a *API) Registry() *registry.Store {
+	return a.registry
+}
+
+// SetRegistry 设置设备注册表，Get/ResolveDID 将使用本地数据解析名称。
+func (a *API) SetRegistry(r *registry.Store) {
+	a.registry = r
+}
+
+// RefreshRegistry 重新拉取完整设备列表写入注册表，返回本次变化。
+func (a *API) RefreshRegistry() ([]registry.Change, error) {
+	return a.RefreshRegistryContext(context.Background())
+}
+
+// RefreshRegistryContext 同 RefreshRegistry，支持 ctx 取消。
+func (a *API) RefreshRegistryContext(ctx context.Context) ([]registry.Change, error) {
+	if a.registry == nil {
+		return nil, errors.New("device: registry not enabled")
+	}
+	t, err := a.listTransport()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := t.DeviceList(ctx, "", false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return a.registry.Sync(raw)
+}
@@ -0,0 +1,198 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+// 名称解析的匹配级别，按优先级从高到低。
+const (
+	MatchByDID      = "did"
+	MatchByName     = "name"
+	MatchByRoomName = "room+name"
+	MatchByAlias    = "alias"
+	MatchByFuzzy    = "fuzzy"
+)
+
+// AmbiguousDeviceError 表示同一匹配级别命中多个设备。
+type AmbiguousDeviceError struct {
+	Query      string
+	Match      string // 命中的匹配级别
+	Candidates []*Device
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, d := range e.Candidates {
+		if d.RoomName != "" {
+			names[i] = fmt.Sprintf("%s/%s(%s)", d.RoomName, d.Name, d.DID)
+		} else {
+			names[i] = fmt.Sprintf("%s(%s)", d.Name, d.DID)
+		}
+	}
+	return fmt.Sprintf("device: %q matches %d devices by %s: %s", e.Query, len(e.Candidates), e.Match, strings.Join(names, ", "))
+}
+
+// Resolve 在 list 中按 did > 名称 > 房间+名称 > 别名 > 模糊匹配 依次查找 q，
+// 返回最高级别的唯一命中及其级别；同级多个命中返回 *AmbiguousDeviceError。
+// aliases 为别名 → did 或名称。
+func Resolve(list []*Device, q string, aliases map[string]string) (*Device, string, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, "", fmt.Errorf("device: did or name required")
+	}
+	tier, cands := match(list, q, aliases)
+	switch len(cands) {
+	case 0:
+		return nil, "", fmt.Errorf("device not found: %s", q)
+	case 1:
+		return cands[0], tier, nil
+	}
+	return nil, tier, &AmbiguousDeviceError{Query: q, Match: tier, Candidates: cands}
+}
+
+// match 返回第一个有命中的级别及其候选。
+func match(list []*Device, q string, aliases map[string]string) (string, []*Device) {
+	if c := exact(list, q, false); len(c) > 0 {
+		return MatchByDID, c
+	}
+	if c := exact(list, q, true); len(c) > 0 {
+		return MatchByName, c
+	}
+	key := compact(q)
+	var c []*Device
+	for _, d := range list {
+		if d.RoomName != "" && compact(d.RoomName+d.Name) == key {
+			c = append(c, d)
+		}
+	}
+	if len(c) > 0 {
+		return MatchByRoomName, c
+	}
+	for alias, target := range aliases {
+		if strings.EqualFold(alias, q) {
+			if c = exact(list, target, false); len(c) == 0 {
+				c = exact(list, target, true)
+			}
+			break
+		}
+	}
+	if len(c) > 0 {
+		return MatchByAlias, c
+	}
+	for _, d := range list {
+		if MatchName(d, q) {
+			c = append(c, d)
+		}
+	}
+	return MatchByFuzzy, c
+}
+
+// exact 按 did（byName 为 false）或名称（不区分大小写）完全匹配。
+func exact(list []*Device, q string, byName bool) []*Device {
+	var out []*Device
+	for _, d := range list {
+		if !byName && d.DID == q || byName && strings.EqualFold(d.Name, q) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// compact 去掉空白与分隔符，使“客厅 台灯”“客厅/台灯”与“客厅台灯”等价。
+func compact(s string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '/', '-', '_', '·':
+			return -1
+		}
+		return r
+	}, s))
+}
+
+// Aliases 合并配置 miio.aliases 与注册表中的别名，后者优先；reg 可为 nil。
+func Aliases(reg *registry.Store) map[string]string {
+	out := make(map[string]string)
+	for k, v := range config.Get().MiIO.Aliases {
+		out[k] = v
+	}
+	if reg != nil {
+		if m, err := reg.Aliases(); err == nil {
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// Resolve 按排序规则将 did、名称、“房间 名称”或别名解析为单个设备。
+func (a *API) Resolve(q string) (*Device, error) {
+	return a.ResolveContext(context.Background(), q)
+}
+
+// ResolveContext 同 Resolve，支持 ctx 取消。启用注册表时候选来自本地索引，
+// 未命中时刷新一次注册表再查；多个设备同级命中返回 *AmbiguousDeviceError。
+func (a *API) ResolveContext(ctx context.Context, q string) (*Device, error) {
+	list, cached, err := a.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.resolveIn(ctx, list, q)
+	if err == nil || !cached {
+		return d, err
+	}
+	if _, ok := err.(*AmbiguousDeviceError); ok {
+		return nil, err
+	}
+	// 注册表可能尚未包含新设备
+	if _, rerr := a.RefreshRegistryContext(ctx); rerr != nil {
+		return nil, err
+	}
+	if list, _, err = a.candidates(ctx); err != nil {
+		return nil, err
+	}
+	return a.resolveIn(ctx, list, q)
+}
+
+// resolveIn 唯一的精确 did/名称命中不需要房间信息，否则先补全房间再完整匹配。
+func (a *API) resolveIn(ctx context.Context, list []*Device, q string) (*Device, error) {
+	if tier, c := match(list, strings.TrimSpace(q), nil); len(c) == 1 && (tier == MatchByDID || tier == MatchByName) {
+		return c[0], nil
+	}
+	a.annotateRooms(ctx, list)
+	d, _, err := Resolve(list, q, Aliases(a.registry))
+	return d, err
+}
+
+// candidates 返回全部设备：注册表可用时取本地数据（过期先刷新），否则请求设备列表。
+// cached 表示数据来自注册表且本次未刷新。
+func (a *API) candidates(ctx context.Context) (list []*Device, cached bool, err error) {
+	r := a.registry
+	if r != nil {
+		cached = true
+		if r.Stale() {
+			_, err := a.RefreshRegistryContext(ctx)
+			cached = err != nil
+		}
+		if !r.RefreshedAt().IsZero() {
+			raw, err := r.List()
+			if err == nil {
+				out := make([]*Device, 0, len(raw))
+				for _, m := range raw {
+					if d := FromMap(m); d != nil && d.DID != "" {
+						a.annotate(d)
+						out = append(out, d)
+					}
+				}
+				return out, cached, nil
+			}
+		}
+	}
+	list, err = a.ListContext(ctx, "", false, 0)
+	return list, false, err
+}
@@ -0,0 +1,45 @@
+package device
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveRanking(t *testing.T) {
+	list := []*Device{
+		{DID: "1", Name: "台灯", RoomName: "卧室"},
+		{DID: "2", Name: "台灯", RoomName: "书房"},
+		{DID: "3", Name: "吸顶灯", RoomName: "客厅"},
+		{DID: "4", Name: "灯带", RoomName: "客厅"},
+		{DID: "5", Name: "小爱音箱"},
+	}
+	aliases := map[string]string{"大灯": "3", "音箱": "小爱音箱"}
+	for _, tc := range []struct {
+		q, did, tier string
+	}{
+		{"4", "4", MatchByDID},
+		{"吸顶灯", "3", MatchByName},
+		{"书房 台灯", "2", MatchByRoomName},
+		{"卧室/台灯", "1", MatchByRoomName},
+		{"大灯", "3", MatchByAlias},
+		{"音箱", "5", MatchByAlias},
+		{"灯带", "4", MatchByName},
+		{"小爱", "5", MatchByFuzzy},
+	} {
+		d, tier, err := Resolve(list, tc.q, aliases)
+		if err != nil || d.DID != tc.did || tier != tc.tier {
+			t.Errorf("Resolve(%q) = %v, %s, %v; want %s by %s", tc.q, d, tier, err, tc.did, tc.tier)
+		}
+	}
+
+	for q, n := range map[string]int{"台灯": 2, "灯": 4} {
+		_, _, err := Resolve(list, q, aliases)
+		var amb *AmbiguousDeviceError
+		if !errors.As(err, &amb) || len(amb.Candidates) != n {
+			t.Errorf("Resolve(%q) err = %v, want %d candidates", q, err, n)
+		}
+	}
+	if _, _, err := Resolve(list, "空调", aliases); err == nil {
+		t.Error("Resolve(空调) should fail")
+	}
+}
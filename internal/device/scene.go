@@ -0,0 +1,114 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zeusro/miflow/internal/mihomeapi"
+)
+
+// Scene 为米家手动场景。
+type Scene = mihomeapi.Scene
+
+// SceneRunner 可选接口：列出并执行手动场景（云端通道实现）。
+type SceneRunner interface {
+	SceneListContext(ctx context.Context) ([]Scene, error)
+	RunSceneContext(ctx context.Context, sceneID string) error
+}
+
+// sceneRunner 返回可执行场景的通道，优先云端。
+func (a *API) sceneRunner() SceneRunner {
+	ts := a.transports
+	if c := a.transport(TransportCloud); c != nil {
+		ts = append([]Transport{c}, ts...)
+	}
+	for _, t := range ts {
+		if sr, ok := t.(SceneRunner); ok {
+			return sr
+		}
+		if mt, ok := t.(*miotTransport); ok {
+			if sr, ok := mt.svc.(SceneRunner); ok {
+				return sr
+			}
+		}
+	}
+	return nil
+}
+
+// Scenes 返回全部家庭的手动场景。
+func (a *API) Scenes() ([]Scene, error) {
+	return a.ScenesContext(context.Background())
+}
+
+// ScenesContext 同 Scenes，支持 ctx 取消。
+func (a *API) ScenesContext(ctx context.Context) ([]Scene, error) {
+	sr := a.sceneRunner()
+	if sr == nil {
+		return nil, errors.New("device: scenes require cloud service")
+	}
+	return sr.SceneListContext(ctx)
+}
+
+// RunScene 按场景 ID 或名称执行场景。
+func (a *API) RunScene(idOrName string) (*Scene, error) {
+	return a.RunSceneContext(context.Background(), idOrName)
+}
+
+// RunSceneContext 同 RunScene，支持 ctx 取消。返回实际执行的场景。
+func (a *API) RunSceneContext(ctx context.Context, idOrName string) (*Scene, error) {
+	sr := a.sceneRunner()
+	if sr == nil {
+		return nil, errors.New("device: scenes require cloud service")
+	}
+	list, err := sr.SceneListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := matchScene(list, idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if err := sr.RunSceneContext(ctx, sc.ID); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// matchScene 依次按 ID、名称完全匹配、唯一的名称包含查找场景。
+func matchScene(list []Scene, q string) (*Scene, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, errors.New("device: scene id or name required")
+	}
+	for i := range list {
+		if list[i].ID == q {
+			return &list[i], nil
+		}
+	}
+	var exact, partial []int
+	for i := range list {
+		switch {
+		case strings.EqualFold(list[i].Name, q):
+			exact = append(exact, i)
+		case strings.Contains(strings.ToLower(list[i].Name), strings.ToLower(q)):
+			partial = append(partial, i)
+		}
+	}
+	cand := exact
+	if len(cand) == 0 {
+		cand = partial
+	}
+	switch len(cand) {
+	case 0:
+		return nil, fmt.Errorf("device: scene %q not found", q)
+	case 1:
+		return &list[cand[0]], nil
+	}
+	names := make([]string, len(cand))
+	for i, j := range cand {
+		names[i] = fmt.Sprintf("%s(%s)", list[j].Name, list[j].ID)
+	}
+	return nil, fmt.Errorf("device: scene %q is ambiguous: %s", q, strings.Join(names, ", "))
+}
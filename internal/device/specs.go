@@ -1,13 +1,27 @@
 package device
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
-// LoadSpec 从 API 获取指定型号的 SPEC 并解析为 ModelSpec。
+// LoadSpec 从 API 获取指定型号的 SPEC 并解析为 ModelSpec，结果按型号缓存。
 func (a *API) LoadSpec(model string) (*ModelSpec, error) {
-	raw, err := a.Spec(model, "json")
+	return a.LoadSpecContext(context.Background(), model)
+}
+
+// LoadSpecContext 同 LoadSpec，支持 ctx 取消。
+func (a *API) LoadSpecContext(ctx context.Context, model string) (*ModelSpec, error) {
+	a.specsMu.Lock()
+	spec := a.specs[model]
+	a.specsMu.Unlock()
+	if spec != nil {
+		return spec, nil
+	}
+	raw, err := a.SpecContext(ctx, model, "json")
 	if err != nil {
 		return nil, err
 	}
@@ -15,7 +29,58 @@ func (a *API) LoadSpec(model string) (*ModelSpec, error) {
 	if !ok {
 		return nil, fmt.Errorf("device: invalid spec response for %s", model)
 	}
-	return parseModelSpec(m)
+	if spec, err = ParseModelSpec(m); err != nil {
+		return nil, err
+	}
+	a.localize(ctx, spec)
+	a.specsMu.Lock()
+	if a.specs == nil {
+		a.specs = make(map[string]*ModelSpec)
+	}
+	a.specs[model] = spec
+	a.specsMu.Unlock()
+	return spec, nil
+}
+
+// ModelSpec 返回设备 did 所属型号的 SPEC。
+func (a *API) ModelSpec(did string) (*ModelSpec, error) {
+	return a.ModelSpecContext(context.Background(), did)
+}
+
+// ModelSpecContext 同 ModelSpec，支持 ctx 取消。型号取自缓存的 did → model，没有时查注册表或设备列表。
+func (a *API) ModelSpecContext(ctx context.Context, did string) (*ModelSpec, error) {
+	if model := a.modelOf(did); model != "" {
+		return a.LoadSpecContext(ctx, model)
+	}
+	list, _, err := a.candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.rememberModels(list)
+	if model := a.modelOf(did); model != "" {
+		return a.LoadSpecContext(ctx, model)
+	}
+	return nil, fmt.Errorf("device: model of %s unknown", did)
+}
+
+func (a *API) modelOf(did string) string {
+	a.specsMu.Lock()
+	defer a.specsMu.Unlock()
+	return a.models[did]
+}
+
+// rememberModels 记录 list 中各设备的型号。
+func (a *API) rememberModels(list []*Device) {
+	a.specsMu.Lock()
+	defer a.specsMu.Unlock()
+	if a.models == nil {
+		a.models = make(map[string]string)
+	}
+	for _, d := range list {
+		if d.DID != "" && d.Model != "" {
+			a.models[d.DID] = d.Model
+		}
+	}
 }
 
 // LoadAllModelSpecs 获取 m list 中所有唯一型号的 SPEC，遵循 docs/spec.md 流程。
@@ -51,7 +116,8 @@ func uniqueModels(devs []*Device) []string {
 	return out
 }
 
-func parseModelSpec(m map[string]interface{}) (*ModelSpec, error) {
+// ParseModelSpec 将 miot-spec.org instance JSON 解析为 ModelSpec。
+func ParseModelSpec(m map[string]interface{}) (*ModelSpec, error) {
 	spec := &ModelSpec{
 		Type:        getStr(m, "type"),
 		Description: getStr(m, "description"),
@@ -69,21 +135,17 @@ func parseModelSpec(m map[string]interface{}) (*ModelSpec, error) {
 		}
 		for _, p := range toSlice(sm["properties"]) {
 			if pm, ok := p.(map[string]interface{}); ok {
-				svc.Properties = append(svc.Properties, PropSpec{
-					IID:         int(getFloat(pm, "iid")),
-					Description: getStr(pm, "description"),
-					Format:      getStr(pm, "format"),
-					Access:      getStrSlice(pm, "access"),
-				})
+				svc.Properties = append(svc.Properties, parsePropSpec(pm))
 			}
 		}
 		for _, a := range toSlice(sm["actions"]) {
 			if am, ok := a.(map[string]interface{}); ok {
 				svc.Actions = append(svc.Actions, ActionSpec{
 					IID:         int(getFloat(am, "iid")),
+					Type:        getStr(am, "type"),
 					Description: getStr(am, "description"),
-					In:          toSlice(am["in"]),
-					Out:         toSlice(am["out"]),
+					In:          getIntSlice(am, "in"),
+					Out:         getIntSlice(am, "out"),
 				})
 			}
 		}
@@ -91,8 +153,9 @@ func parseModelSpec(m map[string]interface{}) (*ModelSpec, error) {
 			if em, ok := e.(map[string]interface{}); ok {
 				svc.Events = append(svc.Events, EventSpec{
 					IID:         int(getFloat(em, "iid")),
+					Type:        getStr(em, "type"),
 					Description: getStr(em, "description"),
-					Arguments:   toSlice(em["arguments"]),
+					Arguments:   getIntSlice(em, "arguments"),
 				})
 			}
 		}
@@ -101,6 +164,37 @@ func parseModelSpec(m map[string]interface{}) (*ModelSpec, error) {
 	return spec, nil
 }
 
+// parsePropSpec 解析属性，含 value-range [min, max, step]、value-list 与 unit（unit 为 none 时置空）。
+func parsePropSpec(pm map[string]interface{}) PropSpec {
+	p := PropSpec{
+		IID:         int(getFloat(pm, "iid")),
+		Type:        getStr(pm, "type"),
+		Description: getStr(pm, "description"),
+		Format:      getStr(pm, "format"),
+		Access:      getStrSlice(pm, "access"),
+		Unit:        getStr(pm, "unit"),
+	}
+	if p.Unit == "none" {
+		p.Unit = ""
+	}
+	if r := toSlice(pm["value-range"]); len(r) >= 2 {
+		vr := &ValueRange{Min: num(r[0]), Max: num(r[1])}
+		if len(r) > 2 {
+			vr.Step = num(r[2])
+		}
+		p.ValueRange = vr
+	}
+	for _, it := range toSlice(pm["value-list"]) {
+		if vm, ok := it.(map[string]interface{}); ok {
+			p.ValueList = append(p.ValueList, ValueItem{
+				Value:       int(getFloat(vm, "value")),
+				Description: getStr(vm, "description"),
+			})
+		}
+	}
+	return p
+}
+
 func getStr(m map[string]interface{}, k string) string {
 	if v, ok := m[k].(string); ok {
 		return v
@@ -109,13 +203,7 @@ func getStr(m map[string]interface{}, k string) string {
 }
 
 func getFloat(m map[string]interface{}, k string) float64 {
-	switch v := m[k].(type) {
-	case float64:
-		return v
-	case int:
-		return float64(v)
-	}
-	return 0
+	return num(m[k])
 }
 
 func getStrSlice(m map[string]interface{}, k string) []string {
@@ -129,6 +217,25 @@ func getStrSlice(m map[string]interface{}, k string) []string {
 	return out
 }
 
+func getIntSlice(m map[string]interface{}, k string) []int {
+	raw := toSlice(m[k])
+	out := make([]int, 0, len(raw))
+	for _, r := range raw {
+		out = append(out, int(num(r)))
+	}
+	return out
+}
+
+func num(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case int:
+		return float64(x)
+	}
+	return 0
+}
+
 func toSlice(v interface{}) []interface{} {
 	if v == nil {
 		return nil
@@ -139,6 +246,149 @@ func toSlice(v interface{}) []interface{} {
 	return nil
 }
 
+// Service 按 siid 查找服务，未找到返回 nil。
+func (s *ModelSpec) Service(siid int) *ServiceSpec {
+	for i := range s.Services {
+		if s.Services[i].IID == siid {
+			return &s.Services[i]
+		}
+	}
+	return nil
+}
+
+// Property 按 siid/piid 查找属性，未找到返回 nil。
+func (s *ModelSpec) Property(siid, piid int) *PropSpec {
+	if svc := s.Service(siid); svc != nil {
+		return svc.Property(piid)
+	}
+	return nil
+}
+
+// Action 按 siid/aiid 查找动作，未找到返回 nil。
+func (s *ModelSpec) Action(siid, aiid int) *ActionSpec {
+	if svc := s.Service(siid); svc != nil {
+		for i := range svc.Actions {
+			if svc.Actions[i].IID == aiid {
+				return &svc.Actions[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Event 按 siid/eiid 查找事件，未找到返回 nil。
+func (s *ModelSpec) Event(siid, eiid int) *EventSpec {
+	if svc := s.Service(siid); svc != nil {
+		for i := range svc.Events {
+			if svc.Events[i].IID == eiid {
+				return &svc.Events[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Name 返回服务 URN 中的名称，如 light。
+func (s *ServiceSpec) Name() string { return urnName(s.Type) }
+
+// Property 按 piid 查找属性，未找到返回 nil。
+func (s *ServiceSpec) Property(piid int) *PropSpec {
+	for i := range s.Properties {
+		if s.Properties[i].IID == piid {
+			return &s.Properties[i]
+		}
+	}
+	return nil
+}
+
+// Name 返回属性 URN 中的名称，如 brightness。
+func (p *PropSpec) Name() string { return urnName(p.Type) }
+
+// Readable 报告属性是否可读。
+func (p *PropSpec) Readable() bool { return p.can("read") }
+
+// Writable 报告属性是否可写。
+func (p *PropSpec) Writable() bool { return p.can("write") }
+
+// Notifiable 报告属性变化是否推送。
+func (p *PropSpec) Notifiable() bool { return p.can("notify") }
+
+func (p *PropSpec) can(access string) bool {
+	for _, a := range p.Access {
+		if a == access {
+			return true
+		}
+	}
+	return false
+}
+
+// Name 返回动作 URN 中的名称，如 toggle。
+func (a *ActionSpec) Name() string { return urnName(a.Type) }
+
+// Name 返回事件 URN 中的名称。
+func (e *EventSpec) Name() string { return urnName(e.Type) }
+
+// LookupProperty 按“服务.属性”查找属性，如 light.on、speaker.volume；名称为 URN 名称段，不区分大小写。
+// 同名服务有多个时（如多键开关）可用 siid 代替服务名，如 3.on。
+func (s *ModelSpec) LookupProperty(name string) (*ServiceSpec, *PropSpec, error) {
+	svc, key, err := s.lookupService(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range svc.Properties {
+		if strings.EqualFold(svc.Properties[i].Name(), key) {
+			return svc, &svc.Properties[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("device: spec has no property %s", name)
+}
+
+// LookupAction 按“服务.动作”查找动作，如 play-control.pause，规则同 LookupProperty。
+func (s *ModelSpec) LookupAction(name string) (*ServiceSpec, *ActionSpec, error) {
+	svc, key, err := s.lookupService(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range svc.Actions {
+		if strings.EqualFold(svc.Actions[i].Name(), key) {
+			return svc, &svc.Actions[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("device: spec has no action %s", name)
+}
+
+// lookupService 拆分 name 并按服务名或 siid 查找服务，返回服务与剩余的属性/动作名。
+func (s *ModelSpec) lookupService(name string) (*ServiceSpec, string, error) {
+	i := strings.LastIndex(name, ".")
+	if i <= 0 || i == len(name)-1 {
+		return nil, "", fmt.Errorf("device: %q is not service.name", name)
+	}
+	sname, key := name[:i], name[i+1:]
+	if siid, err := strconv.Atoi(sname); err == nil {
+		if svc := s.Service(siid); svc != nil {
+			return svc, key, nil
+		}
+		return nil, "", fmt.Errorf("device: spec has no service %d", siid)
+	}
+	var found []*ServiceSpec
+	for j := range s.Services {
+		if strings.EqualFold(s.Services[j].Name(), sname) {
+			found = append(found, &s.Services[j])
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, "", fmt.Errorf("device: spec has no service %s", sname)
+	case 1:
+		return found[0], key, nil
+	}
+	siids := make([]string, len(found))
+	for j, svc := range found {
+		siids[j] = strconv.Itoa(svc.IID)
+	}
+	return nil, "", fmt.Errorf("device: service %s is ambiguous (siid %s), use <siid>.%s", sname, strings.Join(siids, ", "), key)
+}
+
 // Summary 返回 SPEC 的简要描述（服务数、属性数、动作数）。
 func (s *ModelSpec) Summary() string {
 	var props, actions int
@@ -153,3 +403,12 @@ func (s *ModelSpec) Summary() string {
 func (s *ModelSpec) ToJSON() ([]byte, error) {
 	return json.MarshalIndent(s, "", "  ")
 }
+
+// urnName 取 MIoT URN 的名称段，如 urn:miot-spec-v2:service:light:00007802:... → light。
+func urnName(urn string) string {
+	parts := strings.Split(urn, ":")
+	if len(parts) > 3 {
+		return parts[3]
+	}
+	return ""
+}
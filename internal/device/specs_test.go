@@ -1,7 +1,9 @@
 package device
 
 import (
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/zeusro/miflow/internal/config"
@@ -98,3 +100,88 @@ func TestLoadSpec(t *testing.T) {
 	}
 	t.Logf("bean.switch.bln31: %s", spec.Summary())
 }
+
+func TestParseModelSpec(t *testing.T) {
+	const raw = `{
+	"type": "urn:miot-spec-v2:device:light:0000A001:yeelink-ceiling4:1",
+	"description": "Light",
+	"services": [{
+		"iid": 2,
+		"type": "urn:miot-spec-v2:service:light:00007802:yeelink-ceiling4:1",
+		"description": "Light",
+		"properties": [
+			{"iid": 1, "type": "urn:miot-spec-v2:property:on:00000006:yeelink-ceiling4:1", "description": "Switch Status", "format": "bool", "access": ["read", "write", "notify"]},
+			{"iid": 2, "type": "urn:miot-spec-v2:property:mode:00000008:yeelink-ceiling4:1", "description": "Mode", "format": "uint8", "access": ["read", "write"], "unit": "none",
+			 "value-list": [{"value": 0, "description": "Day"}, {"value": 1, "description": "Night"}]},
+			{"iid": 3, "type": "urn:miot-spec-v2:property:brightness:0000000D:yeelink-ceiling4:1", "description": "Brightness", "format": "uint8", "access": ["read", "write", "notify"], "unit": "percentage",
+			 "value-range": [1, 100, 1]}
+		],
+		"actions": [{"iid": 1, "type": "urn:miot-spec-v2:action:toggle:00002811:yeelink-ceiling4:1", "description": "Toggle", "in": [], "out": []},
+			{"iid": 2, "type": "urn:miot-spec-v2:action:brightness-up:00002828:yeelink-ceiling4:1", "description": "Brightness Up", "in": [3], "out": [3]}],
+		"events": [{"iid": 1, "type": "urn:miot-spec-v2:event:low-battery:00005003:yeelink-ceiling4:1", "description": "Low Battery", "arguments": [1, 3]}]
+	}]
+}`
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := ParseModelSpec(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := spec.Service(2)
+	if svc == nil || svc.Name() != "light" {
+		t.Fatalf("service 2 = %+v", svc)
+	}
+	on := spec.Property(2, 1)
+	if on == nil || on.Name() != "on" || !on.Writable() || !on.Notifiable() || on.ValueRange != nil {
+		t.Errorf("on = %+v", on)
+	}
+	mode := spec.Property(2, 2)
+	if mode == nil || mode.Unit != "" || len(mode.ValueList) != 2 || mode.ValueList[1].Value != 1 || mode.ValueList[1].Description != "Night" {
+		t.Errorf("mode = %+v", mode)
+	}
+	br := spec.Property(2, 3)
+	if br == nil || br.Unit != "percentage" || br.ValueRange == nil || *br.ValueRange != (ValueRange{Min: 1, Max: 100, Step: 1}) {
+		t.Errorf("brightness = %+v", br)
+	}
+	if spec.Property(2, 9) != nil || spec.Property(3, 1) != nil {
+		t.Error("unknown siid/piid should be nil")
+	}
+	up := spec.Action(2, 2)
+	if up == nil || up.Name() != "brightness-up" || len(up.In) != 1 || up.In[0] != 3 || len(up.Out) != 1 {
+		t.Errorf("brightness-up = %+v", up)
+	}
+	if a := spec.Action(2, 1); a == nil || len(a.In) != 0 {
+		t.Errorf("toggle = %+v", a)
+	}
+	ev := spec.Event(2, 1)
+	if ev == nil || ev.Name() != "low-battery" || len(ev.Arguments) != 2 || ev.Arguments[1] != 3 {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+func TestLookupByName(t *testing.T) {
+	spec := testLightSpec()
+	spec.Services = append(spec.Services,
+		ServiceSpec{IID: 3, Type: "urn:miot-spec-v2:service:switch:0000780C:test:1", Properties: []PropSpec{{IID: 1, Type: "urn:miot-spec-v2:property:on:00000006:test:1"}}},
+		ServiceSpec{IID: 4, Type: "urn:miot-spec-v2:service:switch:0000780C:test:1", Properties: []PropSpec{{IID: 1, Type: "urn:miot-spec-v2:property:on:00000006:test:1"}}},
+	)
+	if svc, p, err := spec.LookupProperty("Light.Brightness"); err != nil || svc.IID != 2 || p.IID != 3 {
+		t.Errorf("light.brightness: %v %v %v", svc, p, err)
+	}
+	if svc, a, err := spec.LookupAction("light.play-text"); err != nil || svc.IID != 2 || a.IID != 2 {
+		t.Errorf("light.play-text: %v %v %v", svc, a, err)
+	}
+	if _, _, err := spec.LookupProperty("switch.on"); err == nil || !strings.Contains(err.Error(), "siid 3, 4") {
+		t.Errorf("switch.on should be ambiguous, got %v", err)
+	}
+	if svc, _, err := spec.LookupProperty("4.on"); err != nil || svc.IID != 4 {
+		t.Errorf("4.on: %v %v", svc, err)
+	}
+	for _, name := range []string{"light.volume", "fan.on", "on", "light."} {
+		if _, _, err := spec.LookupProperty(name); err == nil {
+			t.Errorf("%s should not resolve", name)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/discovery"
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/miiolocal"
+	"github.com/zeusro/miflow/internal/miioservice"
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+// Transport 为设备通信通道：云端（ha.api.io.mi.com）或局域网（miIO UDP）。
+type Transport interface {
+	Name() string
+	GetProps(ctx context.Context, did string, iids [][2]int) ([]interface{}, error)
+	SetProps(ctx context.Context, did string, props [][3]interface{}) ([]int, error)
+	Action(ctx context.Context, did string, siid, aiid int, in []interface{}) (int, error)
+	DeviceList(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error)
+}
+
+// Reacher 可选接口：报告 Transport 能否访问某设备（如局域网是否已知其地址）。
+type Reacher interface {
+	Reaches(ctx context.Context, did string) bool
+}
+
+// 内置 Transport 名称。
+const (
+	TransportCloud = "cloud"
+	TransportLocal = "local"
+)
+
+// Policy 为 Transport 选择策略。
+type Policy string
+
+const (
+	PolicyCloud Policy = "cloud" // 只走云端
+	PolicyLocal Policy = "local" // 只走局域网
+	PolicyAuto  Policy = "auto"  // 优先局域网，失败或不可达时回退云端
+)
+
+// ParsePolicy 解析策略字符串，空值为 cloud。
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "", PolicyCloud:
+		return PolicyCloud, nil
+	case PolicyLocal, PolicyAuto:
+		return p, nil
+	}
+	return "", fmt.Errorf("device: unknown transport policy %q (cloud|local|auto)", s)
+}
+
+// Route 记录一次调用实际使用的 Transport。
+type Route struct {
+	DID       string    `json:"did"`
+	Op        string    `json:"op"`        // get_props|set_props|action|device_list
+	Transport string    `json:"transport"` // cloud|local
+	Fallback  bool      `json:"fallback,omitempty"`
+	Error     string    `json:"error,omitempty"` // 回退前局域网的错误
+	Time      time.Time `json:"time"`
+}
+
+// miotTransport 将 miioservice.MiotService 适配为 Transport。
+type miotTransport struct {
+	name string
+	svc  miioservice.MiotService
+}
+
+// NewMiotTransport 将实现 miioservice.MiotService 的服务包装为 Transport。
+func NewMiotTransport(name string, svc miioservice.MiotService) Transport {
+	return &miotTransport{name: name, svc: svc}
+}
+
+func (t *miotTransport) Name() string { return t.name }
+
+func (t *miotTransport) GetProps(ctx context.Context, did string, iids [][2]int) ([]interface{}, error) {
+	return t.svc.MiotGetPropsContext(ctx, did, iids)
+}
+
+func (t *miotTransport) SetProps(ctx context.Context, did string, props [][3]interface{}) ([]int, error) {
+	return t.svc.MiotSetPropsContext(ctx, did, props)
+}
+
+func (t *miotTransport) Action(ctx context.Context, did string, siid, aiid int, in []interface{}) (int, error) {
+	return t.svc.MiotActionContext(ctx, did, siid, aiid, in)
+}
+
+func (t *miotTransport) DeviceList(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	return t.svc.DeviceListContext(ctx, name, getVirtualModel, getHuamiDevices)
+}
+
+// Reaches 委托给底层服务的 HasContext（如 miiolocal.Service），没有时视为可达。
+func (t *miotTransport) Reaches(ctx context.Context, did string) bool {
+	if h, ok := t.svc.(interface {
+		HasContext(context.Context, string) bool
+	}); ok {
+		return h.HasContext(ctx, did)
+	}
+	return true
+}
+
+// NewAPIWithTransports 使用给定 Transport 与策略创建 API，可用于注入测试用 Transport。
+// 名称为 cloud/local 的 Transport 分别作为云端与局域网通道；其他名称在策略为 cloud 时也可作为唯一通道。
+func NewAPIWithTransports(policy Policy, ts ...Transport) *API {
+	return &API{policy: policy, transports: ts, routes: make(map[string]Route)}
+}
+
+// NewAPIFromConfig 按 miio.transport 配置创建 API：cloud 只用 io；local/auto 额外创建局域网通道，
+// 设备地址来自 miio.local_addrs 或局域网发现，token 取自云端设备列表。
+func NewAPIFromConfig(io *miioservice.Service) (*API, error) {
+	cfg := config.Get().MiIO
+	policy, err := ParsePolicy(cfg.Transport)
+	if err != nil {
+		return nil, err
+	}
+	a := NewAPI(io)
+	a.policy = policy
+	a.discovery = discovery.NewTable()
+	a.registry = registry.Default()
+	a.validate = cfg.ValidateSpec
+	a.locale = cfg.SpecLocale
+	if policy == PolicyCloud {
+		return a, nil
+	}
+	l := newTokenLookup(io, cfg.LocalAddrs)
+	l.table = a.discovery
+	if cfg.DiscoveryTimeoutMS >= 0 {
+		l.scanner = discovery.NewScanner()
+		l.scanner.Table = a.discovery
+		if cfg.DiscoveryTimeoutMS > 0 {
+			l.scanner.Timeout = time.Duration(cfg.DiscoveryTimeoutMS) * time.Millisecond
+		}
+	}
+	local := miiolocal.New()
+	local.Lookup = l.lookup
+	a.transports = append(a.transports, NewMiotTransport(TransportLocal, local))
+	return a, nil
+}
+
+// tokenLookup 根据配置的 did→IP（或发现表中的 IP）与云端设备列表中的 token 提供局域网地址。
+type tokenLookup struct {
+	io    *miioservice.Service
+	addrs map[string]string
+
+	table   *discovery.Table   // 发现表，可为 nil
+	scanner *discovery.Scanner // 未知设备时扫描一次，可为 nil
+
+	scanMu  sync.Mutex
+	scanned bool // 已完成一次未被取消的扫描
+
+	tokensMu sync.Mutex
+	tokens   map[string]string // 成功拉取设备列表后才设置
+}
+
+func newTokenLookup(io *miioservice.Service, addrs map[string]string) *tokenLookup {
+	return &tokenLookup{io: io, addrs: addrs}
+}
+
+// addr 优先使用配置地址，其次发现表；都没有时用调用方的 ctx 触发一次局域网扫描，
+// 扫描被取消时下次查询会重试。
+func (l *tokenLookup) addr(ctx context.Context, did string) string {
+	if addr := l.addrs[did]; addr != "" {
+		return addr
+	}
+	if l.table == nil {
+		return ""
+	}
+	if e, ok := l.table.Lookup(did); ok {
+		return e.IP
+	}
+	if l.scanner != nil {
+		l.scanMu.Lock()
+		if !l.scanned {
+			_, _ = l.scanner.Scan(ctx)
+			l.scanned = ctx.Err() == nil
+		}
+		l.scanMu.Unlock()
+		if e, ok := l.table.Lookup(did); ok {
+			return e.IP
+		}
+	}
+	return ""
+}
+
+func (l *tokenLookup) lookup(ctx context.Context, did string) (string, string, bool) {
+	addr := l.addr(ctx, did)
+	if addr == "" {
+		return "", "", false
+	}
+	token, ok := l.token(ctx, did)
+	return addr, token, ok
+}
+
+// token 从云端设备列表取 token，列表只拉取一次；失败（含 ctx 取消）时下次查询重试。
+func (l *tokenLookup) token(ctx context.Context, did string) (string, bool) {
+	l.tokensMu.Lock()
+	defer l.tokensMu.Unlock()
+	if l.tokens == nil && l.io != nil {
+		list, err := l.io.DeviceListContext(ctx, "", false, 0)
+		if err != nil {
+			return "", false
+		}
+		l.tokens = make(map[string]string)
+		for _, m := range list {
+			d := FromMap(m)
+			if d != nil && d.Token != "" {
+				l.tokens[d.DID] = d.Token
+			}
+		}
+	}
+	token, ok := l.tokens[did]
+	return token, ok
+}
+
+func (a *API) transport(name string) Transport {
+	for _, t := range a.transports {
+		if t.Name() == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// pick 按策略返回主通道与回退通道（可为 nil）。
+func (a *API) pick(ctx context.Context, did string) (primary, fallback Transport, err error) {
+	cloud, local := a.transport(TransportCloud), a.transport(TransportLocal)
+	switch a.policy {
+	case PolicyLocal:
+		if local == nil {
+			return nil, nil, errors.New("device: no local transport configured")
+		}
+		return local, nil, nil
+	case PolicyAuto:
+		if local != nil && reaches(ctx, local, did) {
+			return local, cloud, nil
+		}
+		if cloud != nil {
+			return cloud, nil, nil
+		}
+		if local != nil {
+			return local, nil, nil
+		}
+	default:
+		if cloud != nil {
+			return cloud, nil, nil
+		}
+	}
+	if len(a.transports) > 0 {
+		return a.transports[0], nil, nil
+	}
+	return nil, nil, errors.New("device: no transport configured")
+}
+
+func reaches(ctx context.Context, t Transport, did string) bool {
+	if r, ok := t.(Reacher); ok {
+		return r.Reaches(ctx, did)
+	}
+	return true
+}
+
+// route 选择通道执行 fn 并记录 Route。auto 策略下局域网失败（非 ctx 取消）时回退云端：
+// 读操作任何错误都回退；写操作（set_props、action）只在请求未发出时回退，避免超时后重复执行。
+func (a *API) route(ctx context.Context, did, op string, fn func(Transport) error) error {
+	primary, fallback, err := a.pick(ctx, did)
+	if err != nil {
+		return err
+	}
+	r := Route{DID: did, Op: op, Transport: primary.Name(), Time: time.Now()}
+	err = fn(primary)
+	if err != nil && fallback != nil && ctx.Err() == nil && (!isWrite(op) || miaccount.NotSent(err)) {
+		r.Fallback = true
+		r.Error = err.Error()
+		r.Transport = fallback.Name()
+		err = fn(fallback)
+	}
+	a.recordRoute(r)
+	return err
+}
+
+func isWrite(op string) bool { return op == "set_props" || op == "action" }
+
+func (a *API) recordRoute(r Route) {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+	if a.routes == nil {
+		a.routes = make(map[string]Route)
+	}
+	a.routes[r.DID] = r
+	a.lastRoute = r
+}
+
+// LastRoute 返回该设备最近一次调用使用的通道。
+func (a *API) LastRoute(did string) (Route, bool) {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+	r, ok := a.routes[did]
+	return r, ok
+}
+
+// RecentRoute 返回最近一次调用（任意设备）使用的通道，仅适用于单次命令的 CLI；
+// 并发场景（如 web）请用 LastRoute。
+func (a *API) RecentRoute() (Route, bool) {
+	a.routesMu.Lock()
+	defer a.routesMu.Unlock()
+	return a.lastRoute, !a.lastRoute.Time.IsZero()
+}
+
+// Policy 返回当前通道策略。
+func (a *API) Policy() Policy { return a.policy }
+
+// Discovery 返回局域网发现表，未启用时为 nil。
+func (a *API) Discovery() *discovery.Table {
+	a.discoveryMu.Lock()
+	defer a.discoveryMu.Unlock()
+	return a.discovery
+}
+
+// SetDiscovery 设置局域网发现表，List 结果据此填充 LocalIP 与 LastSeen。
+func (a *API) SetDiscovery(t *discovery.Table) {
+	a.discoveryMu.Lock()
+	defer a.discoveryMu.Unlock()
+	a.discovery = t
+}
+
+// EnsureDiscovery 返回局域网发现表，未启用时创建并设置一个；并发调用得到同一个表。
+func (a *API) EnsureDiscovery() *discovery.Table {
+	a.discoveryMu.Lock()
+	defer a.discoveryMu.Unlock()
+	if a.discovery == nil {
+		a.discovery = discovery.NewTable()
+	}
+	return a.discovery
+}
+
+// Cloud 返回云端 miioservice（用于 SPEC、原始 MiIO 调用等），未配置时为 nil。
+func (a *API) Cloud() *miioservice.Service { return a.io }
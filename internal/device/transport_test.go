@@ -0,0 +1,331 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/device/devicetest"
+	"github.com/zeusro/miflow/internal/discovery"
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/registry"
+)
+
+func TestRoutePolicyCloud(t *testing.T) {
+	cloud, local := devicetest.New(TransportCloud), devicetest.New(TransportLocal)
+	api := NewAPIWithTransports(PolicyCloud, cloud, local)
+	if _, err := api.SetProps("1", [][3]interface{}{{2, 1, true}}); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.Calls != 1 || local.Calls != 0 {
+		t.Errorf("calls cloud=%d local=%d", cloud.Calls, local.Calls)
+	}
+	if r, _ := api.LastRoute("1"); r.Transport != TransportCloud || r.Op != "set_props" {
+		t.Errorf("route = %+v", r)
+	}
+}
+
+func TestRoutePolicyAutoPrefersLocal(t *testing.T) {
+	cloud, local := devicetest.New(TransportCloud), devicetest.New(TransportLocal)
+	local.Reach = map[string]bool{"1": true}
+	local.Props[[2]int{2, 1}] = true
+	api := NewAPIWithTransports(PolicyAuto, cloud, local)
+
+	vals, err := api.GetProps("1", [][2]int{{2, 1}})
+	if err != nil || vals[0] != true {
+		t.Fatalf("GetProps = %v, %v", vals, err)
+	}
+	if r, _ := api.LastRoute("1"); r.Transport != TransportLocal || r.Fallback {
+		t.Errorf("route = %+v", r)
+	}
+
+	// 局域网不可达的设备直接走云端
+	if _, err := api.GetProps("2", [][2]int{{2, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := api.LastRoute("2"); r.Transport != TransportCloud || r.Fallback {
+		t.Errorf("route = %+v", r)
+	}
+}
+
+func TestRoutePolicyAutoFallback(t *testing.T) {
+	cloud, local := devicetest.New(TransportCloud), devicetest.New(TransportLocal)
+	local.Fail = errors.New("timeout")
+	api := NewAPIWithTransports(PolicyAuto, cloud, local)
+
+	// 读操作回退云端
+	if _, err := api.GetProps("1", [][2]int{{2, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := api.LastRoute("1")
+	if !ok || r.Transport != TransportCloud || !r.Fallback || r.Error != "timeout" {
+		t.Errorf("route = %+v", r)
+	}
+	if local.Calls != 1 || cloud.Calls != 1 {
+		t.Errorf("calls cloud=%d local=%d", cloud.Calls, local.Calls)
+	}
+
+	// 已发出后超时的写操作不能再经云端发送一次
+	local.Calls, cloud.Calls = 0, 0
+	for _, write := range []func() error{
+		func() error { _, err := api.Action("1", 5, 1, nil); return err },
+		func() error { _, err := api.SetProps("1", [][3]interface{}{{2, 1, true}}); return err },
+	} {
+		if err := write(); err == nil || err.Error() != "timeout" {
+			t.Errorf("write err = %v, want local timeout", err)
+		}
+	}
+	if local.Calls != 2 || cloud.Calls != 0 {
+		t.Errorf("write calls cloud=%d local=%d, want no resend", cloud.Calls, local.Calls)
+	}
+	if r, _ := api.LastRoute("1"); r.Transport != TransportLocal || r.Fallback {
+		t.Errorf("route = %+v", r)
+	}
+
+	// 请求未发出（如握手失败）时写操作可以回退
+	local.Fail = fmt.Errorf("handshake: %w", miaccount.ErrNotSent)
+	if _, err := api.Action("1", 5, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.Calls != 1 {
+		t.Errorf("not-sent action should fall back, cloud calls = %d", cloud.Calls)
+	}
+}
+
+func TestRoutePolicyLocalNoFallback(t *testing.T) {
+	cloud, local := devicetest.New(TransportCloud), devicetest.New(TransportLocal)
+	local.Fail = errors.New("timeout")
+	api := NewAPIWithTransports(PolicyLocal, cloud, local)
+	if _, err := api.Action("1", 5, 1, nil); err == nil {
+		t.Fatal("local policy should not fall back")
+	}
+	if cloud.Calls != 0 {
+		t.Errorf("cloud called %d times", cloud.Calls)
+	}
+	if _, err := NewAPIWithTransports(PolicyLocal, cloud).Action("1", 5, 1, nil); err == nil {
+		t.Error("local policy without local transport should fail")
+	}
+}
+
+func TestListWithFakeTransport(t *testing.T) {
+	cloud := devicetest.New(TransportCloud)
+	cloud.Devices = []map[string]interface{}{{"did": "1", "name": "台灯", "model": "x.light.v1"}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	d, err := api.Get("台灯")
+	if err != nil || d.DID != "1" {
+		t.Fatalf("Get = %v, %v", d, err)
+	}
+	if _, err := api.Spec("x.light.v1", "json"); err == nil {
+		t.Error("Spec without cloud service should fail")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"": PolicyCloud, "AUTO": PolicyAuto, "local": PolicyLocal} {
+		if got, err := ParsePolicy(in); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePolicy("lan"); err == nil {
+		t.Error("ParsePolicy(lan) should fail")
+	}
+}
+
+func TestListAnnotatesDiscovery(t *testing.T) {
+	cloud := devicetest.New(TransportCloud)
+	cloud.Devices = []map[string]interface{}{{"did": "1", "name": "台灯"}, {"did": "2", "name": "插座"}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	tbl := discovery.NewTable()
+	tbl.Observe(discovery.Entry{DID: "1", IP: "192.168.1.20", Source: discovery.SourceHello})
+	api.SetDiscovery(tbl)
+	list, err := api.List("", false, 0)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	if list[0].LocalIP != "192.168.1.20" || list[0].LastSeen == nil {
+		t.Errorf("device 1 = %+v", list[0])
+	}
+	if list[1].LocalIP != "" || list[1].LastSeen != nil {
+		t.Errorf("device 2 = %+v", list[1])
+	}
+}
+
+// homeFake 为实现 HomeLister 的云端假通道。
+type homeFake struct {
+	*devicetest.Transport
+	homes     []Home
+	fail      error
+	delay     time.Duration
+	homeCalls atomic.Int32
+}
+
+func (h *homeFake) HomeListContext(ctx context.Context) ([]Home, error) {
+	h.homeCalls.Add(1)
+	time.Sleep(h.delay)
+	return h.homes, h.fail
+}
+
+func TestListRoomsAndFilter(t *testing.T) {
+	cloud := &homeFake{Transport: devicetest.New(TransportCloud)}
+	cloud.Devices = []map[string]interface{}{
+		{"did": "1", "name": "吸顶灯", "model": "yeelink.light.ceiling1", "isOnline": true},
+		{"did": "2", "name": "台灯", "model": "yeelink.light.lamp1", "isOnline": false},
+		{"did": "3", "name": "净化器", "model": "zhimi.airpurifier.mb3", "isOnline": true, "parent_id": "9"},
+	}
+	cloud.homes = []Home{{ID: "100", Name: "我的家", DIDs: []string{"3"}, Rooms: []Room{
+		{ID: "101", Name: "客厅", HomeID: "100", DIDs: []string{"1"}},
+		{ID: "102", Name: "卧室", HomeID: "100", DIDs: []string{"2"}},
+	}}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+
+	list, err := api.List("", false, 0)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("List = %v, %v", list, err)
+	}
+	if d := list[0]; d.HomeID != "100" || d.RoomID != "101" || d.RoomName != "客厅" || !d.IsOnline {
+		t.Errorf("device 1 = %+v", d)
+	}
+	if d := list[2]; d.HomeID != "100" || d.RoomName != "" || d.ParentDID != "9" {
+		t.Errorf("device 3 = %+v", d)
+	}
+
+	lights, err := api.Find(Filter{Room: "客厅", Model: "light"})
+	if err != nil || len(lights) != 1 || lights[0].DID != "1" {
+		t.Errorf("Find(客厅, light) = %v, %v", lights, err)
+	}
+	online := true
+	if got, _ := api.Find(Filter{Home: "我的家", Online: &online}); len(got) != 2 {
+		t.Errorf("Find(我的家, online) = %d devices", len(got))
+	}
+	if n := cloud.homeCalls.Load(); n != 1 {
+		t.Errorf("HomeList called %d times, want cached", n)
+	}
+}
+
+func TestHomesCacheFailureAndConcurrency(t *testing.T) {
+	cloud := &homeFake{Transport: devicetest.New(TransportCloud), fail: errors.New("unauthorized")}
+	cloud.Devices = []map[string]interface{}{{"did": "1", "name": "吸顶灯"}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+
+	// 获取家庭失败时列表仍可用，且重试间隔内不再请求 /gethome
+	for i := 0; i < 3; i++ {
+		if list, err := api.List("", false, 0); err != nil || len(list) != 1 || list[0].RoomID != "" {
+			t.Fatalf("List = %v, %v", list, err)
+		}
+	}
+	if n := cloud.homeCalls.Load(); n != 1 {
+		t.Errorf("failing HomeList called %d times, want 1", n)
+	}
+
+	// 并发调用共享一次在途请求
+	cloud = &homeFake{Transport: devicetest.New(TransportCloud), delay: 20 * time.Millisecond,
+		homes: []Home{{ID: "100", Name: "我的家"}}}
+	api = NewAPIWithTransports(PolicyCloud, cloud)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if homes, err := api.Homes(); err != nil || len(homes) != 1 {
+				t.Errorf("Homes = %v, %v", homes, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := cloud.homeCalls.Load(); n != 1 {
+		t.Errorf("concurrent HomeList called %d times, want 1", n)
+	}
+}
+
+// sceneFake 为实现 SceneRunner 的云端假通道。
+type sceneFake struct {
+	*devicetest.Transport
+	scenes []Scene
+	ran    []string
+}
+
+func (s *sceneFake) SceneListContext(ctx context.Context) ([]Scene, error) {
+	return s.scenes, nil
+}
+
+func (s *sceneFake) RunSceneContext(ctx context.Context, id string) error {
+	s.ran = append(s.ran, id)
+	return nil
+}
+
+func TestRunScene(t *testing.T) {
+	cloud := &sceneFake{Transport: devicetest.New(TransportCloud), scenes: []Scene{
+		{ID: "11", Name: "回家", HomeID: "100"},
+		{ID: "12", Name: "离家", HomeID: "100"},
+		{ID: "13", Name: "回家模式", HomeID: "100"},
+	}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+
+	for q, want := range map[string]string{"12": "12", "回家": "11", "模式": "13"} {
+		sc, err := api.RunScene(q)
+		if err != nil || sc.ID != want {
+			t.Errorf("RunScene(%q) = %v, %v; want %s", q, sc, err, want)
+		}
+	}
+	if len(cloud.ran) != 3 {
+		t.Errorf("ran = %v", cloud.ran)
+	}
+	if _, err := api.RunScene("家"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("RunScene(家) err = %v, want ambiguous", err)
+	}
+	if _, err := api.RunScene("睡觉"); err == nil {
+		t.Error("RunScene(睡觉) should fail")
+	}
+	if _, err := NewAPIWithTransports(PolicyLocal, devicetest.New(TransportLocal)).Scenes(); err == nil {
+		t.Error("Scenes without cloud should fail")
+	}
+}
+
+func TestGetUsesRegistry(t *testing.T) {
+	reg, err := registry.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg.Close()
+	cloud := devicetest.New(TransportCloud)
+	cloud.Devices = []map[string]interface{}{
+		{"did": "1", "name": "吸顶灯", "model": "yeelink.light.ceiling1"},
+		{"did": "2", "name": "台灯", "model": "yeelink.light.lamp1"},
+	}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	api.SetRegistry(reg)
+
+	if did, err := api.ResolveDID("台灯"); err != nil || did != "2" {
+		t.Fatalf("ResolveDID(台灯) = %q, %v", did, err)
+	}
+	// 注册表未过期时不再请求设备列表
+	cloud.Fail = errors.New("offline")
+	if d, err := api.Get("吸顶灯"); err != nil || d.DID != "1" {
+		t.Errorf("Get(吸顶灯) = %v, %v", d, err)
+	}
+	if _, err := api.RefreshRegistry(); err == nil {
+		t.Error("RefreshRegistry should surface list error")
+	}
+}
+
+func TestEnsureDiscoveryConcurrent(t *testing.T) {
+	api := NewAPIWithTransports(PolicyCloud, devicetest.New(TransportCloud))
+	tables := make(chan *discovery.Table, 8)
+	for i := 0; i < cap(tables); i++ {
+		go func() { tables <- api.EnsureDiscovery() }()
+	}
+	first := <-tables
+	for i := 1; i < cap(tables); i++ {
+		if tbl := <-tables; tbl != first {
+			t.Fatal("EnsureDiscovery returned different tables")
+		}
+	}
+	if api.Discovery() != first {
+		t.Error("Discovery should return the ensured table")
+	}
+}
@@ -0,0 +1,265 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SpecError 表示写入的值或动作参数不符合 SPEC。
+type SpecError struct {
+	SIID   int
+	IID    int    // piid 或 aiid
+	Name   string // 如 light.brightness，SPEC 中无此项时为空
+	Reason string
+}
+
+func (e *SpecError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("device: %s (%d.%d): %s", e.Name, e.SIID, e.IID, e.Reason)
+	}
+	return fmt.Sprintf("device: %d.%d: %s", e.SIID, e.IID, e.Reason)
+}
+
+// 整数格式的取值范围。
+var intBounds = map[string][2]float64{
+	"uint8":  {0, math.MaxUint8},
+	"uint16": {0, math.MaxUint16},
+	"uint32": {0, math.MaxUint32},
+	"int8":   {math.MinInt8, math.MaxInt8},
+	"int16":  {math.MinInt16, math.MaxInt16},
+	"int32":  {math.MinInt32, math.MaxInt32},
+	"int64":  {math.MinInt64, math.MaxInt64},
+}
+
+// SetValidate 开启或关闭写属性/动作前的 SPEC 校验，默认取配置 miio.validate_spec。
+func (a *API) SetValidate(on bool) {
+	a.validate = on
+}
+
+// ValidateProps 按 SPEC 检查 [siid, piid, value] 三元组：属性存在且可写、格式、范围、步长与枚举值，
+// 返回转换后的副本（如 "60" → 60、1 → true、枚举描述 → 值）。
+func (s *ModelSpec) ValidateProps(props [][3]interface{}) ([][3]interface{}, error) {
+	out := make([][3]interface{}, len(props))
+	for i, p := range props {
+		siid, piid := toInt(p[0]), toInt(p[1])
+		prop := s.Property(siid, piid)
+		if prop == nil {
+			return nil, &SpecError{SIID: siid, IID: piid, Reason: "property not found in spec"}
+		}
+		if !prop.Writable() {
+			return nil, s.propError(siid, prop, "property is not writable")
+		}
+		v, err := prop.Coerce(p[2])
+		if err != nil {
+			return nil, s.propError(siid, prop, err.Error())
+		}
+		out[i] = [3]interface{}{p[0], p[1], v}
+	}
+	return out, nil
+}
+
+// ValidateAction 按 SPEC 检查动作存在、参数个数与 in 中各属性的取值，返回转换后的参数。
+func (s *ModelSpec) ValidateAction(siid, aiid int, in []interface{}) ([]interface{}, error) {
+	act := s.Action(siid, aiid)
+	if act == nil {
+		return nil, &SpecError{SIID: siid, IID: aiid, Reason: "action not found in spec"}
+	}
+	name := s.Service(siid).Name() + "." + act.Name()
+	if len(in) != len(act.In) {
+		return nil, &SpecError{SIID: siid, IID: aiid, Name: name, Reason: fmt.Sprintf("expects %d argument(s), got %d", len(act.In), len(in))}
+	}
+	out := make([]interface{}, len(in))
+	for i, v := range in {
+		prop := s.Property(siid, act.In[i])
+		if prop == nil {
+			out[i] = v
+			continue
+		}
+		cv, err := prop.Coerce(v)
+		if err != nil {
+			return nil, &SpecError{SIID: siid, IID: aiid, Name: name, Reason: fmt.Sprintf("argument %d (%s): %v", i+1, prop.Name(), err)}
+		}
+		out[i] = cv
+	}
+	return out, nil
+}
+
+func (s *ModelSpec) propError(siid int, p *PropSpec, reason string) *SpecError {
+	name := p.Name()
+	if name == "" {
+		name = p.Description
+	}
+	if svc := s.Service(siid); svc != nil && svc.Name() != "" {
+		name = svc.Name() + "." + name
+	}
+	return &SpecError{SIID: siid, IID: p.IID, Name: name, Reason: reason}
+}
+
+// Coerce 将 v 转换为属性格式并检查范围、步长与枚举值，不安全的转换返回错误。
+func (p *PropSpec) Coerce(v interface{}) (interface{}, error) {
+	switch p.Format {
+	case "bool":
+		return coerceBool(v)
+	case "string", "hex":
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case int, int64, float64:
+			return fmt.Sprint(x), nil
+		}
+		return nil, fmt.Errorf("want %s, got %T", p.Format, v)
+	case "float":
+		f, err := coerceNumber(v, p.ValueList)
+		if err != nil {
+			return nil, err
+		}
+		return f, p.checkRange(f)
+	}
+	bounds, ok := intBounds[p.Format]
+	if !ok {
+		return v, nil // 未知格式不校验
+	}
+	f, err := coerceNumber(v, p.ValueList)
+	if err != nil {
+		return nil, err
+	}
+	if f != math.Trunc(f) {
+		return nil, fmt.Errorf("want %s, got %v", p.Format, v)
+	}
+	if f < bounds[0] || f > bounds[1] {
+		return nil, fmt.Errorf("%v overflows %s", v, p.Format)
+	}
+	if err := p.checkRange(f); err != nil {
+		return nil, err
+	}
+	if len(p.ValueList) > 0 && !p.inList(int(f)) {
+		return nil, fmt.Errorf("%v not in value-list %s", v, p.listString())
+	}
+	return int(f), nil
+}
+
+func (p *PropSpec) checkRange(f float64) error {
+	r := p.ValueRange
+	if r == nil {
+		return nil
+	}
+	if f < r.Min || f > r.Max {
+		return fmt.Errorf("%v out of range [%v, %v]", f, r.Min, r.Max)
+	}
+	if r.Step > 0 {
+		n := (f - r.Min) / r.Step
+		if math.Abs(n-math.Round(n)) > 1e-6 {
+			return fmt.Errorf("%v is not a multiple of step %v from %v", f, r.Step, r.Min)
+		}
+	}
+	return nil
+}
+
+func (p *PropSpec) inList(v int) bool {
+	for _, it := range p.ValueList {
+		if it.Value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PropSpec) listString() string {
+	items := make([]string, len(p.ValueList))
+	for i, it := range p.ValueList {
+		items[i] = fmt.Sprintf("%d=%s", it.Value, it.Description)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+func coerceBool(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case int, int64, float64:
+		switch toFloat(x) {
+		case 0:
+			return false, nil
+		case 1:
+			return true, nil
+		}
+	case string:
+		switch strings.ToLower(x) {
+		case "true", "on", "1":
+			return true, nil
+		case "false", "off", "0":
+			return false, nil
+		}
+	}
+	return nil, fmt.Errorf("want bool, got %v", v)
+}
+
+// coerceNumber 接受数字、数字字符串或枚举描述（不区分大小写）。
+func coerceNumber(v interface{}, list []ValueItem) (float64, error) {
+	switch x := v.(type) {
+	case int, int64, float64:
+		return toFloat(x), nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+			return f, nil
+		}
+		for _, it := range list {
+			if strings.EqualFold(it.Description, x) {
+				return float64(it.Value), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("want number, got %T %v", v, v)
+}
+
+func toFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	}
+	return 0
+}
+
+func toInt(v interface{}) int {
+	return int(toFloat(v))
+}
+
+// checkProps 开启校验时按设备型号的 SPEC 转换 props；型号或 SPEC 不可用时原样返回。
+func (a *API) checkProps(ctx context.Context, did string, props [][3]interface{}) ([][3]interface{}, error) {
+	if !a.validate {
+		return props, nil
+	}
+	spec := a.specFor(ctx, did)
+	if spec == nil {
+		return props, nil
+	}
+	return spec.ValidateProps(props)
+}
+
+// checkAction 同 checkProps，用于动作参数。
+func (a *API) checkAction(ctx context.Context, did string, siid, aiid int, in []interface{}) ([]interface{}, error) {
+	if !a.validate {
+		return in, nil
+	}
+	spec := a.specFor(ctx, did)
+	if spec == nil {
+		return in, nil
+	}
+	return spec.ValidateAction(siid, aiid, in)
+}
+
+// specFor 返回 did 对应型号的 SPEC，无法确定时返回 nil。
+func (a *API) specFor(ctx context.Context, did string) *ModelSpec {
+	spec, err := a.ModelSpecContext(ctx, did)
+	if err != nil {
+		return nil
+	}
+	return spec
+}
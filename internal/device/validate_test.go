@@ -0,0 +1,118 @@
+package device
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device/devicetest"
+)
+
+func testLightSpec() *ModelSpec {
+	return &ModelSpec{Services: []ServiceSpec{{
+		IID:  2,
+		Type: "urn:miot-spec-v2:service:light:00007802:test:1",
+		Properties: []PropSpec{
+			{IID: 1, Type: "urn:miot-spec-v2:property:on:00000006:test:1", Format: "bool", Access: []string{"read", "write"}},
+			{IID: 2, Type: "urn:miot-spec-v2:property:mode:00000008:test:1", Format: "uint8", Access: []string{"read", "write"},
+				ValueList: []ValueItem{{Value: 0, Description: "Day"}, {Value: 1, Description: "Night"}}},
+			{IID: 3, Type: "urn:miot-spec-v2:property:brightness:0000000D:test:1", Format: "uint8", Access: []string{"read", "write"},
+				ValueRange: &ValueRange{Min: 1, Max: 100, Step: 1}},
+			{IID: 4, Type: "urn:miot-spec-v2:property:color-temperature:0000000F:test:1", Format: "uint32", Access: []string{"read", "write"},
+				ValueRange: &ValueRange{Min: 2700, Max: 6500, Step: 100}},
+			{IID: 5, Type: "urn:miot-spec-v2:property:fault:00000009:test:1", Format: "uint8", Access: []string{"read", "notify"}},
+			{IID: 6, Type: "urn:miot-spec-v2:property:text-content:0000005D:test:1", Format: "string", Access: []string{"write"}},
+		},
+		Actions: []ActionSpec{
+			{IID: 1, Type: "urn:miot-spec-v2:action:toggle:00002811:test:1"},
+			{IID: 2, Type: "urn:miot-spec-v2:action:play-text:00002838:test:1", In: []int{6}},
+		},
+	}}}
+}
+
+func TestValidateProps(t *testing.T) {
+	spec := testLightSpec()
+	got, err := spec.ValidateProps([][3]interface{}{{2, 1, 1}, {2, 2, "night"}, {2, 3, "60"}, {2, 4, 3000.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{true, 1, 60, 3000}
+	for i, w := range want {
+		if got[i][2] != w {
+			t.Errorf("prop %d: got %#v, want %#v", i, got[i][2], w)
+		}
+	}
+
+	for _, tc := range []struct {
+		props  [3]interface{}
+		substr string
+	}{
+		{[3]interface{}{2, 3, 120}, "light.brightness (2.3): 120 out of range [1, 100]"},
+		{[3]interface{}{2, 3, 50.5}, "want uint8"},
+		{[3]interface{}{2, 3, true}, "want number"},
+		{[3]interface{}{2, 4, 2750}, "step 100"},
+		{[3]interface{}{2, 2, 3}, "not in value-list [0=Day, 1=Night]"},
+		{[3]interface{}{2, 1, "maybe"}, "light.on (2.1): want bool"},
+		{[3]interface{}{2, 5, 0}, "light.fault (2.5): property is not writable"},
+		{[3]interface{}{2, 9, 0}, "2.9: property not found"},
+	} {
+		_, err := spec.ValidateProps([][3]interface{}{tc.props})
+		var se *SpecError
+		if !errors.As(err, &se) || !strings.Contains(err.Error(), tc.substr) {
+			t.Errorf("%v: got %v, want %q", tc.props, err, tc.substr)
+		}
+	}
+}
+
+func TestValidateAction(t *testing.T) {
+	spec := testLightSpec()
+	if _, err := spec.ValidateAction(2, 1, nil); err != nil {
+		t.Errorf("toggle: %v", err)
+	}
+	if in, err := spec.ValidateAction(2, 2, []interface{}{42}); err != nil || in[0] != "42" {
+		t.Errorf("play-text: got %v, %v", in, err)
+	}
+	if _, err := spec.ValidateAction(2, 2, nil); err == nil || !strings.Contains(err.Error(), "light.play-text (2.2): expects 1 argument(s), got 0") {
+		t.Errorf("arity: got %v", err)
+	}
+	if _, err := spec.ValidateAction(2, 7, nil); err == nil {
+		t.Error("unknown action should fail")
+	}
+}
+
+func TestSetPropsValidate(t *testing.T) {
+	cloud := devicetest.New(TransportCloud)
+	cloud.Devices = []map[string]interface{}{{"did": "1", "name": "台灯", "model": "test.light.v1"}}
+	api := NewAPIWithTransports(PolicyCloud, cloud)
+	api.specs = map[string]*ModelSpec{"test.light.v1": testLightSpec()}
+
+	// 未开启校验时原样发送
+	if _, err := api.SetProps("1", [][3]interface{}{{2, 3, 120}}); err != nil {
+		t.Fatal(err)
+	}
+	api.SetValidate(true)
+	cloud.Calls = 0
+	if _, err := api.SetProps("1", [][3]interface{}{{2, 3, 120}}); err == nil {
+		t.Error("out of range value should be rejected")
+	}
+	if cloud.Calls != 0 {
+		t.Errorf("rejected value reached transport: %d calls", cloud.Calls)
+	}
+	if _, err := api.SetProps("1", [][3]interface{}{{2, 3, "60"}}); err != nil {
+		t.Fatal(err)
+	}
+	if v := cloud.Props[[2]int{2, 3}]; v != 60 {
+		t.Errorf("brightness = %#v, want coerced 60", v)
+	}
+	if _, err := api.Action("1", 2, 2, nil); err == nil {
+		t.Error("action arity should be checked")
+	}
+	// 型号只查一次设备列表，之后的校验走 did → model 缓存
+	if cloud.Lists != 1 {
+		t.Errorf("device list fetched %d times, want 1", cloud.Lists)
+	}
+	// 型号未知时不校验
+	if _, err := api.SetProps("9", [][3]interface{}{{2, 3, 120}}); err != nil {
+		t.Errorf("unknown model: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package discovery 发现局域网内的米家设备：广播 miIO hello（UDP 54321）并查询 mDNS _miio._udp，
+// 按 did 维护 IP 与最后发现时间，可与云端 device_list_page 结果关联。
+package discovery
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 发现来源。
+const (
+	SourceHello = "hello"
+	SourceMDNS  = "mdns"
+)
+
+// 默认地址与超时。
+const (
+	DefaultHelloAddr = "255.255.255.255:54321"
+	DefaultMDNSAddr  = "224.0.0.251:5353"
+	DefaultTimeout   = 2 * time.Second
+)
+
+// Entry 为一次发现结果，DID 与 m list 一致。
+type Entry struct {
+	DID      string    `json:"did"`
+	IP       string    `json:"ip"`
+	Model    string    `json:"model,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Source   string    `json:"source"`   // hello|mdns
+	InCloud  bool      `json:"in_cloud"` // 已与云端设备列表关联
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Table 为 did → Entry 的 IP/最后发现时间表，并发安全。
+type Table struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// NewTable 创建空表。
+func NewTable() *Table {
+	return &Table{entries: make(map[string]*Entry)}
+}
+
+// Observe 记录一次发现：更新 IP、来源与时间，保留已知的型号与名称。
+func (t *Table) Observe(e Entry) {
+	if e.DID == "" {
+		return
+	}
+	if e.LastSeen.IsZero() {
+		e.LastSeen = time.Now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.entries == nil {
+		t.entries = make(map[string]*Entry)
+	}
+	old, ok := t.entries[e.DID]
+	if !ok {
+		t.entries[e.DID] = &e
+		return
+	}
+	if e.IP != "" {
+		old.IP = e.IP
+	}
+	if e.Model != "" {
+		old.Model = e.Model
+	}
+	if e.Name != "" {
+		old.Name = e.Name
+	}
+	if e.Source != "" {
+		old.Source = e.Source
+	}
+	old.InCloud = old.InCloud || e.InCloud
+	if e.LastSeen.After(old.LastSeen) {
+		old.LastSeen = e.LastSeen
+	}
+}
+
+// Lookup 返回 did 的发现记录。
+func (t *Table) Lookup(did string) (Entry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[did]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// List 返回全部记录，按 did 排序。
+func (t *Table) List() []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DID < out[j].DID })
+	return out
+}
+
+// Correlate 用云端设备列表（device_list_page 原始结果）补全名称与型号，返回关联上的条数。
+func (t *Table) Correlate(cloud []map[string]interface{}) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, m := range cloud {
+		did, _ := m["did"].(string)
+		e, ok := t.entries[did]
+		if !ok {
+			continue
+		}
+		if name, _ := m["name"].(string); name != "" {
+			e.Name = name
+		}
+		if model, _ := m["model"].(string); model != "" {
+			e.Model = model
+		}
+		e.InCloud = true
+		n++
+	}
+	return n
+}
+
+// Scanner 执行一次局域网扫描。
+type Scanner struct {
+	HelloAddr string        // hello 广播地址，默认 255.255.255.255:54321，空串关闭
+	MDNSAddr  string        // mDNS 组播地址，默认 224.0.0.251:5353，空串关闭
+	Timeout   time.Duration // 等待响应的时间
+	Table     *Table        // 非 nil 时扫描结果写入该表
+}
+
+// NewScanner 创建使用默认地址与超时的扫描器。
+func NewScanner() *Scanner {
+	return &Scanner{HelloAddr: DefaultHelloAddr, MDNSAddr: DefaultMDNSAddr, Timeout: DefaultTimeout}
+}
+
+// Scan 并发发送 hello 广播与 mDNS 查询，等待 Timeout 后返回按 did 去重的结果。
+// 仅当所有方式都失败时返回错误。
+func (s *Scanner) Scan(ctx context.Context) ([]Entry, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	type result struct {
+		entries []Entry
+		err     error
+	}
+	var probes []func(context.Context, string, time.Duration) ([]Entry, error)
+	var addrs []string
+	if s.HelloAddr != "" {
+		probes, addrs = append(probes, scanHello), append(addrs, s.HelloAddr)
+	}
+	if s.MDNSAddr != "" {
+		probes, addrs = append(probes, scanMDNS), append(addrs, s.MDNSAddr)
+	}
+	if len(probes) == 0 {
+		return nil, errors.New("discovery: no probe address configured")
+	}
+	results := make(chan result, len(probes))
+	for i, probe := range probes {
+		go func(probe func(context.Context, string, time.Duration) ([]Entry, error), addr string) {
+			es, err := probe(ctx, addr, timeout)
+			results <- result{es, err}
+		}(probe, addrs[i])
+	}
+
+	tbl := NewTable()
+	var errs []error
+	for range probes {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+		for _, e := range r.entries {
+			tbl.Observe(e)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(errs) == len(probes) {
+		return nil, errors.Join(errs...)
+	}
+	out := tbl.List()
+	if s.Table != nil {
+		for _, e := range out {
+			s.Table.Observe(e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,149 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miiolocal"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// listen 在 127.0.0.1 上启动 UDP 响应器，对每个请求调用 reply。
+func listen(t *testing.T, reply func(req []byte) [][]byte) string {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			for _, b := range reply(append([]byte(nil), buf[:n]...)) {
+				_, _ = conn.WriteTo(b, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func helloReply(deviceID uint32) []byte {
+	b := miiolocal.Hello()
+	binary.BigEndian.PutUint32(b[4:], 0)
+	binary.BigEndian.PutUint32(b[8:], deviceID)
+	binary.BigEndian.PutUint32(b[12:], 100)
+	return b
+}
+
+func TestScanHello(t *testing.T) {
+	addr := listen(t, func(req []byte) [][]byte {
+		if !bytes.Equal(req, miiolocal.Hello()) {
+			t.Errorf("unexpected request %x", req)
+			return nil
+		}
+		return [][]byte{helloReply(123456789), []byte("junk")}
+	})
+	s := &Scanner{HelloAddr: addr, Timeout: 200 * time.Millisecond, Table: NewTable()}
+	es, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].DID != "123456789" || es[0].IP != "127.0.0.1" || es[0].Source != SourceHello {
+		t.Fatalf("entries = %+v", es)
+	}
+	if e, ok := s.Table.Lookup("123456789"); !ok || e.LastSeen.IsZero() {
+		t.Errorf("table entry = %+v, %v", e, ok)
+	}
+}
+
+func TestScanMDNS(t *testing.T) {
+	addr := listen(t, func(req []byte) [][]byte {
+		var q dnsmessage.Message
+		if err := q.Unpack(req); err != nil || len(q.Questions) != 1 || q.Questions[0].Type != dnsmessage.TypePTR {
+			t.Errorf("bad query: %v", err)
+			return nil
+		}
+		inst := dnsmessage.MustNewName("zhimi-airpurifier-mb3_miio987654._miio._udp.local.")
+		host := dnsmessage.MustNewName("zhimi-airpurifier-mb3.local.")
+		msg := dnsmessage.Message{
+			Header: dnsmessage.Header{Response: true, Authoritative: true},
+			Answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Questions[0].Name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.PTRResource{PTR: inst},
+			}},
+			Additionals: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: inst, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.SRVResource{Target: host, Port: 54321},
+			}, {
+				Header: dnsmessage.ResourceHeader{Name: host, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}},
+			}},
+		}
+		b, err := msg.Pack()
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		return [][]byte{b}
+	})
+	s := &Scanner{MDNSAddr: addr, Timeout: 200 * time.Millisecond}
+	es, err := s.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 1 || es[0].DID != "987654" || es[0].IP != "192.168.1.20" || es[0].Model != "zhimi.airpurifier.mb3" {
+		t.Fatalf("entries = %+v", es)
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	addr := listen(t, func([]byte) [][]byte { return nil })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s := &Scanner{HelloAddr: addr, Timeout: 5 * time.Second}
+	start := time.Now()
+	if _, err := s.Scan(ctx); err == nil {
+		t.Fatal("want ctx error")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Scan did not honour ctx")
+	}
+}
+
+func TestTableCorrelate(t *testing.T) {
+	tbl := NewTable()
+	tbl.Observe(Entry{DID: "1", IP: "10.0.0.2", Model: "old.model", Source: SourceMDNS})
+	tbl.Observe(Entry{DID: "1", IP: "10.0.0.3", Source: SourceHello})
+	tbl.Observe(Entry{DID: "2", IP: "10.0.0.4", Source: SourceHello})
+	n := tbl.Correlate([]map[string]interface{}{
+		{"did": "1", "name": "台灯", "model": "yeelink.light.lamp1"},
+		{"did": "3", "name": "不在局域网"},
+	})
+	if n != 1 {
+		t.Errorf("Correlate = %d, want 1", n)
+	}
+	e, _ := tbl.Lookup("1")
+	if e.IP != "10.0.0.3" || e.Name != "台灯" || e.Model != "yeelink.light.lamp1" || !e.InCloud {
+		t.Errorf("entry = %+v", e)
+	}
+	if list := tbl.List(); len(list) != 2 || list[1].InCloud {
+		t.Errorf("List = %+v", list)
+	}
+}
+
+func TestParseInstance(t *testing.T) {
+	did, model, ok := parseInstance("yeelink-light-color1_miio12345._miio._udp.local.")
+	if !ok || did != "12345" || model != "yeelink.light.color1" {
+		t.Errorf("parseInstance = %q %q %v", did, model, ok)
+	}
+	if _, _, ok := parseInstance("printer._ipp._tcp.local."); ok {
+		t.Error("non-miio instance should not parse")
+	}
+}
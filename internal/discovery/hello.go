@@ -0,0 +1,60 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miiolocal"
+)
+
+// scanHello 向 addr 发送 miIO hello，收集 timeout 内的响应；响应头中的设备 ID 即 did。
+func scanHello(ctx context.Context, addr string, timeout time.Duration) ([]Entry, error) {
+	dst, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteTo(miiolocal.Hello(), dst); err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	var out []Entry
+	buf := make([]byte, 1024)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return out, nil
+			}
+			return out, err
+		}
+		p, err := miiolocal.Decode(nil, buf[:n])
+		// 忽略无法解析的包与广播回环的 hello 本身
+		if err != nil || p.DeviceID == 0 || p.DeviceID == 0xffffffff {
+			continue
+		}
+		ua, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		out = append(out, Entry{
+			DID:      strconv.FormatUint(uint64(p.DeviceID), 10),
+			IP:       ua.IP.String(),
+			Source:   SourceHello,
+			LastSeen: time.Now(),
+		})
+	}
+}
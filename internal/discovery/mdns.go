@@ -0,0 +1,121 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// miioService mDNS 服务名；实例名形如 zhimi-airpurifier-mb3_miio123456789._miio._udp.local.
+const miioService = "_miio._udp.local."
+
+// scanMDNS 查询 _miio._udp 的 PTR 记录（要求单播响应），收集 timeout 内的应答。
+func scanMDNS(ctx context.Context, addr string, timeout time.Duration) ([]Entry, error) {
+	dst, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	query, err := mdnsQuery()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.WriteTo(query, dst); err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	var out []Entry
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return out, nil
+			}
+			return out, err
+		}
+		ua, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		out = append(out, parseMDNS(buf[:n], ua.IP)...)
+	}
+}
+
+// mdnsQuery 构造 _miio._udp.local. PTR 查询，class 最高位置 1 表示请求单播响应（QU）。
+func mdnsQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(miioService)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{Questions: []dnsmessage.Question{{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET | 1<<15,
+	}}}
+	return msg.Pack()
+}
+
+// parseMDNS 解析 mDNS 应答：PTR 给出实例名，SRV/A 给出地址；没有 A 记录时使用发送方 IP。
+func parseMDNS(b []byte, from net.IP) []Entry {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(b); err != nil || !msg.Header.Response {
+		return nil
+	}
+	var instances []string
+	targets := make(map[string]string) // 实例 → 主机名
+	hosts := make(map[string]net.IP)   // 主机名 → IPv4
+	for _, r := range append(msg.Answers, msg.Additionals...) {
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(r.Header.Name.String(), miioService) {
+				instances = append(instances, body.PTR.String())
+			}
+		case *dnsmessage.SRVResource:
+			targets[r.Header.Name.String()] = body.Target.String()
+		case *dnsmessage.AResource:
+			hosts[r.Header.Name.String()] = net.IP(body.A[:])
+		}
+	}
+	var out []Entry
+	for _, inst := range instances {
+		did, model, ok := parseInstance(inst)
+		if !ok {
+			continue
+		}
+		ip := from
+		if a, ok := hosts[targets[inst]]; ok {
+			ip = a
+		}
+		out = append(out, Entry{DID: did, IP: ip.String(), Model: model, Source: SourceMDNS, LastSeen: time.Now()})
+	}
+	return out
+}
+
+// parseInstance 从实例名解析 did 与型号，如 yeelink-light-color1_miio12345._miio._udp.local. → 12345, yeelink.light.color1。
+func parseInstance(name string) (did, model string, ok bool) {
+	label, _, _ := strings.Cut(name, ".")
+	i := strings.LastIndex(label, "_miio")
+	if i < 0 {
+		return "", "", false
+	}
+	did = label[i+len("_miio"):]
+	if did == "" || strings.Trim(did, "0123456789") != "" {
+		return "", "", false
+	}
+	return did, strings.ReplaceAll(label[:i], "-", "."), true
+}
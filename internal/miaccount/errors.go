@@ -0,0 +1,157 @@
+package miaccount
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// 可用 errors.Is 匹配的错误类型。
+var (
+	// ErrDeviceOffline 设备离线或云端联系不到设备（可重试）。
+	ErrDeviceOffline = errors.New("device offline")
+	// ErrTokenRevoked token 失效且刷新失败，需要重新 m login。
+	ErrTokenRevoked = errors.New("token revoked or expired, run 'm login' to re-authorize")
+	// ErrRateLimited 云端限流（HTTP 429）。
+	ErrRateLimited = errors.New("rate limited by cloud")
+	// ErrNotSent 请求未发出（如连接、握手失败），写操作也可安全重试或改走其他通道。
+	ErrNotSent = errors.New("request not sent")
+)
+
+// APIError 为云端返回的非 0 业务码，Code 为原始错误码。
+// Unwrap 返回对应的哨兵错误（如 ErrDeviceOffline），没有则为 nil。
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = codeMessages[e.Code]
+	}
+	return fmt.Sprintf("api error %d: %s", e.Code, msg)
+}
+
+// Unwrap 使 errors.Is(err, ErrDeviceOffline) 等可用。
+func (e *APIError) Unwrap() error {
+	return codeSentinels[e.Code]
+}
+
+// Temporary 报告该错误码是否值得重试。
+func (e *APIError) Temporary() bool {
+	return transientCodes[e.Code]
+}
+
+// MIoT 云端错误码，取自 ha_xiaomi_home miot/i18n 中的 error_code 表。
+const (
+	CodeUnauthorized        = -704010000 // 未授权（设备可能已被删除）
+	CodePropertyNotReadable = -704030013
+	CodePropertyNotWritable = -704030023
+	CodeServiceNotFound     = -704040002
+	CodePropertyNotFound    = -704040003
+	CodeActionNotFound      = -704040005
+	CodeDeviceNotFound      = -704042001
+	CodeDeviceOffline       = -704042011
+	CodeOperationTimeout    = -704053036
+	CodeInvalidID           = -704220008
+	CodeActionArgMismatch   = -704220025
+)
+
+var codeMessages = map[int]string{
+	CodeUnauthorized:        "unauthorized (device may have been removed)",
+	CodePropertyNotReadable: "property not readable",
+	CodePropertyNotWritable: "property not writable",
+	CodeServiceNotFound:     "service not found",
+	CodePropertyNotFound:    "property not found",
+	CodeActionNotFound:      "action not found",
+	CodeDeviceNotFound:      "device not found",
+	CodeDeviceOffline:       "device offline",
+	CodeOperationTimeout:    "device operation timeout",
+	CodeInvalidID:           "invalid id",
+	CodeActionArgMismatch:   "action argument count mismatch",
+}
+
+var codeSentinels = map[int]error{
+	CodeDeviceOffline:    ErrDeviceOffline,
+	CodeOperationTimeout: ErrDeviceOffline,
+}
+
+var transientCodes = map[int]bool{
+	CodeDeviceOffline:    true,
+	CodeOperationTimeout: true,
+}
+
+// NewAPIError 根据云端返回码创建 APIError，code 为 0 时返回 nil。
+func NewAPIError(code int, message string) error {
+	if code == 0 {
+		return nil
+	}
+	return &APIError{Code: code, Message: message}
+}
+
+// httpStatusError 为非 200 的 HTTP 响应。
+type httpStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.Status, e.Body)
+}
+
+func (e *httpStatusError) Unwrap() error {
+	if e.Status == 429 {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// IsTransient 报告 err 是否为可重试的临时错误：超时、5xx、429、设备离线。
+// context 取消/超时不视为临时错误。
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrDeviceOffline) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Status >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// NotSent 报告 err 是否发生在请求发出之前：ErrNotSent、建立连接失败或 DNS 解析失败。
+func NotSent(err error) bool {
+	if errors.Is(err, ErrNotSent) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// CodesError 返回逐项结果码（如 prop/set 的每项 code）中第一个失败码对应的 *APIError，全部成功时为 nil。
+// 1 表示设备已受理、异步执行，视为成功。
+func CodesError(codes []int) error {
+	for _, code := range codes {
+		if code < 0 {
+			return NewAPIError(code, "")
+		}
+	}
+	return nil
+}
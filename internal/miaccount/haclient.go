@@ -2,10 +2,12 @@ package miaccount
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/zeusro/miflow/internal/config"
@@ -21,6 +23,8 @@ type HAClient struct {
 	HTTP        *http.Client
 	TokenStore  *TokenStore
 	OAuthToken  *OAuthToken
+	// Retry 临时错误重试策略，零值表示不重试
+	Retry RetryPolicy
 }
 
 // NewHAClient creates client for given OAuth token.
@@ -53,17 +57,18 @@ func NewHAClient(t *OAuthToken, tokenStore *TokenStore) *HAClient {
 		HTTP:        &http.Client{Timeout: time.Duration(timeout) * time.Second},
 		TokenStore:  tokenStore,
 		OAuthToken:  t,
+		Retry:       RetryPolicyFromConfig(cfg.HTTP),
 	}
 }
 
 // ensureToken refreshes if expired.
-func (c *HAClient) ensureToken() error {
+func (c *HAClient) ensureToken(ctx context.Context) error {
 	if c.OAuthToken.IsValid() {
 		c.AccessToken = c.OAuthToken.AccessToken
 		return nil
 	}
 	if c.OAuthToken.RefreshToken == "" {
-		return fmt.Errorf("token expired, run 'm login' to re-authorize")
+		return ErrTokenRevoked
 	}
 	oc := NewOAuthClient()
 	oc.CloudServer = c.OAuthToken.CloudServer
@@ -71,7 +76,7 @@ func (c *HAClient) ensureToken() error {
 	oc.RedirectURI = c.OAuthToken.OAuthRedirect
 	oc.DeviceID = c.OAuthToken.DeviceID
 	oc.State = c.OAuthToken.State
-	newT, err := oc.RefreshToken(c.OAuthToken.RefreshToken)
+	newT, err := oc.RefreshTokenContext(ctx, c.OAuthToken.RefreshToken)
 	if err != nil {
 		return err
 	}
@@ -83,9 +88,28 @@ func (c *HAClient) ensureToken() error {
 	return nil
 }
 
+// ValidToken returns a non-expired access token, refreshing it if needed.
+// Used by clients that authenticate with the OAuth token outside HTTP (e.g. the MQTT push broker).
+func (c *HAClient) ValidToken(ctx context.Context) (string, error) {
+	if err := c.ensureToken(ctx); err != nil {
+		return "", err
+	}
+	return c.AccessToken, nil
+}
+
 // Post sends POST to path with JSON body, returns parsed result.
 func (c *HAClient) Post(path string, data interface{}) (map[string]interface{}, error) {
-	if err := c.ensureToken(); err != nil {
+	return c.PostContext(context.Background(), path, data)
+}
+
+// PostContext is like Post but aborts the request when ctx is cancelled or its deadline passes.
+// Transient failures (timeouts, 5xx, 429, device offline) are retried per c.Retry on idempotent
+// paths; a request that was never sent (e.g. connection refused) is retried on any path. Other
+// paths (prop/set, action, RunScene) are otherwise only retried on 429, so a write is not repeated
+// after a timeout. A 401 triggers one token refresh, a second 401 returns ErrTokenRevoked.
+// Cloud error codes are returned as *APIError.
+func (c *HAClient) PostContext(ctx context.Context, path string, data interface{}) (map[string]interface{}, error) {
+	if err := c.ensureToken(ctx); err != nil {
 		return nil, err
 	}
 	var body []byte
@@ -96,44 +120,91 @@ func (c *HAClient) Post(path string, data interface{}) (map[string]interface{},
 			return nil, err
 		}
 	}
+	attempts := c.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		out, status, err := c.post(ctx, path, body)
+		if status == http.StatusUnauthorized {
+			if refreshed {
+				return nil, ErrTokenRevoked
+			}
+			// token invalid, clear and refresh once
+			refreshed = true
+			c.OAuthToken.AccessToken = ""
+			c.OAuthToken.ExpiresTS = 0
+			if err := c.ensureToken(ctx); err != nil {
+				return nil, err
+			}
+			attempt--
+			continue
+		}
+		if err == nil {
+			return out, nil
+		}
+		transient := IsTransient(err) || (NotSent(err) && ctx.Err() == nil)
+		if attempt >= attempts || !transient || !retryable(path, err) {
+			return nil, err
+		}
+		if err := sleepContext(ctx, c.Retry.Backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// idempotentPaths are read-only endpoints that are safe to send again after a timeout or 5xx.
+var idempotentPaths = []string{
+	"/miotspec/prop/get",
+	"/home/device_list_page",
+	"/homeroom/gethome",
+	"/homeroom/get_dev_room_page",
+	"/AppSceneService/GetSceneList",
+}
+
+// retryable reports whether a transient err on path may be retried without repeating a write.
+func retryable(path string, err error) bool {
+	for _, p := range idempotentPaths {
+		if strings.HasSuffix(path, p) {
+			return true
+		}
+	}
+	return errors.Is(err, ErrRateLimited) || NotSent(err)
+}
+
+// post sends a single request and returns the parsed body and HTTP status (0 on transport error).
+func (c *HAClient) post(ctx context.Context, path string, body []byte) (map[string]interface{}, int, error) {
 	url := c.BaseURL + path
 	logHttpReq("POST", url, body)
-	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	c.setHeaders(req)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 	raw, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 	logHttpResp(resp.StatusCode, raw)
-	if resp.StatusCode == 401 {
-		// token invalid, clear and retry once
-		c.OAuthToken.AccessToken = ""
-		c.OAuthToken.ExpiresTS = 0
-		if err := c.ensureToken(); err != nil {
-			return nil, err
-		}
-		return c.Post(path, data)
-	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, string(raw))
+		return nil, resp.StatusCode, &httpStatusError{Status: resp.StatusCode, Body: string(raw)}
 	}
 	var out map[string]interface{}
 	if err := json.Unmarshal(raw, &out); err != nil {
-		return nil, err
+		return nil, resp.StatusCode, err
 	}
 	if code, ok := out["code"].(float64); ok && code != 0 {
-		return nil, fmt.Errorf("api error %.0f: %s", code, out["message"])
+		msg, _ := out["message"].(string)
+		return nil, resp.StatusCode, NewAPIError(int(code), msg)
 	}
-	return out, nil
+	return out, resp.StatusCode, nil
 }
 
 func (c *HAClient) setHeaders(req *http.Request) {
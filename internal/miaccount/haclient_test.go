@@ -0,0 +1,257 @@
+package miaccount
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestHAClient(baseURL string) *HAClient {
+	return &HAClient{
+		BaseURL:     baseURL,
+		ClientID:    "test",
+		AccessToken: "token",
+		HTTP:        &http.Client{Timeout: 10 * time.Second},
+		OAuthToken:  &OAuthToken{AccessToken: "token"},
+	}
+}
+
+func TestPostContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestHAClient(srv.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := c.PostContext(ctx, "/app/v2/miotspec/prop/get", map[string]interface{}{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("PostContext returned after %v, want prompt cancellation", d)
+	}
+}
+
+func TestPostContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestHAClient(srv.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.PostContext(ctx, "/app/v2/miotspec/prop/get", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func newRetryClient(baseURL string) *HAClient {
+	c := newTestHAClient(baseURL)
+	c.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	return c
+}
+
+func TestPostRetriesTransient(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"code":0,"result":[]}`))
+	}))
+	defer srv.Close()
+
+	if _, err := newRetryClient(srv.URL).Post("/app/v2/miotspec/prop/get", nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestPostWriteNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	// action 可能已执行，5xx 后不能重发
+	for _, path := range []string{"/app/v2/miotspec/action", "/app/v2/miotspec/prop/set"} {
+		atomic.StoreInt32(&calls, 0)
+		if _, err := newRetryClient(srv.URL).Post(path, nil); err == nil {
+			t.Fatalf("Post(%s) should fail", path)
+		}
+		if calls != 1 {
+			t.Errorf("Post(%s) calls = %d, want 1", path, calls)
+		}
+	}
+}
+
+func TestPostRetriesNotSent(t *testing.T) {
+	var calls, dials int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"code":0,"result":{"code":0}}`))
+	}))
+	defer srv.Close()
+	// 关闭后的端口拒绝连接
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := ln.Addr().String()
+	ln.Close()
+
+	// 第一次连接被拒（请求未发出），之后连接到 srv；写路径同样重试
+	for _, path := range []string{"/app/v2/miotspec/prop/get", "/app/v2/miotspec/action"} {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&dials, 0)
+		c := newRetryClient(srv.URL)
+		c.HTTP = &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if atomic.AddInt32(&dials, 1) == 1 {
+				addr = refused
+			}
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		}}}
+		if _, err := c.Post(path, nil); err != nil {
+			t.Fatalf("Post(%s) = %v", path, err)
+		}
+		if dials != 2 || calls != 1 {
+			t.Errorf("Post(%s) dials = %d, calls = %d; want 2 dials, 1 call", path, dials, calls)
+		}
+	}
+
+	// 一直被拒时按 MaxAttempts 重试后返回未发出的错误
+	atomic.StoreInt32(&dials, 0)
+	c := newRetryClient("http://" + refused)
+	c.HTTP = &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}}}
+	_, err = c.Post("/app/v2/miotspec/action", nil)
+	if !NotSent(err) {
+		t.Errorf("err = %v, want not sent", err)
+	}
+	if dials != 3 {
+		t.Errorf("refused dials = %d, want 3", dials)
+	}
+}
+
+func TestPostRateLimited(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := newRetryClient(srv.URL).Post("/x", nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("err = %v, want ErrRateLimited", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3 (capped)", calls)
+	}
+}
+
+func TestPostDeviceOffline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":-704042011,"message":"device offline"}`))
+	}))
+	defer srv.Close()
+
+	_, err := newRetryClient(srv.URL).Post("/x", nil)
+	if !errors.Is(err, ErrDeviceOffline) {
+		t.Fatalf("err = %v, want ErrDeviceOffline", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodeDeviceOffline {
+		t.Fatalf("errors.As APIError failed: %v", err)
+	}
+}
+
+func TestPostPermanentNotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"code":-704040003,"message":"property not found"}`))
+	}))
+	defer srv.Close()
+
+	_, err := newRetryClient(srv.URL).Post("/x", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != CodePropertyNotFound {
+		t.Fatalf("err = %v, want APIError %d", err, CodePropertyNotFound)
+	}
+	if IsTransient(err) {
+		t.Error("property not found should not be transient")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestPostUnauthorizedRevoked(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	// 无 refresh_token，401 后无法刷新
+	_, err := newRetryClient(srv.URL).Post("/x", nil)
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("err = %v, want ErrTokenRevoked", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryBackoffCapped(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt := 1; attempt <= 10; attempt++ {
+		if d := p.Backoff(attempt); d < 0 || d >= p.MaxDelay {
+			t.Errorf("Backoff(%d) = %v, want [0, %v)", attempt, d, p.MaxDelay)
+		}
+	}
+}
+
+func TestCodesError(t *testing.T) {
+	if err := CodesError([]int{0, 1, 0}); err != nil {
+		t.Errorf("CodesError(ok) = %v", err)
+	}
+	err := CodesError([]int{0, CodeDeviceOffline})
+	if !errors.Is(err, ErrDeviceOffline) {
+		t.Errorf("CodesError(offline) = %v, want ErrDeviceOffline", err)
+	}
+	var apiErr *APIError
+	if err := CodesError([]int{-704220043}); !errors.As(err, &apiErr) || apiErr.Code != -704220043 {
+		t.Errorf("CodesError(invalid value) = %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package miaccount
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
@@ -122,26 +123,36 @@ func (c *OAuthClient) GenAuthURL(redirectURI, state string, skipConfirm bool) st
 
 // GetToken exchanges authorization code for access/refresh tokens.
 func (c *OAuthClient) GetToken(code string) (*OAuthToken, error) {
+	return c.GetTokenContext(context.Background(), code)
+}
+
+// GetTokenContext is like GetToken but honours ctx cancellation.
+func (c *OAuthClient) GetTokenContext(ctx context.Context, code string) (*OAuthToken, error) {
 	data := map[string]string{
 		"client_id":    c.ClientID,
 		"redirect_uri": c.RedirectURI,
 		"code":         code,
 		"device_id":    c.DeviceID,
 	}
-	return c.getToken(data)
+	return c.getToken(ctx, data)
 }
 
 // RefreshToken refreshes access token using refresh_token.
 func (c *OAuthClient) RefreshToken(refreshToken string) (*OAuthToken, error) {
+	return c.RefreshTokenContext(context.Background(), refreshToken)
+}
+
+// RefreshTokenContext is like RefreshToken but honours ctx cancellation.
+func (c *OAuthClient) RefreshTokenContext(ctx context.Context, refreshToken string) (*OAuthToken, error) {
 	data := map[string]string{
 		"client_id":     c.ClientID,
 		"redirect_uri":  c.RedirectURI,
 		"refresh_token": refreshToken,
 	}
-	return c.getToken(data)
+	return c.getToken(ctx, data)
 }
 
-func (c *OAuthClient) getToken(data map[string]string) (*OAuthToken, error) {
+func (c *OAuthClient) getToken(ctx context.Context, data map[string]string) (*OAuthToken, error) {
 	cfg := config.Get()
 	apiHost := cfg.OAuth.APIHost
 	if apiHost == "" {
@@ -157,7 +168,7 @@ func (c *OAuthClient) getToken(data map[string]string) (*OAuthToken, error) {
 	}
 	payload, _ := json.Marshal(data)
 	reqURL := "https://" + host + tokenPath + "?data=" + url.QueryEscape(string(payload))
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +187,7 @@ func (c *OAuthClient) getToken(data map[string]string) (*OAuthToken, error) {
 	}
 	logHttpResp(resp.StatusCode, raw)
 	if resp.StatusCode == 401 {
-		return nil, fmt.Errorf("oauth: 401 unauthorized, token may be revoked")
+		return nil, fmt.Errorf("oauth: 401 unauthorized: %w", ErrTokenRevoked)
 	}
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("oauth: http %d: %s", resp.StatusCode, string(raw))
@@ -193,6 +204,10 @@ func (c *OAuthClient) getToken(data map[string]string) (*OAuthToken, error) {
 		return nil, fmt.Errorf("oauth: invalid response: %w", err)
 	}
 	if out.Code != 0 {
+		if data["refresh_token"] != "" {
+			// refresh_token 被拒绝，只能重新授权
+			return nil, fmt.Errorf("oauth: code %.0f, %s: %w", out.Code, string(raw), ErrTokenRevoked)
+		}
 		return nil, fmt.Errorf("oauth: code %.0f, %s", out.Code, string(raw))
 	}
 	r := &out.Result
@@ -0,0 +1,66 @@
+package miaccount
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+)
+
+// RetryPolicy 控制 HAClient 对临时错误的重试：指数退避 + full jitter，最多 MaxAttempts 次。
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数（含首次），<=1 表示不重试
+	BaseDelay   time.Duration // 首次退避上限
+	MaxDelay    time.Duration // 单次退避上限
+}
+
+// DefaultRetryPolicy 返回默认策略：3 次，200ms 起，最长 5s。
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 5 * time.Second}
+}
+
+// RetryPolicyFromConfig 从 http 配置读取重试策略，未配置项使用默认值。
+func RetryPolicyFromConfig(c config.HTTPConfig) RetryPolicy {
+	p := DefaultRetryPolicy()
+	if c.RetryMaxAttempts > 0 {
+		p.MaxAttempts = c.RetryMaxAttempts
+	}
+	if c.RetryBaseDelayMS > 0 {
+		p.BaseDelay = time.Duration(c.RetryBaseDelayMS) * time.Millisecond
+	}
+	if c.RetryMaxDelayMS > 0 {
+		p.MaxDelay = time.Duration(c.RetryMaxDelayMS) * time.Millisecond
+	}
+	return p
+}
+
+// Backoff 返回第 attempt 次失败（从 1 开始）后的等待时间，取 [0, min(MaxDelay, BaseDelay*2^(attempt-1))) 内随机值。
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	ceil := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || ceil < p.MaxDelay); i++ {
+		ceil *= 2
+	}
+	if p.MaxDelay > 0 && ceil > p.MaxDelay {
+		ceil = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceil)))
+}
+
+// sleepContext 等待 d 或 ctx 结束。
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
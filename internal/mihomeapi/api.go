@@ -1,9 +1,12 @@
 package mihomeapi
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/zeusro/miflow/internal/config"
 	"github.com/zeusro/miflow/internal/miaccount"
 )
 
@@ -11,35 +14,74 @@ import (
 // Ref: https://github.com/XiaoMi/ha_xiaomi_home
 type Service struct {
 	Client *miaccount.HAClient
+	// Limiter 按账号/设备限速，nil 表示不限
+	Limiter *Limiter
+
+	props *propCoalescer
 }
 
 // New creates service with OAuth-backed HAClient.
+// Requests share the per-account limiter, and concurrent GetProps calls within
+// http.coalesce_window_ms are merged into one request.
 func New(token *miaccount.OAuthToken, tokenPath string) (*Service, error) {
 	if token == nil {
 		return nil, fmt.Errorf("oauth token required")
 	}
 	store := &miaccount.TokenStore{Path: tokenPath}
-	return &Service{
-		Client: miaccount.NewHAClient(token, store),
-	}, nil
+	client := miaccount.NewHAClient(token, store)
+	cfg := config.Get().HTTP
+	s := &Service{
+		Client:  client,
+		Limiter: sharedLimiter(client, cfg),
+	}
+	s.SetCoalesceWindow(time.Duration(cfg.CoalesceWindowMS) * time.Millisecond)
+	return s, nil
+}
+
+// SetCoalesceWindow 设置 GetProps 合并窗口，<=0 关闭合并。
+func (s *Service) SetCoalesceWindow(window time.Duration) {
+	if window <= 0 {
+		s.props = nil
+		return
+	}
+	s.props = newPropCoalescer(window, s.getProps)
+}
+
+// paramDIDs 返回参数中涉及的 did，用于设备限速。
+func paramDIDs(params []map[string]interface{}) []string {
+	dids := make([]string, 0, len(params))
+	for _, p := range params {
+		if did, ok := p["did"].(string); ok {
+			dids = append(dids, did)
+		}
+	}
+	return dids
 }
 
 // DeviceList fetches devices. name filters by did/name; "full" returns full info.
 func (s *Service) DeviceList(name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
-	return s.deviceListPage(name, nil, nil, getVirtualModel, getHuamiDevices)
+	return s.DeviceListContext(context.Background(), name, getVirtualModel, getHuamiDevices)
+}
+
+// DeviceListContext is like DeviceList but honours ctx cancellation across all pages.
+func (s *Service) DeviceListContext(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	return s.deviceListPage(ctx, name, nil, nil, getVirtualModel, getHuamiDevices)
 }
 
-func (s *Service) deviceListPage(name string, dids []string, startDID *string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+func (s *Service) deviceListPage(ctx context.Context, name string, dids []string, startDID *string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
 	data := map[string]interface{}{
-		"limit":             200,
-		"get_split_device":  true,
-		"get_third_device":  true,
-		"dids":              dids,
+		"limit":            200,
+		"get_split_device": true,
+		"get_third_device": true,
+		"dids":             dids,
 	}
 	if startDID != nil {
 		data["start_did"] = *startDID
 	}
-	res, err := s.Client.Post("/app/v2/home/device_list_page", data)
+	if err := s.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/home/device_list_page", data)
 	if err != nil {
 		return nil, err
 	}
@@ -70,16 +112,28 @@ func (s *Service) deviceListPage(name string, dids []string, startDID *string, g
 		if name != "" && !strings.Contains(did, name) && !strings.Contains(n, name) {
 			continue
 		}
-		out = append(out, map[string]interface{}{
-			"name":   m["name"],
-			"model":  model,
-			"did":    did,
-			"token":  m["token"],
-		})
+		d := map[string]interface{}{
+			"name":  m["name"],
+			"model": model,
+			"did":   did,
+			"token": m["token"],
+		}
+		if online, ok := m["isOnline"].(bool); ok {
+			d["isOnline"] = online
+		}
+		for _, k := range []string{"parent_id", "localip"} {
+			if v, _ := m[k].(string); v != "" {
+				d[k] = v
+			}
+		}
+		out = append(out, d)
 	}
 	if hasMore && nextStart != "" {
-		more, err := s.deviceListPage(name, dids, &nextStart, getVirtualModel, getHuamiDevices)
+		more, err := s.deviceListPage(ctx, name, dids, &nextStart, getVirtualModel, getHuamiDevices)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return out, nil
 		}
 		out = append(out, more...)
@@ -89,7 +143,22 @@ func (s *Service) deviceListPage(name string, dids []string, startDID *string, g
 
 // GetProps gets MIoT properties.
 func (s *Service) GetProps(params []map[string]interface{}) ([]map[string]interface{}, error) {
-	res, err := s.Client.Post("/app/v2/miotspec/prop/get", map[string]interface{}{
+	return s.GetPropsContext(context.Background(), params)
+}
+
+// GetPropsContext is like GetProps but honours ctx cancellation.
+func (s *Service) GetPropsContext(ctx context.Context, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	if s.props != nil {
+		return s.props.get(ctx, params)
+	}
+	return s.getProps(ctx, params)
+}
+
+func (s *Service) getProps(ctx context.Context, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	if err := s.Limiter.Wait(ctx, paramDIDs(params)...); err != nil {
+		return nil, err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/miotspec/prop/get", map[string]interface{}{
 		"datasource": 1,
 		"params":     params,
 	})
@@ -112,7 +181,15 @@ func (s *Service) GetProps(params []map[string]interface{}) ([]map[string]interf
 
 // SetProps sets MIoT properties.
 func (s *Service) SetProps(params []map[string]interface{}) ([]map[string]interface{}, error) {
-	res, err := s.Client.Post("/app/v2/miotspec/prop/set", map[string]interface{}{
+	return s.SetPropsContext(context.Background(), params)
+}
+
+// SetPropsContext is like SetProps but honours ctx cancellation.
+func (s *Service) SetPropsContext(ctx context.Context, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	if err := s.Limiter.Wait(ctx, paramDIDs(params)...); err != nil {
+		return nil, err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/miotspec/prop/set", map[string]interface{}{
 		"params": params,
 	})
 	if err != nil {
@@ -134,7 +211,15 @@ func (s *Service) SetProps(params []map[string]interface{}) ([]map[string]interf
 
 // Action runs MIoT action.
 func (s *Service) Action(did string, siid, aiid int, in []interface{}) (map[string]interface{}, error) {
-	res, err := s.Client.Post("/app/v2/miotspec/action", map[string]interface{}{
+	return s.ActionContext(context.Background(), did, siid, aiid, in)
+}
+
+// ActionContext is like Action but honours ctx cancellation.
+func (s *Service) ActionContext(ctx context.Context, did string, siid, aiid int, in []interface{}) (map[string]interface{}, error) {
+	if err := s.Limiter.Wait(ctx, did); err != nil {
+		return nil, err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/miotspec/action", map[string]interface{}{
 		"params": map[string]interface{}{
 			"did":  did,
 			"siid": siid,
@@ -0,0 +1,194 @@
+package mihomeapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxBatchProps 单次合并请求的属性上限，达到即立即发送。
+const maxBatchProps = 100
+
+type propKey struct {
+	did        string
+	siid, piid int
+}
+
+// propCall 为一次 GetProps 调用，等待批量结果。
+type propCall struct {
+	keys []propKey
+	done chan struct{}
+	out  []map[string]interface{}
+	err  error
+}
+
+// propBatch 为一个合并窗口内的所有调用。
+type propBatch struct {
+	calls   []*propCall
+	size    int
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// propCoalescer 将窗口期内并发的 GetProps 合并为一次 /miotspec/prop/get 请求，
+// 去重后按 did/siid/piid 把结果分发回各调用方。
+type propCoalescer struct {
+	window time.Duration
+	fetch  func(ctx context.Context, params []map[string]interface{}) ([]map[string]interface{}, error)
+
+	mu      sync.Mutex
+	pending *propBatch
+}
+
+func newPropCoalescer(window time.Duration, fetch func(context.Context, []map[string]interface{}) ([]map[string]interface{}, error)) *propCoalescer {
+	return &propCoalescer{window: window, fetch: fetch}
+}
+
+// get 加入当前批次并等待结果；ctx 结束时放弃等待，批次内所有调用方都放弃时取消请求。
+func (c *propCoalescer) get(ctx context.Context, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	call := &propCall{done: make(chan struct{})}
+	for _, p := range params {
+		siid, _ := toInt(p["siid"])
+		piid, _ := toInt(p["piid"])
+		did, _ := p["did"].(string)
+		call.keys = append(call.keys, propKey{did: did, siid: siid, piid: piid})
+	}
+
+	c.mu.Lock()
+	b := c.pending
+	if b == nil {
+		bctx, cancel := context.WithCancel(context.Background())
+		b = &propBatch{ctx: bctx, cancel: cancel}
+		c.pending = b
+		time.AfterFunc(c.window, func() { c.flush(b) })
+	}
+	b.calls = append(b.calls, call)
+	b.size += len(call.keys)
+	b.waiters++
+	full := b.size >= maxBatchProps
+	if full {
+		c.pending = nil
+	}
+	c.mu.Unlock()
+	if full {
+		go c.run(b)
+	}
+
+	select {
+	case <-call.done:
+		return call.out, call.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		b.waiters--
+		if c.pending == b {
+			// 批次尚未发送：移除本调用；无人等待时丢弃批次，之后的调用方另起新批次
+			b.remove(call)
+			if b.waiters == 0 {
+				c.pending = nil
+			}
+		}
+		if b.waiters == 0 {
+			b.cancel()
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// remove 从未发送的批次中移除 call，调用方须持有 propCoalescer.mu。
+func (b *propBatch) remove(call *propCall) {
+	for i, x := range b.calls {
+		if x == call {
+			b.calls = append(b.calls[:i], b.calls[i+1:]...)
+			b.size -= len(call.keys)
+			return
+		}
+	}
+}
+
+// flush 由窗口定时器触发；若批次已因满额发送则忽略。
+func (c *propCoalescer) flush(b *propBatch) {
+	c.mu.Lock()
+	if c.pending != b {
+		c.mu.Unlock()
+		return
+	}
+	c.pending = nil
+	c.mu.Unlock()
+	c.run(b)
+}
+
+func (c *propCoalescer) run(b *propBatch) {
+	defer b.cancel()
+	index := make(map[propKey]int)
+	var params []map[string]interface{}
+	for _, call := range b.calls {
+		for _, k := range call.keys {
+			if _, ok := index[k]; ok {
+				continue
+			}
+			index[k] = len(params)
+			params = append(params, map[string]interface{}{"did": k.did, "siid": k.siid, "piid": k.piid})
+		}
+	}
+
+	res, err := c.fetch(b.ctx, params)
+	byKey := make(map[propKey]map[string]interface{}, len(res))
+	if err == nil {
+		for i, m := range res {
+			if m == nil {
+				continue
+			}
+			k, ok := resultKey(m)
+			if !ok && i < len(params) {
+				// 响应缺少 did/siid/piid 时按位置对应
+				k = propKey{did: params[i]["did"].(string), siid: params[i]["siid"].(int), piid: params[i]["piid"].(int)}
+			}
+			byKey[k] = m
+		}
+	}
+	for _, call := range b.calls {
+		if err != nil {
+			call.err = err
+		} else {
+			call.out = make([]map[string]interface{}, len(call.keys))
+			for i, k := range call.keys {
+				if m, ok := byKey[k]; ok {
+					call.out[i] = m
+				} else {
+					call.out[i] = map[string]interface{}{"did": k.did, "siid": float64(k.siid), "piid": float64(k.piid), "code": float64(-1)}
+				}
+			}
+		}
+		close(call.done)
+	}
+}
+
+func resultKey(m map[string]interface{}) (propKey, bool) {
+	did, ok1 := m["did"].(string)
+	siid, ok2 := toInt(m["siid"])
+	piid, ok3 := toInt(m["piid"])
+	if !ok1 || !ok2 || !ok3 {
+		return propKey{}, false
+	}
+	return propKey{did: did, siid: siid, piid: piid}, true
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch x := v.(type) {
+	case float64:
+		return int(x), true
+	case int:
+		return x, true
+	case int64:
+		return int(x), true
+	case string:
+		var n int
+		if _, err := fmt.Sscanf(x, "%d", &n); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
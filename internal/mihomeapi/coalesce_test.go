@@ -0,0 +1,178 @@
+package mihomeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+// newTestService 返回指向 httptest 服务的 Service，prop/get 按 siid*10+piid 返回值；
+// batches 记录每个请求的参数（did-siid-piid，已排序）。
+func newTestService(t *testing.T, requests *int32, batches *[][]string) *Service {
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var body struct {
+			Params []struct {
+				DID  string `json:"did"`
+				SIID int    `json:"siid"`
+				PIID int    `json:"piid"`
+			} `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		batch := make([]string, len(body.Params))
+		for i, p := range body.Params {
+			batch[i] = fmt.Sprintf("%s-%d-%d", p.DID, p.SIID, p.PIID)
+		}
+		sort.Strings(batch)
+		mu.Lock()
+		*batches = append(*batches, batch)
+		mu.Unlock()
+		result := make([]map[string]interface{}, 0, len(body.Params))
+		// 倒序返回，确认按 did/siid/piid 分发而非按位置
+		for i := len(body.Params) - 1; i >= 0; i-- {
+			p := body.Params[i]
+			result = append(result, map[string]interface{}{
+				"did": p.DID, "siid": p.SIID, "piid": p.PIID, "code": 0,
+				"value": p.SIID*10 + p.PIID,
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+	return &Service{Client: &miaccount.HAClient{
+		BaseURL:    srv.URL,
+		HTTP:       &http.Client{Timeout: 5 * time.Second},
+		OAuthToken: &miaccount.OAuthToken{AccessToken: "token"},
+	}}
+}
+
+func TestGetPropsCoalesce(t *testing.T) {
+	var requests int32
+	var batches [][]string
+	s := newTestService(t, &requests, &batches)
+	s.SetCoalesceWindow(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func(piid int) {
+			defer wg.Done()
+			// 每个调用都带上 2-1，验证去重
+			res, err := s.GetProps([]map[string]interface{}{
+				{"did": "100", "siid": 2, "piid": piid},
+				{"did": "100", "siid": 2, "piid": 1},
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if v, _ := res[0]["value"].(float64); int(v) != 20+piid {
+				t.Errorf("piid %d: value = %v, want %d", piid, res[0]["value"], 20+piid)
+			}
+			if v, _ := res[1]["value"].(float64); int(v) != 21 {
+				t.Errorf("piid %d: 2-1 value = %v, want 21", piid, res[1]["value"])
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	// 5 个调用合并为一个请求，重复的 2-1 只出现一次
+	want := [][]string{{"100-2-1", "100-2-2", "100-2-3", "100-2-4", "100-2-5"}}
+	if requests != 1 || !reflect.DeepEqual(batches, want) {
+		t.Errorf("requests = %d, batches = %v; want 1 request %v", requests, batches, want)
+	}
+}
+
+func TestGetPropsCoalesceCancel(t *testing.T) {
+	var requests int32
+	var batches [][]string
+	s := newTestService(t, &requests, &batches)
+	s.SetCoalesceWindow(time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.GetPropsContext(ctx, []map[string]interface{}{{"did": "1", "siid": 2, "piid": 1}}); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if requests != 0 {
+		t.Errorf("requests = %d, want none after cancel", requests)
+	}
+}
+
+func TestGetPropsCoalesceCancelThenJoin(t *testing.T) {
+	var requests int32
+	var batches [][]string
+	s := newTestService(t, &requests, &batches)
+	s.SetCoalesceWindow(100 * time.Millisecond)
+
+	// A 在窗口内取消后，B 在同一窗口加入，不能拿到已取消批次的 context.Canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	errA := make(chan error, 1)
+	go func() {
+		_, err := s.GetPropsContext(ctx, []map[string]interface{}{{"did": "100", "siid": 2, "piid": 1}})
+		errA <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-errA; err != context.Canceled {
+		t.Fatalf("A err = %v, want context.Canceled", err)
+	}
+	res, err := s.GetProps([]map[string]interface{}{{"did": "100", "siid": 2, "piid": 2}})
+	if err != nil {
+		t.Fatalf("B err = %v", err)
+	}
+	if v, _ := res[0]["value"].(float64); int(v) != 22 {
+		t.Errorf("B value = %v, want 22", res[0]["value"])
+	}
+	if want := [][]string{{"100-2-2"}}; atomic.LoadInt32(&requests) != 1 || !reflect.DeepEqual(batches, want) {
+		t.Errorf("requests = %d, batches = %v; want 1 request %v", requests, batches, want)
+	}
+}
+
+func TestLimiterDevice(t *testing.T) {
+	l := NewLimiter(0, 0, 20, 1)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx, "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 突发 1，之后每 50ms 一个令牌
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("3 requests took %v, want >= ~100ms", d)
+	}
+	// 其他设备不受影响
+	start = time.Now()
+	if err := l.Wait(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 20*time.Millisecond {
+		t.Errorf("other device waited %v", d)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := NewLimiter(1, 1, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_ = l.Wait(ctx)
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+}
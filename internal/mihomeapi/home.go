@@ -0,0 +1,167 @@
+package mihomeapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Home 为米家家庭（含共享给当前账号的家庭）。
+// gethome 与 get_dev_room_page 的响应只有家庭、房间与设备分配，没有楼层字段，因此不建模楼层，
+// 按位置筛选以房间为最小粒度（m list --room）。
+type Home struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	UID    string   `json:"uid,omitempty"`    // 家庭所有者
+	Shared bool     `json:"shared,omitempty"` // 来自 share_home_list
+	Rooms  []Room   `json:"rooms"`
+	DIDs   []string `json:"dids"` // 未分配房间的设备
+}
+
+// Room 为家庭中的房间。
+type Room struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	HomeID string   `json:"home_id"`
+	DIDs   []string `json:"dids"`
+}
+
+// HomeList fetches homes, rooms and the device-to-room assignment.
+func (s *Service) HomeList() ([]Home, error) {
+	return s.HomeListContext(context.Background())
+}
+
+// HomeListContext is like HomeList but honours ctx cancellation.
+// Ref: ha_xiaomi_home miot_cloud.get_homeinfos_async (/app/v2/homeroom/gethome + get_dev_room_page)
+func (s *Service) HomeListContext(ctx context.Context) ([]Home, error) {
+	if err := s.Limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/homeroom/gethome", map[string]interface{}{
+		"limit":           150,
+		"fetch_share":     true,
+		"fetch_share_dev": true,
+		"plat_form":       0,
+		"app_ver":         9,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, _ := res["result"].(map[string]interface{})
+	if result == nil {
+		return nil, fmt.Errorf("invalid gethome response")
+	}
+	var homes []Home
+	for _, src := range []string{"homelist", "share_home_list"} {
+		list, _ := result[src].([]interface{})
+		for _, it := range list {
+			m, ok := it.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			h := parseHome(m)
+			h.Shared = src == "share_home_list"
+			homes = append(homes, h)
+		}
+	}
+	if hasMore, _ := result["has_more"].(bool); hasMore {
+		if maxID, ok := result["max_id"].(string); ok && maxID != "" {
+			if err := s.devRoomPage(ctx, homes, maxID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return homes, nil
+}
+
+// devRoomPage 分页获取其余的设备-房间分配并合并到 homes。
+func (s *Service) devRoomPage(ctx context.Context, homes []Home, startID string) error {
+	if err := s.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	res, err := s.Client.PostContext(ctx, "/app/v2/homeroom/get_dev_room_page", map[string]interface{}{
+		"start_id": startID,
+		"limit":    150,
+	})
+	if err != nil {
+		return err
+	}
+	result, _ := res["result"].(map[string]interface{})
+	if result == nil {
+		return fmt.Errorf("invalid get_dev_room_page response")
+	}
+	info, _ := result["info"].([]interface{})
+	for _, it := range info {
+		m, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		page := parseHome(m)
+		for i := range homes {
+			if homes[i].ID != page.ID {
+				continue
+			}
+			homes[i].DIDs = append(homes[i].DIDs, page.DIDs...)
+			for _, pr := range page.Rooms {
+				for j := range homes[i].Rooms {
+					if homes[i].Rooms[j].ID == pr.ID {
+						homes[i].Rooms[j].DIDs = append(homes[i].Rooms[j].DIDs, pr.DIDs...)
+					}
+				}
+			}
+		}
+	}
+	if hasMore, _ := result["has_more"].(bool); hasMore {
+		if maxID, ok := result["max_id"].(string); ok && maxID != "" && maxID != startID {
+			return s.devRoomPage(ctx, homes, maxID)
+		}
+	}
+	return nil
+}
+
+func parseHome(m map[string]interface{}) Home {
+	h := Home{
+		ID:   toString(m["id"]),
+		Name: toString(m["name"]),
+		UID:  toString(m["uid"]),
+		DIDs: toStrings(m["dids"]),
+	}
+	rooms, _ := m["roomlist"].([]interface{})
+	for _, it := range rooms {
+		r, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		h.Rooms = append(h.Rooms, Room{
+			ID:     toString(r["id"]),
+			Name:   toString(r["name"]),
+			HomeID: h.ID,
+			DIDs:   toStrings(r["dids"]),
+		})
+	}
+	return h
+}
+
+// toString 将 JSON 中的字符串或数字 ID 转为字符串。
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func toStrings(v interface{}) []string {
+	arr, _ := v.([]interface{})
+	out := make([]string, 0, len(arr))
+	for _, it := range arr {
+		if s := toString(it); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package mihomeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+func TestHomeListPaged(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		var result map[string]interface{}
+		switch r.URL.Path {
+		case "/app/v2/homeroom/gethome":
+			result = map[string]interface{}{
+				"homelist": []interface{}{map[string]interface{}{
+					"id": "100", "name": "我的家", "uid": float64(42),
+					"dids": []interface{}{"9"},
+					"roomlist": []interface{}{
+						map[string]interface{}{"id": "101", "name": "客厅", "dids": []interface{}{"1"}},
+						map[string]interface{}{"id": "102", "name": "卧室", "dids": []interface{}{}},
+					},
+				}},
+				"share_home_list": []interface{}{map[string]interface{}{"id": float64(200), "name": "父母家"}},
+				"has_more":        true,
+				"max_id":          "p1",
+			}
+		case "/app/v2/homeroom/get_dev_room_page":
+			result = map[string]interface{}{
+				"info": []interface{}{map[string]interface{}{
+					"id":       "100",
+					"roomlist": []interface{}{map[string]interface{}{"id": "102", "dids": []interface{}{"2", "3"}}},
+				}},
+				"has_more": false,
+			}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "result": result})
+	}))
+	defer srv.Close()
+	s := &Service{Client: &miaccount.HAClient{
+		BaseURL:    srv.URL,
+		HTTP:       &http.Client{Timeout: 5 * time.Second},
+		OAuthToken: &miaccount.OAuthToken{AccessToken: "token"},
+	}}
+
+	homes, err := s.HomeList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Errorf("requests = %v", paths)
+	}
+	if len(homes) != 2 || homes[0].Name != "我的家" || homes[0].UID != "42" || homes[0].Shared {
+		t.Fatalf("homes = %+v", homes)
+	}
+	if homes[1].ID != "200" || !homes[1].Shared {
+		t.Errorf("shared home = %+v", homes[1])
+	}
+	rooms := homes[0].Rooms
+	if len(rooms) != 2 || rooms[0].Name != "客厅" || rooms[0].HomeID != "100" || len(rooms[0].DIDs) != 1 {
+		t.Fatalf("rooms = %+v", rooms)
+	}
+	if len(rooms[1].DIDs) != 2 || rooms[1].DIDs[1] != "3" {
+		t.Errorf("paged room dids = %v", rooms[1].DIDs)
+	}
+}
+
+func TestParseScenes(t *testing.T) {
+	list := []interface{}{
+		map[string]interface{}{"scene_id": float64(1001), "name": "回家"},
+		map[string]interface{}{"name": "无 ID"},
+	}
+	got := parseScenes(list, "100")
+	if len(got) != 1 || got[0].ID != "1001" || got[0].HomeID != "100" {
+		t.Errorf("parseScenes(list) = %+v", got)
+	}
+	wrapped := map[string]interface{}{"scene_info_list": []interface{}{
+		map[string]interface{}{"scene_id": "2002", "name": "离家", "home_id": "200"},
+	}}
+	if got := parseScenes(wrapped, "100"); len(got) != 1 || got[0].HomeID != "200" {
+		t.Errorf("parseScenes(wrapped) = %+v", got)
+	}
+}
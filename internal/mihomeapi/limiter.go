@@ -0,0 +1,140 @@
+package mihomeapi
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+// tokenBucket 令牌桶：每秒补充 rate 个令牌，最多 burst 个。rate<=0 表示不限速。
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve 取走一个令牌，返回需要等待的时间。
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait 阻塞直到拿到令牌或 ctx 结束。
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil || b.rate <= 0 {
+		return ctx.Err()
+	}
+	d := b.reserve()
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Limiter 按账号与设备限速：每次请求先取账号令牌，再取涉及设备各自的令牌。
+type Limiter struct {
+	account *tokenBucket
+
+	mu          sync.Mutex
+	deviceRate  float64
+	deviceBurst int
+	devices     map[string]*tokenBucket
+}
+
+// NewLimiter 创建限速器，rate 为每秒请求数，<=0 表示不限。
+func NewLimiter(accountRate float64, accountBurst int, deviceRate float64, deviceBurst int) *Limiter {
+	return &Limiter{
+		account:     newTokenBucket(accountRate, accountBurst),
+		deviceRate:  deviceRate,
+		deviceBurst: deviceBurst,
+		devices:     make(map[string]*tokenBucket),
+	}
+}
+
+// Wait 为一次请求取令牌，dids 为请求涉及的设备（可重复，会去重）。
+func (l *Limiter) Wait(ctx context.Context, dids ...string) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	if err := l.account.wait(ctx); err != nil {
+		return err
+	}
+	if l.deviceRate <= 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(dids))
+	for _, did := range dids {
+		if did == "" || seen[did] {
+			continue
+		}
+		seen[did] = true
+		if err := l.device(did).wait(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Limiter) device(did string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.devices[did]
+	if !ok {
+		b = newTokenBucket(l.deviceRate, l.deviceBurst)
+		l.devices[did] = b
+	}
+	return b
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*Limiter{}
+)
+
+// sharedLimiter 返回账号共享的限速器：同一进程内使用同一 token 的 Service 共用限额。
+func sharedLimiter(c *miaccount.HAClient, cfg config.HTTPConfig) *Limiter {
+	key := c.BaseURL + "|" + c.ClientID
+	if c.TokenStore != nil && c.TokenStore.Path != "" {
+		key += "|" + c.TokenStore.Path
+	} else if c.OAuthToken != nil {
+		key += "|" + c.OAuthToken.DeviceID
+	}
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[key]; ok {
+		return l
+	}
+	l := NewLimiter(cfg.RateLimitPerSecond, cfg.RateLimitBurst, cfg.DeviceRateLimitPerSecond, cfg.DeviceRateLimitBurst)
+	limiters[key] = l
+	return l
+}
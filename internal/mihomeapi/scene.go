@@ -0,0 +1,88 @@
+package mihomeapi
+
+import (
+	"context"
+	"fmt"
+)
+
+// Scene 为米家手动场景。
+type Scene struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	HomeID string `json:"home_id"`
+}
+
+// SceneList lists manual scenes of all homes.
+func (s *Service) SceneList() ([]Scene, error) {
+	return s.SceneListContext(context.Background())
+}
+
+// SceneListContext is like SceneList but honours ctx cancellation.
+// Scenes are listed per home via appsceneservice GetSceneList.
+func (s *Service) SceneListContext(ctx context.Context) ([]Scene, error) {
+	homes, err := s.HomeListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []Scene
+	for _, h := range homes {
+		if err := s.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		res, err := s.Client.PostContext(ctx, "/app/appgateway/miot/appsceneservice/AppSceneService/GetSceneList", map[string]interface{}{
+			"home_id":   h.ID,
+			"owner_uid": h.UID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, parseScenes(res["result"], h.ID)...)
+	}
+	return out, nil
+}
+
+// parseScenes 解析 GetSceneList 结果：数组或 {scene_info_list: [...]}。
+func parseScenes(result interface{}, homeID string) []Scene {
+	list, ok := result.([]interface{})
+	if !ok {
+		if m, ok := result.(map[string]interface{}); ok {
+			list, _ = m["scene_info_list"].([]interface{})
+		}
+	}
+	out := make([]Scene, 0, len(list))
+	for _, it := range list {
+		m, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sc := Scene{ID: toString(m["scene_id"]), Name: toString(m["name"]), HomeID: toString(m["home_id"])}
+		if sc.ID == "" {
+			continue
+		}
+		if sc.HomeID == "" {
+			sc.HomeID = homeID
+		}
+		out = append(out, sc)
+	}
+	return out
+}
+
+// RunScene triggers a manual scene by ID.
+func (s *Service) RunScene(sceneID string) error {
+	return s.RunSceneContext(context.Background(), sceneID)
+}
+
+// RunSceneContext is like RunScene but honours ctx cancellation.
+func (s *Service) RunSceneContext(ctx context.Context, sceneID string) error {
+	if sceneID == "" {
+		return fmt.Errorf("scene id required")
+	}
+	if err := s.Limiter.Wait(ctx); err != nil {
+		return err
+	}
+	_, err := s.Client.PostContext(ctx, "/app/appgateway/miot/appsceneservice/AppSceneService/RunScene", map[string]interface{}{
+		"scene_id":    sceneID,
+		"trigger_key": "user.click",
+	})
+	return err
+}
@@ -1,18 +1,31 @@
 package miiocommand
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miioservice"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
 )
 
 // Run parses text and runs the appropriate MiIO/MIoT command. did can be device ID or name.
 // prefix is used in help (e.g. "m ").
-func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error) {
+// Property and action commands go through api's transport policy; raw MiIO/MIoT,
+// list and spec use the cloud service.
+func Run(api *device.API, did, text, prefix string) (interface{}, error) {
+	return RunContext(context.Background(), api, did, text, prefix)
+}
+
+// RunContext is like Run but honours ctx cancellation for cloud calls.
+func RunContext(ctx context.Context, api *device.API, did, text, prefix string) (interface{}, error) {
+	svc := api.Cloud()
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return Help(did, prefix), nil
@@ -32,6 +45,9 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 				return nil, fmt.Errorf("invalid JSON: %w", err)
 			}
 		}
+		if svc == nil {
+			return nil, errNoCloud
+		}
 		return svc.MiIORequest(cmd, data)
 	}
 
@@ -43,12 +59,83 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 				return nil, fmt.Errorf("invalid JSON: %w", err)
 			}
 		}
-		return svc.MiotRequest(cmd, params)
+		if svc == nil {
+			return nil, errNoCloud
+		}
+		return svc.MiotRequestContext(ctx, cmd, params)
 	}
 
 	argv := strings.Fields(arg)
 	argc := len(argv)
 
+	if cmd == "homes" {
+		return api.HomesContext(ctx)
+	}
+
+	if cmd == "registry" {
+		reg := api.Registry()
+		if reg == nil {
+			return nil, fmt.Errorf("device registry disabled (miio.registry_ttl_minutes < 0)")
+		}
+		sub := "list"
+		if argc > 0 {
+			sub = argv[0]
+		}
+		switch sub {
+		case "list":
+			return reg.List()
+		case "refresh":
+			return api.RefreshRegistryContext(ctx)
+		case "history":
+			if argc < 2 {
+				return reg.History("", 50)
+			}
+			hdid, err := api.ResolveDIDContext(ctx, argv[1])
+			if err != nil {
+				return nil, err
+			}
+			return reg.History(hdid, 50)
+		}
+		return nil, fmt.Errorf("unknown registry command %q (list|refresh|history)", sub)
+	}
+
+	if cmd == "group" || cmd == "groups" {
+		return runGroup(ctx, api, argv)
+	}
+
+	if cmd == "alias" || cmd == "aliases" {
+		return runAlias(ctx, api, argv)
+	}
+
+	if cmd == "scene" || cmd == "scenes" {
+		sub := "list"
+		if argc > 0 {
+			sub = argv[0]
+		}
+		switch sub {
+		case "list":
+			return api.ScenesContext(ctx)
+		case "run":
+			if argc < 2 {
+				return nil, fmt.Errorf("scene run requires: <name|id>")
+			}
+			return api.RunSceneContext(ctx, strings.Join(argv[1:], " "))
+		}
+		return nil, fmt.Errorf("unknown scene command %q (list|run)", sub)
+	}
+
+	if cmd == "vacuum" {
+		return runVacuum(ctx, api, did, argv)
+	}
+
+	if cmd == "list" && strings.Contains(arg, "--") {
+		f, err := parseListFilter(argv)
+		if err != nil {
+			return nil, err
+		}
+		return api.FindContext(ctx, f)
+	}
+
 	if cmd == "list" {
 		var getVirtual bool
 		var getHuami int
@@ -62,7 +149,14 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 		if argc > 2 {
 			getHuami, _ = strconv.Atoi(argv[2])
 		}
-		return svc.DeviceList(name, getVirtual, getHuami)
+		if svc == nil {
+			return nil, errNoCloud
+		}
+		return svc.DeviceListContext(ctx, name, getVirtual, getHuami)
+	}
+
+	if cmd == "spec" && argc > 0 && specTools[argv[0]] {
+		return runSpecTool(ctx, api, argv)
 	}
 
 	if cmd == "spec" {
@@ -74,11 +168,10 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 		if argc > 1 {
 			format = argv[1]
 		}
-		return svc.MiotSpec(typ, format)
+		return api.Spec(typ, format)
 	}
 
 	if cmd == "spec_all" || cmd == "spec-all" {
-		api := device.NewAPI(svc)
 		specs, failed := api.LoadAllModelSpecs()
 		ok := make(map[string]string)
 		for m, s := range specs {
@@ -110,32 +203,43 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 		return Help(did, prefix), nil
 	}
 
+	// 群组：对每个成员并发执行同一命令
+	if name, ok := device.GroupRef(did); ok {
+		return api.FanOutContext(ctx, name, func(ctx context.Context, d *device.Device) (interface{}, error) {
+			return RunContext(ctx, api, d.DID, text, prefix)
+		})
+	}
+
 	// Resolve did to numeric if it's a name
 	if did != "" && !isDigits(did) {
-		devs, err := svc.DeviceList(did, false, 0)
-		if err != nil || len(devs) == 0 {
-			return nil, fmt.Errorf("device not found: %s", did)
-		}
-		if d, ok := devs[0]["did"].(string); ok {
-			did = d
+		resolved, err := api.ResolveDIDContext(ctx, did)
+		if err != nil {
+			return nil, err
 		}
+		did = resolved
 	}
 
 	if did == "" || cmd == "" {
 		return Help(did, prefix), nil
 	}
 
+	// --decode：读取属性时按 SPEC 返回名称、单位与枚举描述
+	decode := arg == "--decode"
+	if decode {
+		arg, argv, argc = "", nil, 0
+	}
+
 	// Parse comma-separated items: 1,1-2,2=#60,5-4 Hello #1
 	items := strings.Split(cmd, ",")
+	if isNamed(items) {
+		return runNamed(ctx, api, did, items, arg, decode)
+	}
 	var props [][3]interface{} // get: [siid, piid], set: [siid, piid, value], action: [siid, aiid] + args
 	setMode := true
 	miot := true
 	for _, item := range items {
 		key, val := splitTwins(item, "=", "")
-		siidStr, piidStr := splitTwins(key, "-", "1")
-		if isDigits(siidStr) && isDigits(piidStr) {
-			siid, _ := strconv.Atoi(siidStr)
-			piid, _ := strconv.Atoi(piidStr)
+		if siid, piid, ok := numericIID(key); ok {
 			if val == "" {
 				setMode = false
 				props = append(props, [3]interface{}{siid, piid, nil})
@@ -158,7 +262,7 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 		}
 		siid, _ := props[0][0].(int)
 		aiid, _ := props[0][1].(int)
-		code, err := svc.MiotAction(did, siid, aiid, args)
+		code, err := api.ActionContext(ctx, did, siid, aiid, args)
 		if err != nil {
 			return nil, err
 		}
@@ -173,9 +277,12 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 					setProps = append(setProps, p)
 				}
 			}
-			return svc.MiotSetProps(did, setProps)
+			return api.SetPropsContext(ctx, did, setProps)
 		}
 		// Legacy home set
+		if svc == nil {
+			return nil, errNoCloud
+		}
 		var err error
 		for _, p := range props {
 			if p[2] != nil {
@@ -195,9 +302,15 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 			piid, _ := p[1].(int)
 			iids = append(iids, [2]int{siid, piid})
 		}
-		return svc.MiotGetProps(did, iids)
+		if decode {
+			return api.ReadPropsContext(ctx, did, iids)
+		}
+		return api.GetPropsContext(ctx, did, iids)
 	}
 	// Legacy home get_prop
+	if svc == nil {
+		return nil, errNoCloud
+	}
 	propNames := make([]string, 0, len(props))
 	for _, p := range props {
 		propNames = append(propNames, fmt.Sprintf("%v", p[0]))
@@ -205,6 +318,245 @@ func Run(svc *miioservice.Service, did, text, prefix string) (interface{}, error
 	return svc.HomeGetProps(did, propNames)
 }
 
+var errNoCloud = errors.New("command requires cloud service (run 'm login' first)")
+
+// UsageError 表示命令写法或参数有误，web 端据此返回 400。
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string { return e.Msg }
+
+func usage(format string, a ...interface{}) error {
+	return &UsageError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// runGroup 处理 group [list|show <名称>|set <名称> [成员 ...] [--room R] [--home H] [--model P] [--cap C]|rm <名称>]。
+func runGroup(ctx context.Context, api *device.API, argv []string) (interface{}, error) {
+	sub := "list"
+	if len(argv) > 0 {
+		sub = argv[0]
+	}
+	if sub == "list" {
+		return api.Groups()
+	}
+	if len(argv) < 2 {
+		return nil, fmt.Errorf("group %s requires: <name>", sub)
+	}
+	name := strings.TrimPrefix(argv[1], device.GroupPrefix)
+	switch sub {
+	case "show":
+		return api.GroupMembersContext(ctx, name)
+	case "rm":
+		return nil, api.DeleteGroup(name)
+	case "set":
+		g, err := parseGroup(name, argv[2:])
+		if err != nil {
+			return nil, err
+		}
+		if err := api.SaveGroup(g); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+	return nil, fmt.Errorf("unknown group command %q (list|show|set|rm)", sub)
+}
+
+func parseGroup(name string, argv []string) (*device.Group, error) {
+	g := &device.Group{Name: name}
+	for i := 0; i < len(argv); i++ {
+		flag := argv[i]
+		switch flag {
+		case "--room", "--home", "--model", "--cap":
+			if i+1 >= len(argv) {
+				return nil, fmt.Errorf("group: %s requires a value", flag)
+			}
+			i++
+			switch flag {
+			case "--room":
+				g.Room = argv[i]
+			case "--home":
+				g.Home = argv[i]
+			case "--model":
+				g.Model = argv[i]
+			default:
+				g.Capability = argv[i]
+			}
+			continue
+		}
+		if strings.HasPrefix(flag, "--") {
+			return nil, fmt.Errorf("group: unknown flag %s", flag)
+		}
+		g.Members = append(g.Members, flag)
+	}
+	return g, nil
+}
+
+// parseListFilter 解析 list 的筛选参数：--room、--home、--model、--online，其余参数作为名称关键字。
+func parseListFilter(argv []string) (device.Filter, error) {
+	var f device.Filter
+	for i := 0; i < len(argv); i++ {
+		flag := argv[i]
+		switch flag {
+		case "--online":
+			online := true
+			f.Online = &online
+			continue
+		case "--offline":
+			online := false
+			f.Online = &online
+			continue
+		case "--room", "--home", "--model":
+			if i+1 >= len(argv) {
+				return f, fmt.Errorf("list: %s requires a value", flag)
+			}
+			i++
+			switch flag {
+			case "--room":
+				f.Room = argv[i]
+			case "--home":
+				f.Home = argv[i]
+			default:
+				f.Model = argv[i]
+			}
+			continue
+		}
+		if strings.HasPrefix(flag, "--") {
+			return f, fmt.Errorf("list: unknown flag %s", flag)
+		}
+		f.Name = flag
+	}
+	return f, nil
+}
+
+// isNamed 报告 items 是否使用 SPEC 名称（服务.属性 或 服务.动作），数字语法中不含“.”；
+// 含任一名称即交给 runNamed，其中的数字项也由 runNamed 解析。
+func isNamed(items []string) bool {
+	for _, item := range items {
+		key, _ := splitTwins(item, "=", "")
+		if strings.Contains(key, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// runNamed 按设备 SPEC 解析名称并执行：light.on、light.on=true,light.brightness=60、
+// play-control.pause、intelligent-speaker.play-text "你好"。值按属性格式转换，无需 # 前缀。
+// 属性列表可混用数字 siid-piid，如 2-1,speaker.volume，数字项的值规则同数字语法。
+func runNamed(ctx context.Context, api *device.API, did string, items []string, arg string, decode bool) (interface{}, error) {
+	spec, err := api.ModelSpecContext(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 1 && !strings.Contains(items[0], "=") {
+		name := items[0]
+		if _, _, perr := spec.LookupProperty(name); perr != nil || arg != "" {
+			svc, act, aerr := spec.LookupAction(name)
+			if aerr != nil {
+				if arg == "" {
+					return nil, fmt.Errorf("device: spec has no property or action %s", name)
+				}
+				return nil, aerr
+			}
+			args := splitArgs(arg)
+			in := make([]interface{}, len(args))
+			for i, a := range args {
+				in[i] = namedValue(a)
+			}
+			if in, err = spec.ValidateAction(svc.IID, act.IID, in); err != nil {
+				return nil, err
+			}
+			return api.ActionContext(ctx, did, svc.IID, act.IID, in)
+		}
+	}
+	var props [][3]interface{}
+	sets := 0
+	for _, item := range items {
+		key, val := splitTwins(item, "=", "")
+		var p [3]interface{}
+		if siid, piid, ok := numericIID(key); ok {
+			p = [3]interface{}{siid, piid, stringOrValue(val)}
+		} else {
+			svc, prop, err := spec.LookupProperty(key)
+			if err != nil {
+				return nil, err
+			}
+			p = [3]interface{}{svc.IID, prop.IID, namedValue(val)}
+		}
+		if strings.Contains(item, "=") {
+			sets++
+		} else {
+			p[2] = nil
+		}
+		props = append(props, p)
+	}
+	switch sets {
+	case 0:
+		iids := make([][2]int, len(props))
+		for i, p := range props {
+			iids[i] = [2]int{p[0].(int), p[1].(int)}
+		}
+		if decode {
+			return api.ReadPropsContext(ctx, did, iids)
+		}
+		return api.GetPropsContext(ctx, did, iids)
+	case len(props):
+		if props, err = spec.ValidateProps(props); err != nil {
+			return nil, err
+		}
+		return api.SetPropsContext(ctx, did, props)
+	}
+	return nil, fmt.Errorf("cannot mix get and set in one command: %s", strings.Join(items, ","))
+}
+
+// numericIID 解析数字语法的 siid-piid，省略 piid 时为 1。
+func numericIID(key string) (int, int, bool) {
+	siidStr, piidStr := splitTwins(key, "-", "1")
+	if !isDigits(siidStr) || !isDigits(piidStr) {
+		return 0, 0, false
+	}
+	siid, _ := strconv.Atoi(siidStr)
+	piid, _ := strconv.Atoi(piidStr)
+	return siid, piid, true
+}
+
+// namedValue 去掉外层双引号；# 前缀沿用 stringOrValue，其余保留字符串交由 SPEC 转换。
+func namedValue(s string) interface{} {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	if strings.HasPrefix(s, "#") {
+		return stringOrValue(s)
+	}
+	return s
+}
+
+// splitArgs 按空白拆分参数，双引号内的空白保留（引号随参数保留，由 namedValue 去掉）。
+func splitArgs(s string) []string {
+	var out []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t'):
+			if b.Len() > 0 {
+				out = append(out, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		out = append(out, b.String())
+	}
+	return out
+}
+
 func splitTwins(s, sep, defaultRight string) (string, string) {
 	i := strings.Index(s, sep)
 	if i < 0 {
@@ -246,6 +598,220 @@ func stringOrValue(s string) interface{} {
 	return s
 }
 
+// runAlias 处理 alias [list|set <别名> <did|name>|rm <别名>]；set 时目标解析为 did 保存。
+func runAlias(ctx context.Context, api *device.API, argv []string) (interface{}, error) {
+	sub := "list"
+	if len(argv) > 0 {
+		sub = argv[0]
+	}
+	reg := api.Registry()
+	switch sub {
+	case "list":
+		return device.Aliases(reg), nil
+	case "set", "rm":
+		if reg == nil {
+			return nil, fmt.Errorf("device registry disabled, set miio.aliases in config instead")
+		}
+	default:
+		return nil, fmt.Errorf("unknown alias command %q (list|set|rm)", sub)
+	}
+	if sub == "rm" {
+		if len(argv) < 2 {
+			return nil, fmt.Errorf("alias rm requires: <alias>")
+		}
+		return nil, reg.DeleteAlias(argv[1])
+	}
+	if len(argv) < 3 {
+		return nil, fmt.Errorf("alias set requires: <alias> <did|name>")
+	}
+	d, err := api.ResolveContext(ctx, strings.Join(argv[2:], " "))
+	if err != nil {
+		return nil, err
+	}
+	if err := reg.SetAlias(argv[1], d.DID); err != nil {
+		return nil, err
+	}
+	return map[string]string{"alias": argv[1], "did": d.DID, "name": d.Name}, nil
+}
+
+// runVacuum 处理 vacuum [status|start|stop|pause|dock|fan <档位>|rooms <id,...>]，did 须为扫地机器人。
+func runVacuum(ctx context.Context, api *device.API, did string, argv []string) (interface{}, error) {
+	d, err := ctrl.New(api).DeviceContext(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := ctrl.As[ctrl.Vacuum](d)
+	if !ok {
+		return nil, &ctrl.UnsupportedError{Msg: fmt.Sprintf("%s (%s) has no vacuum service", d.Name, d.Model)}
+	}
+	sub := "status"
+	if len(argv) > 0 {
+		sub = argv[0]
+	}
+	switch sub {
+	case "status":
+		return v.State(ctx)
+	case "start":
+		err = v.Start(ctx)
+	case "stop":
+		err = v.Stop(ctx)
+	case "pause":
+		err = v.Pause(ctx)
+	case "dock":
+		err = v.Dock(ctx)
+	case "fan":
+		if len(argv) < 2 {
+			return v.FanLevels(), nil
+		}
+		level, perr := strconv.Atoi(argv[1])
+		if perr != nil {
+			return nil, usage("vacuum fan: invalid level %q", argv[1])
+		}
+		err = v.SetFanLevel(ctx, level)
+	case "rooms":
+		if len(argv) < 2 {
+			return nil, usage("vacuum rooms requires: <id,...>")
+		}
+		var rooms []int
+		for _, f := range strings.Split(strings.Join(argv[1:], ","), ",") {
+			if f = strings.TrimSpace(f); f == "" {
+				continue
+			}
+			id, perr := strconv.Atoi(f)
+			if perr != nil {
+				return nil, usage("vacuum rooms: invalid room id %q", f)
+			}
+			rooms = append(rooms, id)
+		}
+		if len(rooms) == 0 {
+			return nil, usage("vacuum rooms requires: <id,...>")
+		}
+		err = v.CleanRooms(ctx, rooms)
+	default:
+		return nil, usage("unknown vacuum command %q (status|start|stop|pause|dock|fan|rooms)", sub)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return "ok", nil
+}
+
+// specTools 为 spec 的子命令，其余参数按 spec [model|urn] [format] 处理。
+var specTools = map[string]bool{"import": true, "export": true, "versions": true, "diff": true, "check": true, "resolve": true}
+
+// runSpecTool 处理 spec import <file> | export <file> [model ...] | versions <model|urn>
+// | diff <model|urn> [urn] [json] | check | resolve <model|urn>，前三者操作本地 SPEC 缓存。
+func runSpecTool(ctx context.Context, api *device.API, argv []string) (interface{}, error) {
+	store := specs.Default()
+	switch argv[0] {
+	case "import":
+		if len(argv) < 2 {
+			return nil, fmt.Errorf("spec import requires: <file>")
+		}
+		f, err := os.Open(argv[1])
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		n, err := store.Import(f)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"imported": n, "dir": store.Dir}, nil
+	case "export":
+		if len(argv) < 2 {
+			return nil, fmt.Errorf("spec export requires: <file> [model ...]")
+		}
+		f, err := os.Create(argv[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Export(f, argv[2:]...); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return map[string]string{"exported": argv[1]}, f.Close()
+	case "check":
+		return ctrl.New(api).CheckSpecs(ctx)
+	}
+	if len(argv) < 2 {
+		return nil, fmt.Errorf("spec %s requires: <model|type_urn>", argv[0])
+	}
+	urn, err := specURN(store, argv[1])
+	if err != nil {
+		return nil, err
+	}
+	switch argv[0] {
+	case "versions":
+		return store.Versions(urn), nil
+	case "resolve":
+		raw, err := store.Instance(urn)
+		if err != nil {
+			return nil, err
+		}
+		s, matches, err := ctrl.ExplainInstance(raw)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"urn": urn, "spec": s, "matches": matches}, nil
+	}
+
+	// diff：两个参数时比较二者，一个参数时比较缓存中最近的两个版本
+	args := argv[2:]
+	asJSON := len(args) > 0 && args[len(args)-1] == "json"
+	if asJSON {
+		args = args[:len(args)-1]
+	}
+	from, to := urn, ""
+	if len(args) > 0 {
+		if to, err = specURN(store, args[0]); err != nil {
+			return nil, err
+		}
+	} else {
+		vs := store.Versions(urn)
+		if len(vs) < 2 {
+			return nil, fmt.Errorf("spec diff: only %d cached version(s) of %s, give two URNs", len(vs), urn)
+		}
+		from, to = vs[len(vs)-2], vs[len(vs)-1]
+	}
+	a, err := loadInstanceSpec(store, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadInstanceSpec(store, to)
+	if err != nil {
+		return nil, err
+	}
+	d := device.DiffSpecs(a, b)
+	if asJSON {
+		return d, nil
+	}
+	return d.String(), nil
+}
+
+// specURN 将型号解析为 URN，URN 原样返回。
+func specURN(store *specs.Store, s string) (string, error) {
+	if strings.HasPrefix(s, "urn:") {
+		return s, nil
+	}
+	all, err := store.Instances()
+	if err != nil {
+		return "", err
+	}
+	if urn := all[s]; urn != "" {
+		return urn, nil
+	}
+	return "", fmt.Errorf("spec: model not found: %s", s)
+}
+
+func loadInstanceSpec(store *specs.Store, urn string) (*device.ModelSpec, error) {
+	raw, err := store.Instance(urn)
+	if err != nil {
+		return nil, err
+	}
+	return device.ParseModelSpec(raw)
+}
+
 // Help returns command help string.
 func Help(did, prefix string) string {
 	if did == "" {
@@ -253,12 +819,18 @@ func Help(did, prefix string) string {
 	}
 	return fmt.Sprintf(`Get Props: %s [,...]
   %s1,1-2,1-3,2-1,2-2,3
+  %s2-1,2-2 --decode   按 SPEC 返回名称、单位与枚举描述
 Set Props: %s [,...]
   %s2=#60,2-2=#false,3=test
 Do Action: %s [...] 
   %s2 #NA
   %s5 Hello
   %s5-4 Hello #1
+By Name:   按 SPEC 名称（服务.属性/服务.动作，同名服务用 siid 代替服务名），值按属性格式转换
+  %slight.on,light.brightness
+  %slight.on=true,light.brightness=60
+  %splay-control.pause
+  %sintelligent-speaker.play-text "你好"
 
 Call MIoT: %s prop/get|prop/set|action <params>
   %saction '{"did":"%s","siid":5,"aiid":1,"in":["Hello"]}'
@@ -268,14 +840,35 @@ Call MiIO: %s/ <uri> <data>
 
 Devs List: %slist [name=full|name_keyword] [getVirtualModel=false|true] [getHuamiDevices=0|1]
   %slist Light true 0
+  %slist --room 客厅 [--home 家庭] [--model light] [--online]
+Homes:     %shomes  列出家庭、房间与设备分配
+Groups:    %sgroup [list|show <名称>|rm <名称>]
+  %sgroup set 卧室插座 --room 卧室 --model plug   成员也可直接列出 did 或名称，--cap switch 按 SPEC 服务筛选
+  MI_DID=@卧室插座 %s2=#false   以 @群组名 作为设备时对每个成员并发执行
+Aliases:   %salias [list|set <别名> <did|name>|rm <别名>]
+Registry:  %sregistry [list|refresh|history [did|name]]  本地设备注册表与变化记录
+Scenes:    %sscene list
+  %sscene run <场景名称|ID>
+Vacuum:    %svacuum [status|start|stop|pause|dock]  扫地机器人（vacuum、battery 服务），status 返回状态、吸力与电量
+  %svacuum fan [档位]   不带档位时列出可选档位
+  %svacuum rooms 10,11   按房间清扫（SPEC 有 start-room-sweep 时）
 
 MIoT Spec: %sspec [model_keyword|type_urn] [format=text|python|json]
   %sspec speaker
   %sspec xiaomi.wifispeaker.lx04
   %sspec_all  获取 m list 中所有型号的 SPEC（按 docs/spec.md 流程）
+  %sspec export specs.json [model ...]   导出本地 SPEC 缓存，可在离线环境 import
+  %sspec import specs.json
+  %sspec versions <model|type_urn>   列出缓存中的 SPEC 版本
+  %sspec diff <urnA> <urnB> [json]   比较两个 SPEC 版本：增删、重新编号、格式/范围变化
+  %sspec diff <model>   比较缓存中最近的两个版本
+  %sspec check   按当前 SPEC 检查 m list 中型号的 ctrl.Specs 常量
+  %sspec resolve <model|type_urn>   按类型 URN 解析 ctrl 能力，并列出每项匹配的规则
+  %s--offline spec xiaomi.wifispeaker.lx04   不联网，只用缓存与内置种子
 
 MIoT Decode: %sdecode <ssecurity> <nonce> <data> [gzip]
 `,
-		prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix,
-		prefix, prefix, did, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix)
+		prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix,
+		prefix, prefix, did, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix,
+		prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix, prefix)
 }
@@ -0,0 +1,130 @@
+package miiocommand
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/internal/device/devicetest"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// TestMain 让 SPEC 只来自空的临时缓存与内置种子，测试不联网。
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "miflow-specs")
+	if err != nil {
+		panic(err)
+	}
+	s := specs.Default()
+	s.Dir, s.InstancesPath, s.Offline = dir, "", true
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestNamedSyntax(t *testing.T) {
+	for _, tc := range []struct {
+		items []string
+		named bool
+	}{
+		{[]string{"2-1"}, false},
+		{[]string{"2=#60", "2-2=#false", "3=test"}, false},
+		{[]string{"light.on=true"}, true},
+		{[]string{"2-1", "speaker.volume"}, true},
+		{[]string{"3=a.b"}, false},
+	} {
+		if got := isNamed(tc.items); got != tc.named {
+			t.Errorf("isNamed(%v) = %v, want %v", tc.items, got, tc.named)
+		}
+	}
+	args := splitArgs(`"你好 世界" 1  #true`)
+	if want := []string{`"你好 世界"`, "1", "#true"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("splitArgs = %q, want %q", args, want)
+	}
+	vals := []interface{}{namedValue(args[0]), namedValue(args[1]), namedValue(args[2]), namedValue(`"#1"`)}
+	if want := []interface{}{"你好 世界", "1", true, "#1"}; !reflect.DeepEqual(vals, want) {
+		t.Errorf("namedValue = %#v, want %#v", vals, want)
+	}
+}
+
+// fakeDevices 为测试用的云端设备列表。
+var fakeDevices = []map[string]interface{}{
+	{"did": "1", "name": "扫地机", "model": "mijia.vacuum.v2"},
+	{"did": "2", "name": "插座", "model": "chuangmi.plug.v3"},
+	{"did": "3", "name": "音箱", "model": "xiaomi.wifispeaker.oh2"},
+}
+
+func newFakeCloud(props map[[2]int]interface{}) *devicetest.Transport {
+	cloud := devicetest.New(device.TransportCloud, fakeDevices...)
+	cloud.Props = props
+	return cloud
+}
+
+func TestRunVacuum(t *testing.T) {
+	cloud := newFakeCloud(map[[2]int]interface{}{{2, 1}: float64(1), {2, 6}: float64(2), {3, 1}: float64(60)})
+	api := device.NewAPIWithTransports(device.PolicyCloud, cloud)
+	ctx := context.Background()
+
+	got, err := RunContext(ctx, api, "扫地机", "vacuum", "m ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := got.(*ctrl.VacuumState); !ok || st.Status != 1 || st.FanLevel != 2 || st.Battery != 60 || st.Rooms {
+		t.Errorf("vacuum status = %#v", got)
+	}
+	for _, sub := range []string{"start", "dock", "fan 2"} {
+		if _, err := RunContext(ctx, api, "扫地机", "vacuum "+sub, "m "); err != nil {
+			t.Errorf("vacuum %s: %v", sub, err)
+		}
+	}
+	if want := [][2]int{{2, 1}, {3, 1}}; !reflect.DeepEqual(cloud.Actions, want) {
+		t.Errorf("actions = %v, want %v", cloud.Actions, want)
+	}
+	if cloud.Props[[2]int{2, 6}] != 2 {
+		t.Errorf("fan level = %v", cloud.Props[[2]int{2, 6}])
+	}
+	for _, tc := range []struct{ did, cmd string }{
+		{"扫地机", "vacuum rooms 10"}, // v2 没有 start-room-sweep
+		{"扫地机", "vacuum rooms ,"},
+		{"扫地机", "vacuum fan x"},
+		{"扫地机", "vacuum spin"},
+		{"插座", "vacuum start"},
+	} {
+		// 均为请求本身的错误，web 端返回 400
+		_, err := RunContext(ctx, api, tc.did, tc.cmd, "m ")
+		var usage *UsageError
+		var unsupported *ctrl.UnsupportedError
+		if !errors.As(err, &usage) && !errors.As(err, &unsupported) {
+			t.Errorf("%s %q: err = %v, want UsageError or UnsupportedError", tc.did, tc.cmd, err)
+		}
+	}
+}
+
+func TestRunNamedMixed(t *testing.T) {
+	cloud := newFakeCloud(map[[2]int]interface{}{{2, 1}: float64(40), {2, 2}: false})
+	api := device.NewAPIWithTransports(device.PolicyCloud, cloud)
+	ctx := context.Background()
+
+	got, err := RunContext(ctx, api, "音箱", "2-2,speaker.volume", "m ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{false, float64(40)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("get = %#v, want %#v", got, want)
+	}
+	if _, err := RunContext(ctx, api, "音箱", "2-2=#true,speaker.volume=30", "m "); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.Props[[2]int{2, 2}] != true || cloud.Props[[2]int{2, 1}] != 30 {
+		t.Errorf("set = %v", cloud.Props)
+	}
+	for _, cmd := range []string{"2-x,speaker.volume", "2-2,speaker.volume=30", "9-1=#1,speaker.volume=30"} {
+		if _, err := RunContext(ctx, api, "音箱", cmd, "m "); err == nil {
+			t.Errorf("%q should fail", cmd)
+		}
+	}
+}
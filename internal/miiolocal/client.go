@@ -0,0 +1,198 @@
+package miiolocal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+// DefaultTimeout 单次请求等待设备响应的时间。
+const DefaultTimeout = 5 * time.Second
+
+// Client 与单个设备通信，串行发送请求；首次请求前自动握手获取设备 ID 与时间戳。
+type Client struct {
+	Addr    string // host:port，端口缺省为 54321
+	Token   []byte
+	Timeout time.Duration
+	Retries int // 超时后重新握手并重发的次数，只用于幂等方法（见 idempotent）
+
+	mu       sync.Mutex
+	deviceID uint32
+	stamp    uint32
+	stampAt  time.Time
+	msgID    int
+}
+
+// NewClient 创建设备客户端，token 为 32 位十六进制字符串。
+func NewClient(addr, token string) (*Client, error) {
+	tok, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(Port))
+	}
+	return &Client{Addr: addr, Token: tok, Timeout: DefaultTimeout, Retries: 1}, nil
+}
+
+// RPCError 为设备返回的 JSON-RPC error。
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("miio error %d: %s", e.Code, e.Message)
+}
+
+// Call 发送 JSON-RPC 请求，返回 result 原始 JSON。设备无响应时返回 miaccount.ErrDeviceOffline；
+// 连接或握手失败（请求未发出）时错误包含 miaccount.ErrNotSent。
+func (c *Client) Call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", c.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", miaccount.ErrNotSent, err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	var lastErr error
+	sent := false // 请求是否已发出过，未发出的失败可安全改走其他通道
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if c.stampAt.IsZero() || attempt > 0 {
+			if err := c.handshake(conn); err != nil {
+				lastErr = err
+				continue
+			}
+		}
+		sent = true
+		res, err := c.send(conn, method, params)
+		if err == nil {
+			return res, nil
+		}
+		lastErr = err
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) || ctx.Err() != nil || !idempotent(method) {
+			// 写操作已发出，设备可能已执行，重发会执行两次
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	var netErr net.Error
+	if errors.As(lastErr, &netErr) && netErr.Timeout() {
+		lastErr = fmt.Errorf("miiolocal: %s no response: %w", c.Addr, miaccount.ErrDeviceOffline)
+	}
+	if !sent {
+		return nil, fmt.Errorf("%w: %w", miaccount.ErrNotSent, lastErr)
+	}
+	return nil, lastErr
+}
+
+// idempotent 报告 method 重发是否安全；set_properties、action 等写操作只发送一次。
+func idempotent(method string) bool {
+	return method == "get_properties" || method == "miIO.info"
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultTimeout
+}
+
+// handshake 发送 hello，记录设备 ID 与时间戳。
+func (c *Client) handshake(conn net.Conn) error {
+	if _, err := conn.Write(helloPacket); err != nil {
+		return err
+	}
+	buf := make([]byte, 1024)
+	if err := conn.SetReadDeadline(time.Now().Add(c.timeout())); err != nil {
+		return err
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	p, err := Decode(nil, buf[:n])
+	if err != nil {
+		return err
+	}
+	c.deviceID = p.DeviceID
+	c.stamp = p.Stamp
+	c.stampAt = time.Now()
+	return nil
+}
+
+// send 加密发送一条请求并等待相同 id 的响应。
+func (c *Client) send(conn net.Conn, method string, params interface{}) (json.RawMessage, error) {
+	c.msgID++
+	if c.msgID > 9999 {
+		c.msgID = 1
+	}
+	id := c.msgID
+	if params == nil {
+		params = []interface{}{}
+	}
+	payload, err := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+	if err != nil {
+		return nil, err
+	}
+	// 时间戳需不小于设备当前值：握手时的 stamp + 经过的秒数 + 1
+	stamp := c.stamp + uint32(time.Since(c.stampAt)/time.Second) + 1
+	pkt, err := Encode(c.Token, c.deviceID, stamp, payload)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(pkt); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(c.timeout())
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := Decode(c.Token, buf[:n])
+		if err != nil || len(p.Data) == 0 {
+			continue
+		}
+		plain, err := Decrypt(c.Token, p.Data)
+		if err != nil {
+			return nil, err
+		}
+		// 部分固件在 JSON 末尾带 \x00
+		plain = bytes.TrimRight(plain, "\x00")
+		var resp struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *RPCError       `json:"error"`
+		}
+		if err := json.Unmarshal(plain, &resp); err != nil {
+			return nil, fmt.Errorf("miiolocal: invalid response: %w", err)
+		}
+		if resp.ID != id {
+			continue
+		}
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	}
+}
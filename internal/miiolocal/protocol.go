@@ -0,0 +1,148 @@
+// Package miiolocal 实现局域网 miIO 二进制协议（UDP 54321），使用设备 token 直接控制设备。
+//
+// 报文格式：32 字节头 + AES-128-CBC 加密的 JSON。
+// 头部依次为 magic 0x2131、总长度、unknown、设备 ID、时间戳、MD5 校验（头 16 字节 + token + 数据）。
+// 密钥 key = MD5(token)，iv = MD5(key + token)，PKCS#7 填充。
+// Ref: https://github.com/rytilahti/python-miio/blob/master/miio/protocol.py
+package miiolocal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// Port miIO 协议默认 UDP 端口。
+const Port = 54321
+
+const (
+	headerLen = 32
+	magic     = 0x2131
+)
+
+// helloPacket 握手包：magic + 长度 32 + 全 0xFF。
+var helloPacket = func() []byte {
+	b := bytes.Repeat([]byte{0xff}, headerLen)
+	binary.BigEndian.PutUint16(b[0:], magic)
+	binary.BigEndian.PutUint16(b[2:], headerLen)
+	return b
+}()
+
+// Hello 返回 hello 握手包副本，用于发现局域网设备（广播到 UDP 54321）。
+func Hello() []byte {
+	return append([]byte(nil), helloPacket...)
+}
+
+// Packet 为解析后的 miIO 报文。
+type Packet struct {
+	DeviceID uint32
+	Stamp    uint32
+	Checksum [16]byte
+	Data     []byte // 加密数据（hello 为空）
+}
+
+// ParseToken 将 32 位十六进制 token 转为 16 字节。
+func ParseToken(token string) ([]byte, error) {
+	b, err := hex.DecodeString(token)
+	if err != nil || len(b) != 16 {
+		return nil, fmt.Errorf("miiolocal: invalid token %q (want 32 hex chars)", token)
+	}
+	return b, nil
+}
+
+// cipherFor 返回 token 对应的 key 与 iv。
+func cipherFor(token []byte) (key, iv []byte) {
+	k := md5.Sum(token)
+	v := md5.Sum(append(k[:], token...))
+	return k[:], v[:]
+}
+
+// Encrypt 使用 token 派生的 key/iv 加密明文。
+func Encrypt(token, plain []byte) ([]byte, error) {
+	key, iv := cipherFor(token)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	pad := aes.BlockSize - len(plain)%aes.BlockSize
+	buf := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(buf, buf)
+	return buf, nil
+}
+
+// Decrypt 解密并去除 PKCS#7 填充。
+func Decrypt(token, data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("miiolocal: ciphertext is not a multiple of block size")
+	}
+	key, iv := cipherFor(token)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte{}, data...)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(buf, buf)
+	pad := int(buf[len(buf)-1])
+	if pad == 0 || pad > aes.BlockSize || pad > len(buf) {
+		return nil, errors.New("miiolocal: bad padding (wrong token?)")
+	}
+	return buf[:len(buf)-pad], nil
+}
+
+// Encode 组装报文：加密 payload 并填写校验和。
+func Encode(token []byte, deviceID, stamp uint32, payload []byte) ([]byte, error) {
+	enc, err := Encrypt(token, payload)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, headerLen+len(enc))
+	binary.BigEndian.PutUint16(b[0:], magic)
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	binary.BigEndian.PutUint32(b[8:], deviceID)
+	binary.BigEndian.PutUint32(b[12:], stamp)
+	copy(b[headerLen:], enc)
+	sum := checksum(b[:16], token, enc)
+	copy(b[16:32], sum[:])
+	return b, nil
+}
+
+// Decode 解析报文头；token 非空且含数据时校验 MD5。
+func Decode(token, b []byte) (*Packet, error) {
+	if len(b) < headerLen {
+		return nil, fmt.Errorf("miiolocal: short packet (%d bytes)", len(b))
+	}
+	if binary.BigEndian.Uint16(b[0:]) != magic {
+		return nil, errors.New("miiolocal: bad magic")
+	}
+	n := int(binary.BigEndian.Uint16(b[2:]))
+	if n < headerLen || n > len(b) {
+		return nil, fmt.Errorf("miiolocal: bad length %d", n)
+	}
+	p := &Packet{
+		DeviceID: binary.BigEndian.Uint32(b[8:]),
+		Stamp:    binary.BigEndian.Uint32(b[12:]),
+		Data:     b[headerLen:n],
+	}
+	copy(p.Checksum[:], b[16:32])
+	if len(p.Data) > 0 && token != nil {
+		if sum := checksum(b[:16], token, p.Data); sum != p.Checksum {
+			return nil, errors.New("miiolocal: checksum mismatch (wrong token?)")
+		}
+	}
+	return p, nil
+}
+
+func checksum(header, token, data []byte) [16]byte {
+	h := md5.New()
+	h.Write(header)
+	h.Write(token)
+	h.Write(data)
+	var sum [16]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,243 @@
+package miiolocal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/miioservice"
+)
+
+// Device 为局域网可达的设备：did 与 m list 一致，Addr 为 IP 或 IP:端口。
+type Device struct {
+	DID   string
+	Name  string
+	Model string
+	Addr  string
+	Token string
+}
+
+// Service 通过局域网 miIO 协议实现 miioservice.MiotService。
+// 设备来自 Register，或在未注册时调用 Lookup 获取地址与 token。
+type Service struct {
+	Timeout time.Duration
+	// Lookup 按 did 查询地址与 token（如来自局域网发现），可为 nil
+	Lookup func(ctx context.Context, did string) (addr, token string, ok bool)
+
+	mu      sync.Mutex
+	devices map[string]*entry
+}
+
+type entry struct {
+	Device
+	client *Client
+}
+
+var _ miioservice.MiotService = (*Service)(nil)
+
+// New 创建局域网服务。
+func New() *Service {
+	return &Service{Timeout: DefaultTimeout, devices: make(map[string]*entry)}
+}
+
+// Register 注册（或更新）设备地址与 token。
+func (s *Service) Register(d Device) error {
+	c, err := NewClient(d.Addr, d.Token)
+	if err != nil {
+		return err
+	}
+	c.Timeout = s.Timeout
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.devices == nil {
+		s.devices = make(map[string]*entry)
+	}
+	s.devices[d.DID] = &entry{Device: d, client: c}
+	return nil
+}
+
+// Has 报告 did 是否可通过局域网访问（已注册或 Lookup 命中）。
+func (s *Service) Has(did string) bool {
+	return s.HasContext(context.Background(), did)
+}
+
+// HasContext 同 Has，ctx 传给 Lookup（如触发的局域网扫描）。
+func (s *Service) HasContext(ctx context.Context, did string) bool {
+	_, err := s.client(ctx, did)
+	return err == nil
+}
+
+func (s *Service) client(ctx context.Context, did string) (*Client, error) {
+	s.mu.Lock()
+	e, ok := s.devices[did]
+	s.mu.Unlock()
+	if ok {
+		return e.client, nil
+	}
+	if s.Lookup != nil {
+		if addr, token, ok := s.Lookup(ctx, did); ok {
+			if err := s.Register(Device{DID: did, Addr: addr, Token: token}); err != nil {
+				return nil, err
+			}
+			return s.client(ctx, did)
+		}
+	}
+	return nil, fmt.Errorf("miiolocal: device %s not registered: %w", did, miaccount.ErrNotSent)
+}
+
+type propResult struct {
+	DID   string      `json:"did"`
+	SIID  int         `json:"siid"`
+	PIID  int         `json:"piid"`
+	Code  int         `json:"code"`
+	Value interface{} `json:"value"`
+}
+
+// MiotGetProps 读取属性，语义同 miioservice.Service.MiotGetProps。
+func (s *Service) MiotGetProps(did string, iids [][2]int) ([]interface{}, error) {
+	return s.MiotGetPropsContext(context.Background(), did, iids)
+}
+
+// MiotGetPropsContext 通过 get_properties 读取属性；失败项为 nil，全部失败时返回 APIError。
+func (s *Service) MiotGetPropsContext(ctx context.Context, did string, iids [][2]int) ([]interface{}, error) {
+	c, err := s.client(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]map[string]interface{}, len(iids))
+	for i, iid := range iids {
+		params[i] = map[string]interface{}{"did": did, "siid": iid[0], "piid": iid[1]}
+	}
+	raw, err := c.Call(ctx, "get_properties", params)
+	if err != nil {
+		return nil, err
+	}
+	var res []propResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("miiolocal: invalid get_properties result: %w", err)
+	}
+	out := make([]interface{}, len(iids))
+	var firstErr error
+	failed := 0
+	for i, iid := range iids {
+		r, ok := findProp(res, i, iid[0], iid[1])
+		if ok && r.Code == 0 {
+			out[i] = r.Value
+			continue
+		}
+		failed++
+		if firstErr == nil {
+			code := r.Code
+			if !ok {
+				code = -1
+			}
+			firstErr = miaccount.NewAPIError(code, "")
+		}
+	}
+	if failed > 0 && failed == len(iids) {
+		return out, firstErr
+	}
+	return out, nil
+}
+
+// findProp 优先按 siid/piid 匹配，缺失时按位置。
+func findProp(res []propResult, i, siid, piid int) (propResult, bool) {
+	for _, r := range res {
+		if r.SIID == siid && r.PIID == piid {
+			return r, true
+		}
+	}
+	if i < len(res) && res[i].SIID == 0 {
+		return res[i], true
+	}
+	return propResult{}, false
+}
+
+// MiotSetProps 设置属性，语义同 miioservice.Service.MiotSetProps。
+func (s *Service) MiotSetProps(did string, props [][3]interface{}) ([]int, error) {
+	return s.MiotSetPropsContext(context.Background(), did, props)
+}
+
+// MiotSetPropsContext 通过 set_properties 设置属性，返回每项 code；有失败项时同时返回其 APIError。
+func (s *Service) MiotSetPropsContext(ctx context.Context, did string, props [][3]interface{}) ([]int, error) {
+	c, err := s.client(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	params := make([]map[string]interface{}, len(props))
+	for i, p := range props {
+		params[i] = map[string]interface{}{"did": did, "siid": p[0], "piid": p[1], "value": p[2]}
+	}
+	raw, err := c.Call(ctx, "set_properties", params)
+	if err != nil {
+		return nil, err
+	}
+	var res []propResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("miiolocal: invalid set_properties result: %w", err)
+	}
+	out := make([]int, len(res))
+	for i, r := range res {
+		out[i] = r.Code
+	}
+	return out, miaccount.CodesError(out)
+}
+
+// MiotAction 执行动作，语义同 miioservice.Service.MiotAction。
+func (s *Service) MiotAction(did string, siid, aiid int, args []interface{}) (int, error) {
+	return s.MiotActionContext(context.Background(), did, siid, aiid, args)
+}
+
+// MiotActionContext 通过 action 方法执行动作；code 判定同 MiotSetPropsContext（1 为已受理）。
+func (s *Service) MiotActionContext(ctx context.Context, did string, siid, aiid int, args []interface{}) (int, error) {
+	c, err := s.client(ctx, did)
+	if err != nil {
+		return -1, err
+	}
+	if args == nil {
+		args = []interface{}{}
+	}
+	raw, err := c.Call(ctx, "action", map[string]interface{}{"did": did, "siid": siid, "aiid": aiid, "in": args})
+	if err != nil {
+		return -1, err
+	}
+	var res struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(raw, &res)
+	return res.Code, miaccount.CodesError([]int{res.Code})
+}
+
+// DeviceList 返回已注册设备，语义同 miioservice.Service.DeviceList。
+func (s *Service) DeviceList(name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	return s.DeviceListContext(context.Background(), name, getVirtualModel, getHuamiDevices)
+}
+
+// DeviceListContext 返回已注册设备；name 非空且非 full 时按 did/name 过滤。
+func (s *Service) DeviceListContext(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]map[string]interface{}, 0, len(s.devices))
+	for _, e := range s.devices {
+		if name != "" && name != "full" && !strings.Contains(e.DID, name) && !strings.Contains(e.Name, name) {
+			continue
+		}
+		out = append(out, map[string]interface{}{
+			"name":    e.Name,
+			"model":   e.Model,
+			"did":     e.DID,
+			"token":   e.Token,
+			"localip": e.client.Addr,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i]["did"].(string) < out[j]["did"].(string) })
+	return out, nil
+}
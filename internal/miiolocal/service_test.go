@@ -0,0 +1,302 @@
+package miiolocal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+const testToken = "00112233445566778899aabbccddeeff"
+
+// simDevice 为进程内 UDP miIO 设备模拟器，保存 siid-piid 属性并记录收到的动作。
+type simDevice struct {
+	t     *testing.T
+	conn  net.PacketConn
+	token []byte
+	stamp uint32
+
+	mu      sync.Mutex
+	props   map[[2]int]interface{}
+	actions []map[string]interface{}
+	silent  bool // 为 true 时不响应（模拟离线）
+	drop    int  // 处理请求但丢弃接下来的 drop 个响应（模拟响应丢失）
+	code    int  // action 返回的 code
+	handled map[string]int
+}
+
+func newSimDevice(t *testing.T) *simDevice {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, _ := ParseToken(testToken)
+	d := &simDevice{t: t, conn: conn, token: tok, stamp: 1000, props: map[[2]int]interface{}{{2, 1}: false, {2, 2}: float64(50)}}
+	t.Cleanup(func() { conn.Close() })
+	go d.serve()
+	return d
+}
+
+func (d *simDevice) addr() string { return d.conn.LocalAddr().String() }
+
+func (d *simDevice) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := d.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		d.mu.Lock()
+		silent := d.silent
+		d.mu.Unlock()
+		if silent {
+			continue
+		}
+		pkt := buf[:n]
+		if bytes.Equal(pkt, helloPacket) {
+			reply := make([]byte, headerLen)
+			copy(reply, helloPacket)
+			binary.BigEndian.PutUint32(reply[4:], 0)
+			binary.BigEndian.PutUint32(reply[8:], 0x1234abcd)
+			binary.BigEndian.PutUint32(reply[12:], d.stamp)
+			_, _ = d.conn.WriteTo(reply, from)
+			continue
+		}
+		p, err := Decode(d.token, pkt)
+		if err != nil {
+			d.t.Errorf("sim: decode: %v", err)
+			continue
+		}
+		if p.DeviceID != 0x1234abcd || p.Stamp <= d.stamp {
+			d.t.Errorf("sim: bad header device=%x stamp=%d", p.DeviceID, p.Stamp)
+		}
+		plain, err := Decrypt(d.token, p.Data)
+		if err != nil {
+			d.t.Errorf("sim: decrypt: %v", err)
+			continue
+		}
+		var req struct {
+			ID     int             `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		_ = json.Unmarshal(plain, &req)
+		resp := map[string]interface{}{"id": req.ID, "result": d.handle(req.Method, req.Params)}
+		if req.Method == "unknown" {
+			resp = map[string]interface{}{"id": req.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"}}
+		}
+		d.mu.Lock()
+		drop := d.drop > 0
+		if drop {
+			d.drop--
+		}
+		d.mu.Unlock()
+		if drop {
+			continue
+		}
+		body, _ := json.Marshal(resp)
+		out, _ := Encode(d.token, p.DeviceID, p.Stamp, append(body, 0))
+		_, _ = d.conn.WriteTo(out, from)
+	}
+}
+
+func (d *simDevice) handle(method string, params json.RawMessage) interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.handled == nil {
+		d.handled = make(map[string]int)
+	}
+	d.handled[method]++
+	switch method {
+	case "get_properties", "set_properties":
+		var ps []map[string]interface{}
+		_ = json.Unmarshal(params, &ps)
+		out := make([]map[string]interface{}, 0, len(ps))
+		for _, p := range ps {
+			k := [2]int{int(p["siid"].(float64)), int(p["piid"].(float64))}
+			r := map[string]interface{}{"did": p["did"], "siid": k[0], "piid": k[1]}
+			v, ok := d.props[k]
+			switch {
+			case !ok:
+				r["code"] = -4003
+			case method == "set_properties":
+				d.props[k] = p["value"]
+				r["code"] = 0
+			default:
+				r["code"] = 0
+				r["value"] = v
+			}
+			out = append(out, r)
+		}
+		return out
+	case "action":
+		var a map[string]interface{}
+		_ = json.Unmarshal(params, &a)
+		d.actions = append(d.actions, a)
+		return map[string]interface{}{"code": d.code, "out": []interface{}{}}
+	}
+	return nil
+}
+
+func TestProtocolRoundtrip(t *testing.T) {
+	tok, _ := ParseToken(testToken)
+	payload := []byte(`{"id":1,"method":"miIO.info","params":[]}`)
+	pkt, err := Encode(tok, 42, 7, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Decode(tok, pkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.DeviceID != 42 || p.Stamp != 7 {
+		t.Errorf("header = %d/%d", p.DeviceID, p.Stamp)
+	}
+	plain, err := Decrypt(tok, p.Data)
+	if err != nil || !bytes.Equal(plain, payload) {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+	other, _ := ParseToken("ffeeddccbbaa99887766554433221100")
+	if _, err := Decode(other, pkt); err == nil {
+		t.Error("Decode with wrong token should fail checksum")
+	}
+}
+
+func TestServiceAgainstSimulator(t *testing.T) {
+	sim := newSimDevice(t)
+	s := New()
+	if err := s.Register(Device{DID: "100", Name: "台灯", Model: "test.light.v1", Addr: sim.addr(), Token: testToken}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	codes, err := s.MiotSetPropsContext(ctx, "100", [][3]interface{}{{2, 1, true}, {2, 2, 80}})
+	if err != nil || len(codes) != 2 || codes[0] != 0 || codes[1] != 0 {
+		t.Fatalf("SetProps = %v, %v", codes, err)
+	}
+	vals, err := s.MiotGetPropsContext(ctx, "100", [][2]int{{2, 1}, {2, 2}, {9, 9}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vals[0] != true || vals[1] != float64(80) || vals[2] != nil {
+		t.Errorf("GetProps = %v", vals)
+	}
+	if _, err := s.MiotGetPropsContext(ctx, "100", [][2]int{{9, 9}}); err == nil {
+		t.Error("GetProps of missing prop should fail")
+	}
+	if code, err := s.MiotActionContext(ctx, "100", 5, 1, []interface{}{"hi"}); err != nil || code != 0 {
+		t.Fatalf("Action = %d, %v", code, err)
+	}
+	sim.mu.Lock()
+	if len(sim.actions) != 1 || sim.actions[0]["aiid"] != float64(1) {
+		t.Errorf("actions = %v", sim.actions)
+	}
+	sim.mu.Unlock()
+	// 与 set_properties 一致：1 为已受理（异步执行），负数为失败
+	for code, ok := range map[int]bool{1: true, -4004: false} {
+		sim.mu.Lock()
+		sim.code = code
+		sim.mu.Unlock()
+		got, err := s.MiotActionContext(ctx, "100", 5, 1, nil)
+		if got != code || (err == nil) != ok {
+			t.Errorf("Action with code %d = %d, %v", code, got, err)
+		}
+	}
+
+	list, err := s.DeviceListContext(ctx, "台灯", false, 0)
+	if err != nil || len(list) != 1 || list[0]["did"] != "100" {
+		t.Errorf("DeviceList = %v, %v", list, err)
+	}
+}
+
+func TestServiceRPCError(t *testing.T) {
+	sim := newSimDevice(t)
+	c, err := NewClient(sim.addr(), testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Call(context.Background(), "unknown", nil)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Fatalf("err = %v, want RPCError -32601", err)
+	}
+}
+
+func TestServiceOffline(t *testing.T) {
+	sim := newSimDevice(t)
+	sim.mu.Lock()
+	sim.silent = true
+	sim.mu.Unlock()
+	s := New()
+	s.Timeout = 50 * time.Millisecond
+	_ = s.Register(Device{DID: "100", Addr: sim.addr(), Token: testToken})
+	_, err := s.MiotGetPropsContext(context.Background(), "100", [][2]int{{2, 1}})
+	if !errors.Is(err, miaccount.ErrDeviceOffline) {
+		t.Fatalf("err = %v, want ErrDeviceOffline", err)
+	}
+	// 握手无响应时请求未发出，写操作可改走云端
+	if !miaccount.NotSent(err) {
+		t.Errorf("err = %v, want not sent", err)
+	}
+}
+
+func TestClientRetriesOnlyIdempotent(t *testing.T) {
+	sim := newSimDevice(t)
+	c, err := NewClient(sim.addr(), testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Timeout = 50 * time.Millisecond
+	ctx := context.Background()
+
+	// 读操作响应丢失后重新握手并重发
+	sim.mu.Lock()
+	sim.drop = 1
+	sim.mu.Unlock()
+	if _, err := c.Call(ctx, "get_properties", []map[string]interface{}{{"did": "100", "siid": 2, "piid": 1}}); err != nil {
+		t.Fatalf("get_properties after dropped reply = %v", err)
+	}
+
+	// 写操作已发出，响应丢失时报超时且不重发
+	for _, method := range []string{"set_properties", "action"} {
+		sim.mu.Lock()
+		sim.drop = 1
+		sim.mu.Unlock()
+		params := interface{}([]map[string]interface{}{{"did": "100", "siid": 2, "piid": 1, "value": true}})
+		if method == "action" {
+			params = map[string]interface{}{"did": "100", "siid": 5, "aiid": 1, "in": []interface{}{}}
+		}
+		_, err := c.Call(ctx, method, params)
+		if !errors.Is(err, miaccount.ErrDeviceOffline) || miaccount.NotSent(err) {
+			t.Errorf("%s err = %v, want sent timeout", method, err)
+		}
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	want := map[string]int{"get_properties": 2, "set_properties": 1, "action": 1}
+	if !reflect.DeepEqual(sim.handled, want) {
+		t.Errorf("handled = %v, want %v", sim.handled, want)
+	}
+}
+
+func TestServiceLookup(t *testing.T) {
+	sim := newSimDevice(t)
+	s := New()
+	s.Lookup = func(ctx context.Context, did string) (string, string, bool) {
+		return sim.addr(), testToken, did == "200"
+	}
+	if !s.Has("200") || s.Has("300") {
+		t.Fatal("Has via Lookup")
+	}
+	if _, err := s.MiotGetPropsContext(context.Background(), "200", [][2]int{{2, 2}}); err != nil {
+		t.Fatal(err)
+	}
+}
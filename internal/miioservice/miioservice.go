@@ -2,6 +2,7 @@ package miioservice
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -9,18 +10,27 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/zeusro/miflow/internal/config"
 	"github.com/zeusro/miflow/internal/miaccount"
 	"github.com/zeusro/miflow/internal/mihomeapi"
+	"github.com/zeusro/miflow/miiot/specs"
 )
 
+// MiotService 为 MIoT 属性读写、动作与设备列表的公共方法集。
+// 云端 Service 与局域网 miiolocal.Service 都实现该接口。
+type MiotService interface {
+	MiotGetPropsContext(ctx context.Context, did string, iids [][2]int) ([]interface{}, error)
+	MiotSetPropsContext(ctx context.Context, did string, props [][3]interface{}) ([]int, error)
+	MiotActionContext(ctx context.Context, did string, siid, aiid int, args []interface{}) (int, error)
+	DeviceListContext(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error)
+}
+
+var _ MiotService = (*Service)(nil)
+
 // Service implements MiIO/MIoT API via ha.api.io.mi.com (OAuth 2.0).
 // Ref: https://github.com/XiaoMi/ha_xiaomi_home
 type Service struct {
@@ -43,6 +53,9 @@ func New(token *miaccount.OAuthToken, tokenPath string) (*Service, error) {
 	return &Service{ha: ha}, nil
 }
 
+// HAClient returns the underlying OAuth HTTP client (token, client id, cloud server).
+func (s *Service) HAClient() *miaccount.HAClient { return s.ha.Client }
+
 // SignNonce computes key for request signing (used by MiotDecode).
 func SignNonce(ssecurity, nonce string) (string, error) {
 	sb, err := base64.StdEncoding.DecodeString(ssecurity)
@@ -102,13 +115,18 @@ func (s *Service) HomeSetProp(did, prop string, value interface{}) (interface{},
 
 // MiotRequest calls miotspec prop/get, prop/set, or action via HA API.
 func (s *Service) MiotRequest(cmd string, params interface{}) ([]map[string]interface{}, error) {
+	return s.MiotRequestContext(context.Background(), cmd, params)
+}
+
+// MiotRequestContext is like MiotRequest but honours ctx cancellation.
+func (s *Service) MiotRequestContext(ctx context.Context, cmd string, params interface{}) ([]map[string]interface{}, error) {
 	switch cmd {
 	case "prop/get":
 		pm, ok := toParamsArray(params)
 		if !ok {
 			return nil, fmt.Errorf("prop/get expects params array")
 		}
-		res, err := s.ha.GetProps(pm)
+		res, err := s.ha.GetPropsContext(ctx, pm)
 		if err != nil {
 			return nil, err
 		}
@@ -118,7 +136,7 @@ func (s *Service) MiotRequest(cmd string, params interface{}) ([]map[string]inte
 		if !ok {
 			return nil, fmt.Errorf("prop/set expects params array")
 		}
-		_, err := s.ha.SetProps(pm)
+		_, err := s.ha.SetPropsContext(ctx, pm)
 		if err != nil {
 			return nil, err
 		}
@@ -132,7 +150,7 @@ func (s *Service) MiotRequest(cmd string, params interface{}) ([]map[string]inte
 		siid, _ := toInt(p["siid"])
 		aiid, _ := toInt(p["aiid"])
 		inRaw, _ := p["in"].([]interface{})
-		_, err := s.ha.Action(did, siid, aiid, inRaw)
+		_, err := s.ha.ActionContext(ctx, did, siid, aiid, inRaw)
 		if err != nil {
 			return nil, err
 		}
@@ -170,32 +188,53 @@ func toInt(v interface{}) (int, bool) {
 
 // MiotGetProps gets MIoT properties.
 func (s *Service) MiotGetProps(did string, iids [][2]int) ([]interface{}, error) {
+	return s.MiotGetPropsContext(context.Background(), did, iids)
+}
+
+// MiotGetPropsContext is like MiotGetProps but honours ctx cancellation.
+func (s *Service) MiotGetPropsContext(ctx context.Context, did string, iids [][2]int) ([]interface{}, error) {
 	params := make([]map[string]interface{}, len(iids))
 	for i, iid := range iids {
 		params[i] = map[string]interface{}{"did": did, "siid": iid[0], "piid": iid[1]}
 	}
-	result, err := s.ha.GetProps(params)
+	result, err := s.ha.GetPropsContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 	out := make([]interface{}, len(result))
+	var firstErr error
+	failed := 0
 	for i, m := range result {
 		if code, _ := m["code"].(float64); code == 0 {
 			out[i] = m["value"]
 		} else {
 			out[i] = nil
+			failed++
+			if firstErr == nil {
+				firstErr = miaccount.NewAPIError(int(code), "")
+			}
 		}
 	}
+	// 全部失败时返回错误（如设备离线），部分失败仍返回 nil 值
+	if failed > 0 && failed == len(result) {
+		return out, firstErr
+	}
 	return out, nil
 }
 
 // MiotSetProps sets MIoT properties.
 func (s *Service) MiotSetProps(did string, props [][3]interface{}) ([]int, error) {
+	return s.MiotSetPropsContext(context.Background(), did, props)
+}
+
+// MiotSetPropsContext is like MiotSetProps but honours ctx cancellation.
+// Per-item codes are always returned; a failed item (e.g. device offline) is also reported as *miaccount.APIError.
+func (s *Service) MiotSetPropsContext(ctx context.Context, did string, props [][3]interface{}) ([]int, error) {
 	params := make([]map[string]interface{}, len(props))
 	for i, p := range props {
 		params[i] = map[string]interface{}{"did": did, "siid": p[0], "piid": p[1], "value": p[2]}
 	}
-	result, err := s.ha.SetProps(params)
+	result, err := s.ha.SetPropsContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -204,52 +243,82 @@ func (s *Service) MiotSetProps(did string, props [][3]interface{}) ([]int, error
 		code, _ := m["code"].(float64)
 		out[i] = int(code)
 	}
-	return out, nil
+	return out, miaccount.CodesError(out)
 }
 
 // MiotAction runs a MIoT action.
 func (s *Service) MiotAction(did string, siid, aiid int, args []interface{}) (int, error) {
-	_, err := s.ha.Action(did, siid, aiid, args)
+	return s.MiotActionContext(context.Background(), did, siid, aiid, args)
+}
+
+// MiotActionContext is like MiotAction but honours ctx cancellation. Codes are judged like
+// MiotSetPropsContext: 1 (accepted, runs asynchronously) is success, negative codes are errors.
+func (s *Service) MiotActionContext(ctx context.Context, did string, siid, aiid int, args []interface{}) (int, error) {
+	res, err := s.ha.ActionContext(ctx, did, siid, aiid, args)
 	if err != nil {
 		return -1, err
 	}
-	return 0, nil
+	code, _ := res["code"].(float64)
+	return int(code), miaccount.CodesError([]int{int(code)})
 }
 
 // DeviceList returns devices.
 func (s *Service) DeviceList(name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
-	return s.ha.DeviceList(name, getVirtualModel, getHuamiDevices)
+	return s.DeviceListContext(context.Background(), name, getVirtualModel, getHuamiDevices)
+}
+
+// DeviceListContext is like DeviceList but honours ctx cancellation.
+func (s *Service) DeviceListContext(ctx context.Context, name string, getVirtualModel bool, getHuamiDevices int) ([]map[string]interface{}, error) {
+	return s.ha.DeviceListContext(ctx, name, getVirtualModel, getHuamiDevices)
+}
+
+// HomeList returns homes with rooms and device assignment.
+func (s *Service) HomeList() ([]mihomeapi.Home, error) {
+	return s.HomeListContext(context.Background())
+}
+
+// HomeListContext is like HomeList but honours ctx cancellation.
+func (s *Service) HomeListContext(ctx context.Context) ([]mihomeapi.Home, error) {
+	return s.ha.HomeListContext(ctx)
+}
+
+// SceneList returns manual scenes of all homes.
+func (s *Service) SceneList() ([]mihomeapi.Scene, error) {
+	return s.SceneListContext(context.Background())
+}
+
+// SceneListContext is like SceneList but honours ctx cancellation.
+func (s *Service) SceneListContext(ctx context.Context) ([]mihomeapi.Scene, error) {
+	return s.ha.SceneListContext(ctx)
+}
+
+// RunScene triggers a manual scene by ID.
+func (s *Service) RunScene(sceneID string) error {
+	return s.RunSceneContext(context.Background(), sceneID)
 }
 
-// MiotSpec fetches MIoT spec from miot-spec.org (public, no auth).
+// RunSceneContext is like RunScene but honours ctx cancellation.
+func (s *Service) RunSceneContext(ctx context.Context, sceneID string) error {
+	return s.ha.RunSceneContext(ctx, sceneID)
+}
+
+// MiotSpec fetches MIoT spec from miot-spec.org (public, no auth) through the
+// local spec store, so cached and seeded specs resolve offline.
 func (s *Service) MiotSpec(typ, format string) (interface{}, error) {
-	specsPath := config.Get().MiIO.SpecsCachePath
-	if specsPath == "" {
-		specsPath = filepath.Join(os.TempDir(), "miservice_miot_specs.json")
-	}
-	allSpecs := make(map[string]string)
-	if data, err := os.ReadFile(specsPath); err == nil {
-		json.Unmarshal(data, &allSpecs)
-	}
-	if len(allSpecs) == 0 {
-		resp, err := http.DefaultClient.Get("http://miot-spec.org/miot-spec-v2/instances?status=all")
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		var inst struct {
-			Instances []struct {
-				Model string `json:"model"`
-				Type  string `json:"type"`
-			} `json:"instances"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&inst); err != nil {
-			return nil, err
-		}
-		for _, i := range inst.Instances {
-			allSpecs[i.Model] = i.Type
-		}
-		os.WriteFile(specsPath, mustJSON(allSpecs), 0644)
+	return s.MiotSpecContext(context.Background(), typ, format)
+}
+
+// MiotSpecContext is like MiotSpec but honours ctx cancellation.
+func (s *Service) MiotSpecContext(ctx context.Context, typ, format string) (interface{}, error) {
+	return FetchMiotSpec(ctx, typ, format)
+}
+
+// FetchMiotSpec is MiotSpecContext without a Service: specs are public, so no
+// login is needed.
+func FetchMiotSpec(ctx context.Context, typ, format string) (interface{}, error) {
+	allSpecs, err := specs.Load()
+	if err != nil {
+		return nil, err
 	}
 	if typ != "" && !strings.HasPrefix(typ, "urn:") {
 		// 精确匹配优先：若 typ 为完整 model 且存在于 allSpecs，直接取 URN
@@ -283,28 +352,24 @@ func (s *Service) MiotSpec(typ, format string) (interface{}, error) {
 			}
 		}
 	}
-	reqURL := "http://miot-spec.org/miot-spec-v2/instance?type=" + url.QueryEscape(typ)
-	resp, err := http.DefaultClient.Get(reqURL)
+	reqURL := specs.InstanceURL + "?type=" + url.QueryEscape(typ)
+	result, err := specs.FetchInstanceContext(ctx, typ)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
 	if format == "json" {
 		return result, nil
 	}
-	return formatMiotSpecText(result, format, reqURL), nil
-}
-
-func mustJSON(v interface{}) []byte {
-	b, _ := json.Marshal(v)
-	return b
+	// 按 miio.spec_locale 在注释中附加翻译描述，翻译不可用时只有英文
+	locale := config.Get().MiIO.SpecLocale
+	var tr specs.Translation
+	if specs.NormalizeLocale(locale) != "en" {
+		tr, _ = specs.TranslateContext(ctx, typ)
+	}
+	return formatMiotSpecText(result, format, reqURL, tr, locale), nil
 }
 
-func formatMiotSpecText(result map[string]interface{}, format, reqURL string) string {
+func formatMiotSpecText(result map[string]interface{}, format, reqURL string, tr specs.Translation, locale string) string {
 	var buf bytes.Buffer
 	buf.WriteString("# Generated by github.com/zeusro/miflow\n# ")
 	buf.WriteString(reqURL)
@@ -316,13 +381,14 @@ func formatMiotSpecText(result map[string]interface{}, format, reqURL string) st
 		desc, _ := svc["description"].(string)
 		svcName := strings.ReplaceAll(desc, " ", "_")
 		if format == "python" {
-			buf.WriteString(fmt.Sprintf("\nclass %s(tuple, Enum):\n", svcName))
+			buf.WriteString(fmt.Sprintf("\nclass %s(tuple, Enum):%s\n", svcName, translated("", tr.Lookup(locale, specs.ServiceKey(int(siid))), desc)))
 		}
 		for _, p := range toSlice(svc["properties"]) {
 			prop, _ := p.(map[string]interface{})
 			piid, _ := prop["iid"].(float64)
 			pdesc, _ := prop["description"].(string)
 			name, comment := parseDesc(pdesc)
+			comment = translated(comment, tr.Lookup(locale, specs.PropertyKey(int(siid), int(piid))), pdesc)
 			if format == "python" {
 				buf.WriteString(fmt.Sprintf("  %s = (%d, %d)%s\n", name, int(siid), int(piid), comment))
 			} else {
@@ -334,6 +400,7 @@ func formatMiotSpecText(result map[string]interface{}, format, reqURL string) st
 			aiid, _ := act["iid"].(float64)
 			adesc, _ := act["description"].(string)
 			name, comment := parseDesc(adesc)
+			comment = translated(comment, tr.Lookup(locale, specs.ActionKey(int(siid), int(aiid))), adesc)
 			if format == "python" {
 				buf.WriteString(fmt.Sprintf("  %s = (%d, %d)%s\n", name, int(siid), int(aiid), comment))
 			} else {
@@ -344,6 +411,17 @@ func formatMiotSpecText(result map[string]interface{}, format, reqURL string) st
 	return buf.String()
 }
 
+// translated appends the translation t to comment, unless t is empty or equals the English description.
+func translated(comment, t, english string) string {
+	if t == "" || t == english {
+		return comment
+	}
+	if comment == "" {
+		return " # " + t
+	}
+	return comment + " " + t
+}
+
 func toSlice(v interface{}) []interface{} {
 	if v == nil {
 		return nil
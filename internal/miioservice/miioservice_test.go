@@ -0,0 +1,40 @@
+package miioservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/mihomeapi"
+)
+
+// newActionService returns a Service whose /miotspec/action replies with the given code.
+func newActionService(t *testing.T, code int) *Service {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "result": map[string]interface{}{"code": code}})
+	}))
+	t.Cleanup(srv.Close)
+	return &Service{ha: &mihomeapi.Service{Client: &miaccount.HAClient{
+		BaseURL:    srv.URL,
+		HTTP:       &http.Client{Timeout: 5 * time.Second},
+		OAuthToken: &miaccount.OAuthToken{AccessToken: "token"},
+	}}}
+}
+
+func TestMiotActionCodes(t *testing.T) {
+	for code, ok := range map[int]bool{0: true, 1: true, -704220043: false} {
+		got, err := newActionService(t, code).MiotActionContext(context.Background(), "1", 5, 1, nil)
+		if got != code || (err == nil) != ok {
+			t.Errorf("code %d: MiotAction = %d, %v", code, got, err)
+		}
+		var apiErr *miaccount.APIError
+		if !ok && !errors.As(err, &apiErr) {
+			t.Errorf("code %d: err = %T, want *APIError", code, err)
+		}
+	}
+}
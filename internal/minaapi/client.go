@@ -4,6 +4,7 @@ package minaapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,7 +49,7 @@ func New(t *miaccount.OAuthToken, tokenPath string) *Client {
 	}
 }
 
-func (c *Client) ensureToken() error {
+func (c *Client) ensureToken(ctx context.Context) error {
 	if c.OAuthToken == nil || c.OAuthToken.AccessToken == "" {
 		return fmt.Errorf("no OAuth token, run 'm login' first")
 	}
@@ -62,7 +63,7 @@ func (c *Client) ensureToken() error {
 		oc.RedirectURI = c.OAuthToken.OAuthRedirect
 		oc.DeviceID = c.OAuthToken.DeviceID
 		oc.State = c.OAuthToken.State
-		newT, err := oc.RefreshToken(c.OAuthToken.RefreshToken)
+		newT, err := oc.RefreshTokenContext(ctx, c.OAuthToken.RefreshToken)
 		if err != nil {
 			return err
 		}
@@ -95,7 +96,12 @@ func randString(n int) string {
 
 // MinaRequest calls MiNA API. uri should start with /. Uses GET when data is nil.
 func (c *Client) MinaRequest(uri string, data map[string]interface{}) (map[string]interface{}, error) {
-	if err := c.ensureToken(); err != nil {
+	return c.MinaRequestContext(context.Background(), uri, data)
+}
+
+// MinaRequestContext is like MinaRequest but honours ctx cancellation.
+func (c *Client) MinaRequestContext(ctx context.Context, uri string, data map[string]interface{}) (map[string]interface{}, error) {
+	if err := c.ensureToken(ctx); err != nil {
 		return nil, err
 	}
 	requestID := "app_ios_" + randString(30)
@@ -123,7 +129,7 @@ func (c *Client) MinaRequest(uri string, data map[string]interface{}) (map[strin
 	if data == nil {
 		method = "GET"
 	}
-	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -168,8 +174,13 @@ func (c *Client) MinaRequest(uri string, data map[string]interface{}) (map[strin
 
 // DeviceList returns mina devices. master=0 for all. Uses GET per MiService.
 func (c *Client) DeviceList(master int) ([]map[string]interface{}, error) {
+	return c.DeviceListContext(context.Background(), master)
+}
+
+// DeviceListContext is like DeviceList but honours ctx cancellation.
+func (c *Client) DeviceListContext(ctx context.Context, master int) ([]map[string]interface{}, error) {
 	uri := fmt.Sprintf("/admin/v2/device_list?master=%d", master)
-	res, err := c.MinaRequest(uri, nil)
+	res, err := c.MinaRequestContext(ctx, uri, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -188,6 +199,11 @@ func (c *Client) DeviceList(master int) ([]map[string]interface{}, error) {
 
 // UbusRequest sends ubus RPC to device.
 func (c *Client) UbusRequest(deviceID, method, path string, message interface{}) (map[string]interface{}, error) {
+	return c.UbusRequestContext(context.Background(), deviceID, method, path, message)
+}
+
+// UbusRequestContext is like UbusRequest but honours ctx cancellation.
+func (c *Client) UbusRequestContext(ctx context.Context, deviceID, method, path string, message interface{}) (map[string]interface{}, error) {
 	msgJSON, err := json.Marshal(message)
 	if err != nil {
 		return nil, err
@@ -198,7 +214,7 @@ func (c *Client) UbusRequest(deviceID, method, path string, message interface{})
 		"method":   method,
 		"path":     path,
 	}
-	return c.MinaRequest("/remote/ubus", data)
+	return c.MinaRequestContext(ctx, "/remote/ubus", data)
 }
 
 // Hardware models that require play_by_music_url instead of player_play_url.
@@ -211,8 +227,13 @@ var usePlayMusicAPI = map[string]bool{
 
 // PlayByURL plays audio from URL. Uses play_by_music_url for L06A etc., else player_play_url.
 func (c *Client) PlayByURL(deviceID, url string, typ int) (map[string]interface{}, error) {
+	return c.PlayByURLContext(context.Background(), deviceID, url, typ)
+}
+
+// PlayByURLContext is like PlayByURL but honours ctx cancellation.
+func (c *Client) PlayByURLContext(ctx context.Context, deviceID, url string, typ int) (map[string]interface{}, error) {
 	// Resolve hardware from device list to choose API
-	devices, err := c.DeviceList(0)
+	devices, err := c.DeviceListContext(ctx, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -228,18 +249,23 @@ func (c *Client) PlayByURL(deviceID, url string, typ int) (map[string]interface{
 		}
 	}
 	if usePlayMusicAPI[strings.ToUpper(hardware)] {
-		return c.PlayByMusicURL(deviceID, url, typ)
+		return c.PlayByMusicURLContext(ctx, deviceID, url, typ)
 	}
 	msg := map[string]interface{}{
 		"url":   url,
 		"type":  typ,
 		"media": "app_ios",
 	}
-	return c.UbusRequest(deviceID, "player_play_url", "mediaplayer", msg)
+	return c.UbusRequestContext(ctx, deviceID, "player_play_url", "mediaplayer", msg)
 }
 
 // PlayByMusicURL uses player_play_music for L06A/LX05 etc. Ref: MiService play_by_music_url.
 func (c *Client) PlayByMusicURL(deviceID, url string, typ int) (map[string]interface{}, error) {
+	return c.PlayByMusicURLContext(context.Background(), deviceID, url, typ)
+}
+
+// PlayByMusicURLContext is like PlayByMusicURL but honours ctx cancellation.
+func (c *Client) PlayByMusicURLContext(ctx context.Context, deviceID, url string, typ int) (map[string]interface{}, error) {
 	audioID := "1582971365183456177"
 	id := "355454500"
 	audioType := ""
@@ -275,5 +301,5 @@ func (c *Client) PlayByMusicURL(deviceID, url string, typ int) (map[string]inter
 		"startaudioid": audioID,
 		"music":        string(musicJSON),
 	}
-	return c.UbusRequest(deviceID, "player_play_music", "mediaplayer", msg)
+	return c.UbusRequestContext(ctx, deviceID, "player_play_music", "mediaplayer", msg)
 }
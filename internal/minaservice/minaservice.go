@@ -1,12 +1,16 @@
 package minaservice
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miaccount"
 	"github.com/zeusro/miflow/internal/miioservice"
 	"github.com/zeusro/miflow/internal/minaapi"
+	"github.com/zeusro/miflow/internal/registry"
 )
 
 // TTS uses intelligent-speaker service (siid=5).
@@ -25,6 +29,7 @@ const (
 type Service struct {
 	MiIO     *miioservice.Service
 	MinaAPI  *minaapi.Client
+	Registry *registry.Store // 设备注册表，名称解析优先查本地索引，可为 nil
 }
 
 // New creates MiNA service backed by MiIO (OAuth).
@@ -34,7 +39,7 @@ func New(miio *miioservice.Service) *Service {
 
 // NewWithMinaAPI creates service with MinaAPI for play_by_url (api2.mina.mi.com).
 func NewWithMinaAPI(miio *miioservice.Service, token *miaccount.OAuthToken, tokenPath string) *Service {
-	s := &Service{MiIO: miio}
+	s := &Service{MiIO: miio, Registry: registry.Default()}
 	if token != nil && token.IsValid() {
 		s.MinaAPI = minaapi.New(token, tokenPath)
 	}
@@ -43,7 +48,12 @@ func NewWithMinaAPI(miio *miioservice.Service, token *miaccount.OAuthToken, toke
 
 // DeviceList returns speaker devices from MiIO device list.
 func (s *Service) DeviceList(master int) ([]map[string]interface{}, error) {
-	list, err := s.MiIO.DeviceList("", false, 0)
+	return s.DeviceListContext(context.Background(), master)
+}
+
+// DeviceListContext is like DeviceList but honours ctx cancellation.
+func (s *Service) DeviceListContext(ctx context.Context, master int) ([]map[string]interface{}, error) {
+	list, err := s.MiIO.DeviceListContext(ctx, "", false, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -68,13 +78,21 @@ func isSpeaker(model string) bool {
 // play-text (aiid=3) 仅需 [text]；execute-text-directive 需 [text]，格式错误会导致不播放。
 // Ref: ha_xiaomi_home issue #57 - 正确格式为 ["文本"]，不能多传 silent 等参数。
 func (s *Service) TextToSpeech(did string, text string) (map[string]interface{}, error) {
+	return s.TextToSpeechContext(context.Background(), did, text)
+}
+
+// TextToSpeechContext is like TextToSpeech but honours ctx cancellation.
+func (s *Service) TextToSpeechContext(ctx context.Context, did string, text string) (map[string]interface{}, error) {
 	args := []interface{}{text}
 	var lastErr error
 	for _, aiid := range []int{TTSaiidPlay, TTSaiidDirect, 5} {
-		_, err := s.MiIO.MiotAction(did, TTSsiid, aiid, args)
+		_, err := s.MiIO.MiotActionContext(ctx, did, TTSsiid, aiid, args)
 		if err == nil {
 			return map[string]interface{}{"code": 0}, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		lastErr = err
 	}
 	return nil, fmt.Errorf("TTS failed: %w", lastErr)
@@ -83,65 +101,150 @@ func (s *Service) TextToSpeech(did string, text string) (map[string]interface{},
 // GetMinaDeviceID returns device ID for the given MI_DID (did or name).
 // When MinaAPI is available, uses Mina device list (deviceID) for play compatibility.
 func (s *Service) GetMinaDeviceID(miDID string) (string, error) {
+	return s.GetMinaDeviceIDContext(context.Background(), miDID)
+}
+
+// GetMinaDeviceIDContext is like GetMinaDeviceID but honours ctx cancellation.
+// Names are resolved among speakers with device.Resolve ranking (did > name > alias > fuzzy);
+// several equally ranked speakers yield *device.AmbiguousDeviceError.
+func (s *Service) GetMinaDeviceIDContext(ctx context.Context, miDID string) (string, error) {
+	var minaDevices []map[string]interface{}
 	if s.MinaAPI != nil {
 		// Mina API expects deviceID from its own device list
-		devices, err := s.MinaAPI.DeviceList(0)
-		if err == nil {
-			for _, d := range devices {
-				deviceID, _ := d["deviceID"].(string)
-				name, _ := d["name"].(string)
-				did, _ := d["did"].(string)
-				if deviceID == miDID || did == miDID || strings.Contains(name, miDID) {
+		if list, err := s.MinaAPI.DeviceListContext(ctx, 0); err == nil {
+			minaDevices = list
+			for _, d := range list {
+				if deviceID, _ := d["deviceID"].(string); deviceID != "" && deviceID == miDID {
 					return deviceID, nil
 				}
 			}
 		}
 	}
-	// Fallback: ha device list (did)
-	list, err := s.DeviceList(0)
+	did, err := s.resolveDID(ctx, miDID)
 	if err != nil {
 		return "", err
 	}
-	for _, d := range list {
-		did, _ := d["did"].(string)
-		name, _ := d["name"].(string)
-		if did == miDID || strings.Contains(name, miDID) {
-			return did, nil
+	for _, d := range minaDevices {
+		if v, _ := d["did"].(string); v == did {
+			deviceID, _ := d["deviceID"].(string)
+			return deviceID, nil
 		}
 	}
-	return "", fmt.Errorf("device not found: %s (use 'm mina' to list)", miDID)
+	// Fallback: ha device list (did)
+	return did, nil
+}
+
+// resolveDID 在音箱中解析 did 或名称，候选优先取设备注册表，未命中再请求设备列表。
+func (s *Service) resolveDID(ctx context.Context, q string) (string, error) {
+	aliases := device.Aliases(s.Registry)
+	var lastErr error
+	for _, fromRegistry := range []bool{true, false} {
+		var raw []map[string]interface{}
+		if fromRegistry {
+			if s.Registry == nil || s.Registry.RefreshedAt().IsZero() {
+				continue
+			}
+			raw, _ = s.Registry.List()
+		} else {
+			var err error
+			if raw, err = s.MiIO.DeviceListContext(ctx, "", false, 0); err != nil {
+				return "", err
+			}
+		}
+		speakers := make([]*device.Device, 0, len(raw))
+		for _, m := range raw {
+			if d := device.FromMap(m); d != nil && d.DID != "" && isSpeaker(d.Model) {
+				speakers = append(speakers, d)
+			}
+		}
+		d, _, err := device.Resolve(speakers, q, aliases)
+		if err == nil {
+			return d.DID, nil
+		}
+		var amb *device.AmbiguousDeviceError
+		if errors.As(err, &amb) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("%w (use 'm mina' to list)", lastErr)
 }
 
 // PlayerStop: OAuth mode uses MIoT. Many speakers have play_control action; siid/aiid vary by model.
 func (s *Service) PlayerStop(deviceID string) (map[string]interface{}, error) {
+	return s.PlayerStopContext(context.Background(), deviceID)
+}
+
+// PlayerStopContext is like PlayerStop but honours ctx cancellation.
+func (s *Service) PlayerStopContext(ctx context.Context, deviceID string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("player_stop: use MIoT action for your speaker (m spec <model>)")
 }
 
 // PlayerSetVolume: not implemented in OAuth mode.
 func (s *Service) PlayerSetVolume(deviceID string, volume int) (map[string]interface{}, error) {
+	return s.PlayerSetVolumeContext(context.Background(), deviceID, volume)
+}
+
+// PlayerSetVolumeContext is like PlayerSetVolume but honours ctx cancellation.
+func (s *Service) PlayerSetVolumeContext(ctx context.Context, deviceID string, volume int) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("player_set_volume: use MIoT prop for your speaker")
 }
 
 // PlayerPause: not implemented in OAuth mode.
 func (s *Service) PlayerPause(deviceID string) (map[string]interface{}, error) {
+	return s.PlayerPauseContext(context.Background(), deviceID)
+}
+
+// PlayerPauseContext is like PlayerPause but honours ctx cancellation.
+func (s *Service) PlayerPauseContext(ctx context.Context, deviceID string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("player_pause: use MIoT action for your speaker")
 }
 
 // PlayerPlay: not implemented in OAuth mode.
 func (s *Service) PlayerPlay(deviceID string) (map[string]interface{}, error) {
+	return s.PlayerPlayContext(context.Background(), deviceID)
+}
+
+// PlayerPlayContext is like PlayerPlay but honours ctx cancellation.
+func (s *Service) PlayerPlayContext(ctx context.Context, deviceID string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("player_play: use MIoT action for your speaker")
 }
 
 // PlayerSetLoop: not implemented in OAuth mode.
 func (s *Service) PlayerSetLoop(deviceID string, loopType int) (map[string]interface{}, error) {
+	return s.PlayerSetLoopContext(context.Background(), deviceID, loopType)
+}
+
+// PlayerSetLoopContext is like PlayerSetLoop but honours ctx cancellation.
+func (s *Service) PlayerSetLoopContext(ctx context.Context, deviceID string, loopType int) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return nil, fmt.Errorf("player_set_loop: use MIoT action for your speaker")
 }
 
 // PlayByURL plays audio. Uses MinaAPI (api2.mina.mi.com) when available.
 // Ref: https://github.com/hanxi/xiaomusic, MiService minaservice.play_by_url
 func (s *Service) PlayByURL(deviceID, url string, _type int) (map[string]interface{}, error) {
+	return s.PlayByURLContext(context.Background(), deviceID, url, _type)
+}
+
+// PlayByURLContext is like PlayByURL but honours ctx cancellation.
+func (s *Service) PlayByURLContext(ctx context.Context, deviceID, url string, _type int) (map[string]interface{}, error) {
 	if s.MinaAPI != nil {
-		return s.MinaAPI.PlayByURL(deviceID, url, _type)
+		return s.MinaAPI.PlayByURLContext(ctx, deviceID, url, _type)
 	}
 	return nil, fmt.Errorf("play_url: MinaAPI not configured (use NewWithMinaAPI with OAuth token)")
 }
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Group 为设备群组：静态成员与动态选择条件的并集。
+type Group struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members,omitempty"` // 静态成员：did 或设备名称
+	Home    string   `json:"home,omitempty"`    // 家庭 ID 或名称
+	Room    string   `json:"room,omitempty"`    // 房间名称或 ID
+	// Model 型号关键字或通配（path.Match），如 plug、*.plug.*
+	Model string `json:"model,omitempty"`
+	// Capability MIoT 服务类型，如 switch、light、speaker
+	Capability string    `json:"capability,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Dynamic reports whether the group has any selector besides static members.
+func (g *Group) Dynamic() bool {
+	return g.Home != "" || g.Room != "" || g.Model != "" || g.Capability != ""
+}
+
+// Groups returns all groups ordered by name.
+func (s *Store) Groups() ([]Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rows, err := s.db.Query(`SELECT group_json FROM device_groups ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Group
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var g Group
+		if err := json.Unmarshal([]byte(data), &g); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// Group returns a group by name, nil if not found.
+func (s *Store) Group(name string) (*Group, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var data string
+	err := s.db.QueryRow(`SELECT group_json FROM device_groups WHERE name = ?`, name).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var g Group
+	if err := json.Unmarshal([]byte(data), &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// SaveGroup creates or replaces a group.
+func (s *Store) SaveGroup(g *Group) error {
+	if g == nil || g.Name == "" {
+		return fmt.Errorf("group name required")
+	}
+	if len(g.Members) == 0 && !g.Dynamic() {
+		return fmt.Errorf("group %s: members or selector required", g.Name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.UpdatedAt = time.Now()
+	data, _ := json.Marshal(g)
+	_, err := s.db.Exec(`
+		INSERT INTO device_groups (name, group_json, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET group_json = excluded.group_json, updated_at = excluded.updated_at
+	`, g.Name, string(data), g.UpdatedAt.Format(time.RFC3339))
+	return err
+}
+
+// DeleteGroup removes a group.
+func (s *Store) DeleteGroup(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(`DELETE FROM device_groups WHERE name = ?`, name)
+	return err
+}
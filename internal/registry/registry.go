@@ -0,0 +1,412 @@
+// Package registry 将云端设备列表持久化到 miflow.db（SQLite），
+// 记录设备新增、移除、改名与型号变化，并提供按 did/名称的本地索引查询。
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+// Change 类型。
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeRenamed = "renamed"
+	ChangeModel   = "model_changed"
+)
+
+// DefaultTTL 设备列表刷新间隔。
+const DefaultTTL = 10 * time.Minute
+
+// metaRefreshed 为 meta 表中上次刷新时间的键。
+const metaRefreshed = "devices_refreshed_at"
+
+// Change 为一条设备变化记录。
+type Change struct {
+	DID  string    `json:"did"`
+	Kind string    `json:"kind"`
+	Old  string    `json:"old,omitempty"`
+	New  string    `json:"new,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+// Store persists the device registry in SQLite.
+type Store struct {
+	mu sync.RWMutex
+	db *sql.DB
+
+	// TTL 超过该时间 Stale 返回 true，<=0 时只按需刷新。
+	TTL time.Duration
+}
+
+// NewStore opens the registry in dataDir/miflow.db.
+func NewStore(dataDir string) (*Store, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	dbPath := filepath.Join(dataDir, "miflow.db")
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db, TTL: DefaultTTL}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS devices (
+			did TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			model TEXT NOT NULL,
+			data_json TEXT,
+			updated_at TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_devices_name ON devices(name);
+		CREATE TABLE IF NOT EXISTS device_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			did TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			old_value TEXT,
+			new_value TEXT,
+			at TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_device_history_did ON device_history(did);
+		CREATE TABLE IF NOT EXISTS meta (
+			key TEXT PRIMARY KEY,
+			value TEXT
+		);
+		CREATE TABLE IF NOT EXISTS aliases (
+			alias TEXT PRIMARY KEY,
+			target TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS device_groups (
+			name TEXT PRIMARY KEY,
+			group_json TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		)
+	`)
+	return err
+}
+
+// RefreshedAt returns the time of the last Sync, zero if never synced.
+func (s *Store) RefreshedAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshedAtLocked()
+}
+
+// Stale reports whether the registry has never been synced or is older than TTL.
+func (s *Store) Stale() bool {
+	at := s.RefreshedAt()
+	if at.IsZero() {
+		return true
+	}
+	return s.TTL > 0 && time.Since(at) > s.TTL
+}
+
+// Sync replaces the registry with the full device list and records the changes.
+// Entries without did are skipped.
+func (s *Store) Sync(devices []map[string]interface{}) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := make(map[string][2]string)
+	rows, err := s.db.Query(`SELECT did, name, model FROM devices`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var did, name, model string
+		if err := rows.Scan(&did, &name, &model); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		old[did] = [2]string{name, model}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// 首次同步不记录新增，避免历史表被整份列表填满
+	first := s.refreshedAtLocked().IsZero()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var changes []Change
+	seen := make(map[string]bool, len(devices))
+	for _, m := range devices {
+		did := str(m["did"])
+		if did == "" || seen[did] {
+			continue
+		}
+		seen[did] = true
+		name, model := str(m["name"]), str(m["model"])
+		if prev, ok := old[did]; !ok {
+			if !first {
+				changes = append(changes, Change{DID: did, Kind: ChangeAdded, New: name, At: now})
+			}
+		} else {
+			if prev[0] != name {
+				changes = append(changes, Change{DID: did, Kind: ChangeRenamed, Old: prev[0], New: name, At: now})
+			}
+			if prev[1] != model {
+				changes = append(changes, Change{DID: did, Kind: ChangeModel, Old: prev[1], New: model, At: now})
+			}
+		}
+		data, _ := json.Marshal(m)
+		if _, err := tx.Exec(`
+			INSERT INTO devices (did, name, model, data_json, updated_at)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(did) DO UPDATE SET
+				name = excluded.name,
+				model = excluded.model,
+				data_json = excluded.data_json,
+				updated_at = excluded.updated_at
+		`, did, name, model, string(data), now.Format(time.RFC3339)); err != nil {
+			return nil, err
+		}
+	}
+	removed := make([]string, 0)
+	for did := range old {
+		if !seen[did] {
+			removed = append(removed, did)
+		}
+	}
+	sort.Strings(removed)
+	for _, did := range removed {
+		if _, err := tx.Exec(`DELETE FROM devices WHERE did = ?`, did); err != nil {
+			return nil, err
+		}
+		changes = append(changes, Change{DID: did, Kind: ChangeRemoved, Old: old[did][0], At: now})
+	}
+	for _, c := range changes {
+		if _, err := tx.Exec(`INSERT INTO device_history (did, kind, old_value, new_value, at) VALUES (?, ?, ?, ?, ?)`,
+			c.DID, c.Kind, c.Old, c.New, c.At.Format(time.RFC3339Nano)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		metaRefreshed, now.Format(time.RFC3339Nano)); err != nil {
+		return nil, err
+	}
+	return changes, tx.Commit()
+}
+
+func (s *Store) refreshedAtLocked() time.Time {
+	var v string
+	if err := s.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, metaRefreshed).Scan(&v); err != nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, v)
+	return t
+}
+
+// Get returns the device map for did, nil if not registered.
+func (s *Store) Get(did string) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var data string
+	err := s.db.QueryRow(`SELECT data_json FROM devices WHERE did = ?`, did).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data), nil
+}
+
+// FindByName returns devices whose name equals name; when none, devices whose
+// name contains it. Results are ordered by name.
+func (s *Store) FindByName(name string) ([]map[string]interface{}, error) {
+	if name == "" {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out, err := s.query(`SELECT data_json FROM devices WHERE name = ? ORDER BY name, did`, name)
+	if err != nil || len(out) > 0 {
+		return out, err
+	}
+	return s.query(`SELECT data_json FROM devices WHERE instr(name, ?) > 0 ORDER BY name, did`, name)
+}
+
+// List returns all registered devices ordered by name.
+func (s *Store) List() ([]map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.query(`SELECT data_json FROM devices ORDER BY name, did`)
+}
+
+// History returns recorded changes, newest first. did may be empty for all devices;
+// limit <= 0 means no limit.
+func (s *Store) History(did string, limit int) ([]Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q := `SELECT did, kind, old_value, new_value, at FROM device_history`
+	var args []interface{}
+	if did != "" {
+		q += ` WHERE did = ?`
+		args = append(args, did)
+	}
+	q += ` ORDER BY id DESC`
+	if limit > 0 {
+		q += fmt.Sprintf(` LIMIT %d`, limit)
+	}
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Change
+	for rows.Next() {
+		var c Change
+		var oldV, newV sql.NullString
+		var at string
+		if err := rows.Scan(&c.DID, &c.Kind, &oldV, &newV, &at); err != nil {
+			return nil, err
+		}
+		c.Old, c.New = oldV.String, newV.String
+		c.At, _ = time.Parse(time.RFC3339Nano, at)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Aliases returns user-defined aliases (alias → did).
+func (s *Store) Aliases() (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rows, err := s.db.Query(`SELECT alias, target FROM aliases`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]string)
+	for rows.Next() {
+		var alias, target string
+		if err := rows.Scan(&alias, &target); err != nil {
+			return nil, err
+		}
+		out[alias] = target
+	}
+	return out, rows.Err()
+}
+
+// SetAlias creates or replaces an alias.
+func (s *Store) SetAlias(alias, target string) error {
+	if alias == "" || target == "" {
+		return fmt.Errorf("alias and target required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(`INSERT INTO aliases (alias, target) VALUES (?, ?) ON CONFLICT(alias) DO UPDATE SET target = excluded.target`, alias, target)
+	return err
+}
+
+// DeleteAlias removes an alias.
+func (s *Store) DeleteAlias(alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.db.Exec(`DELETE FROM aliases WHERE alias = ?`, alias)
+	return err
+}
+
+func (s *Store) query(q string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []map[string]interface{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		out = append(out, decode(data))
+	}
+	return out, rows.Err()
+}
+
+func decode(data string) map[string]interface{} {
+	m := make(map[string]interface{})
+	_ = json.Unmarshal([]byte(data), &m)
+	return m
+}
+
+func str(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore *Store
+)
+
+// DefaultDir 返回 miio.registry_dir，留空时为用户配置目录下 miflow，CLI 与 web 在任意工作目录下共用同一个库。
+func DefaultDir() (string, error) {
+	if dir := config.Get().MiIO.RegistryDir; dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "miflow"), nil
+}
+
+// Default 按配置（miio.registry_dir、miio.registry_ttl_minutes）打开进程共享的注册表；
+// 关闭或打开失败时返回 nil，调用方退回云端设备列表。
+func Default() *Store {
+	defaultOnce.Do(func() {
+		cfg := config.Get()
+		if cfg.MiIO.RegistryTTLMinutes < 0 {
+			return
+		}
+		dataDir, err := DefaultDir()
+		if err != nil {
+			return
+		}
+		s, err := NewStore(dataDir)
+		if err != nil {
+			return
+		}
+		if cfg.MiIO.RegistryTTLMinutes > 0 {
+			s.TTL = time.Duration(cfg.MiIO.RegistryTTLMinutes) * time.Minute
+		}
+		defaultStore = s
+	})
+	return defaultStore
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func dev(did, name, model string) map[string]interface{} {
+	return map[string]interface{}{"did": did, "name": name, "model": model, "token": "t" + did}
+}
+
+func TestSyncHistoryAndLookup(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var mode string
+	if err := s.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+	if !s.Stale() {
+		t.Error("new registry should be stale")
+	}
+
+	changes, err := s.Sync([]map[string]interface{}{
+		dev("1", "客厅灯", "yeelink.light.ceiling1"),
+		dev("2", "台灯", "yeelink.light.lamp1"),
+		dev("3", "净化器", "zhimi.airpurifier.mb3"),
+	})
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("first Sync = %v, %v; want no changes", changes, err)
+	}
+	if s.Stale() {
+		t.Error("registry should be fresh after Sync")
+	}
+
+	changes, err = s.Sync([]map[string]interface{}{
+		dev("1", "吸顶灯", "yeelink.light.ceiling1"),
+		dev("2", "台灯", "yeelink.light.lamp22"),
+		dev("4", "插座", "cuco.plug.v3"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{}
+	for _, c := range changes {
+		kinds[c.DID+"/"+c.Kind] = c.Old + "->" + c.New
+	}
+	want := map[string]string{
+		"1/" + ChangeRenamed: "客厅灯->吸顶灯",
+		"2/" + ChangeModel:   "yeelink.light.lamp1->yeelink.light.lamp22",
+		"4/" + ChangeAdded:   "->插座",
+		"3/" + ChangeRemoved: "净化器->",
+	}
+	if len(kinds) != len(want) {
+		t.Errorf("changes = %v", kinds)
+	}
+	for k, v := range want {
+		if kinds[k] != v {
+			t.Errorf("change %s = %q, want %q", k, kinds[k], v)
+		}
+	}
+
+	if m, _ := s.Get("3"); m != nil {
+		t.Errorf("removed device still registered: %v", m)
+	}
+	if m, _ := s.Get("4"); m == nil || m["token"] != "t4" {
+		t.Errorf("Get(4) = %v", m)
+	}
+	if got, _ := s.FindByName("台灯"); len(got) != 1 || got[0]["did"] != "2" {
+		t.Errorf("FindByName(台灯) = %v", got)
+	}
+	if got, _ := s.FindByName("灯"); len(got) != 2 {
+		t.Errorf("FindByName(灯) = %v, want 2 partial matches", got)
+	}
+
+	hist, err := s.History("1", 0)
+	if err != nil || len(hist) != 1 || hist[0].Kind != ChangeRenamed {
+		t.Errorf("History(1) = %v, %v", hist, err)
+	}
+	if all, _ := s.History("", 2); len(all) != 2 {
+		t.Errorf("History limit = %d entries", len(all))
+	}
+
+	s.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if !s.Stale() {
+		t.Error("registry should be stale after TTL")
+	}
+}
@@ -0,0 +1,148 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyChanged 为属性变化推送。
+type PropertyChanged struct {
+	DID   string      `json:"did"`
+	SIID  int         `json:"siid"`
+	PIID  int         `json:"piid"`
+	Value interface{} `json:"value"`
+	Time  time.Time   `json:"time"`
+}
+
+// EventArgument 为事件参数（piid 与值）。
+type EventArgument struct {
+	PIID  int         `json:"piid"`
+	Value interface{} `json:"value"`
+}
+
+// EventOccurred 为事件推送。
+type EventOccurred struct {
+	DID       string          `json:"did"`
+	SIID      int             `json:"siid"`
+	EIID      int             `json:"eiid"`
+	Arguments []EventArgument `json:"arguments"`
+	Time      time.Time       `json:"time"`
+}
+
+// DeviceOnlineChanged 为设备上下线推送。
+type DeviceOnlineChanged struct {
+	DID    string    `json:"did"`
+	Online bool      `json:"online"`
+	Time   time.Time `json:"time"`
+}
+
+// PropertyTopic 返回属性变化 topic，siid 或 piid 为 0 时为该设备全部属性。
+func PropertyTopic(did string, siid, piid int) string {
+	return iidTopic(did, "properties_changed", siid, piid)
+}
+
+// EventTopic 返回事件 topic，siid 或 eiid 为 0 时为该设备全部事件。
+func EventTopic(did string, siid, eiid int) string {
+	return iidTopic(did, "event_occured", siid, eiid)
+}
+
+// StateTopic 返回设备上下线 topic。
+func StateTopic(did string) string {
+	return "device/" + did + "/state/#"
+}
+
+func iidTopic(did, kind string, siid, iid int) string {
+	if siid == 0 || iid == 0 {
+		return fmt.Sprintf("device/%s/up/%s/#", did, kind)
+	}
+	return fmt.Sprintf("device/%s/up/%s/%d/%d", did, kind, siid, iid)
+}
+
+// topicIDs 从 device/{did}/up/{kind}/{siid}/{iid} 中取出 did、siid、iid。
+func topicIDs(topic string) (did string, siid, iid int) {
+	parts := strings.Split(topic, "/")
+	if len(parts) > 1 {
+		did = parts[1]
+	}
+	if len(parts) >= 6 {
+		siid, _ = strconv.Atoi(parts[4])
+		iid, _ = strconv.Atoi(parts[5])
+	}
+	return did, siid, iid
+}
+
+// parsePropertyChanged 解析 {"params":{"did","siid","piid","value"}}，缺失字段取自 topic。
+func parsePropertyChanged(topic string, payload []byte) (PropertyChanged, error) {
+	var msg struct {
+		Params *struct {
+			DID   string      `json:"did"`
+			SIID  int         `json:"siid"`
+			PIID  int         `json:"piid"`
+			Value interface{} `json:"value"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return PropertyChanged{}, err
+	}
+	if msg.Params == nil {
+		return PropertyChanged{}, fmt.Errorf("subscribe: no params in %s", topic)
+	}
+	did, siid, piid := topicIDs(topic)
+	m := PropertyChanged{DID: msg.Params.DID, SIID: msg.Params.SIID, PIID: msg.Params.PIID, Value: msg.Params.Value, Time: time.Now()}
+	if m.DID == "" {
+		m.DID = did
+	}
+	if m.SIID == 0 {
+		m.SIID, m.PIID = siid, piid
+	}
+	return m, nil
+}
+
+// parseEventOccurred 解析 {"params":{"did","siid","eiid","arguments":[{"piid","value"}]}}。
+func parseEventOccurred(topic string, payload []byte) (EventOccurred, error) {
+	var msg struct {
+		Params *struct {
+			DID       string          `json:"did"`
+			SIID      int             `json:"siid"`
+			EIID      int             `json:"eiid"`
+			Arguments []EventArgument `json:"arguments"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return EventOccurred{}, err
+	}
+	if msg.Params == nil {
+		return EventOccurred{}, fmt.Errorf("subscribe: no params in %s", topic)
+	}
+	did, siid, eiid := topicIDs(topic)
+	m := EventOccurred{DID: msg.Params.DID, SIID: msg.Params.SIID, EIID: msg.Params.EIID, Arguments: msg.Params.Arguments, Time: time.Now()}
+	if m.DID == "" {
+		m.DID = did
+	}
+	if m.SIID == 0 {
+		m.SIID, m.EIID = siid, eiid
+	}
+	return m, nil
+}
+
+// parseOnlineChanged 解析 {"device_id","event":"online|offline"}。
+func parseOnlineChanged(topic string, payload []byte) (DeviceOnlineChanged, error) {
+	var msg struct {
+		DeviceID string `json:"device_id"`
+		Event    string `json:"event"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return DeviceOnlineChanged{}, err
+	}
+	if msg.Event != "online" && msg.Event != "offline" {
+		return DeviceOnlineChanged{}, fmt.Errorf("subscribe: unknown state event %q", msg.Event)
+	}
+	did := msg.DeviceID
+	if did == "" {
+		did, _, _ = topicIDs(topic)
+	}
+	return DeviceOnlineChanged{DID: did, Online: msg.Event == "online", Time: time.Now()}, nil
+}
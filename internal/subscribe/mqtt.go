@@ -0,0 +1,215 @@
+package subscribe
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MQTT 3.1.1 控制报文类型（只实现订阅端需要的部分）。
+const (
+	pktConnect     = 1
+	pktConnack     = 2
+	pktPublish     = 3
+	pktPuback      = 4
+	pktSubscribe   = 8
+	pktSuback      = 9
+	pktUnsubscribe = 10
+	pktUnsuback    = 11
+	pktPingreq     = 12
+	pktPingresp    = 13
+	pktDisconnect  = 14
+)
+
+// maxPacketLen 单个报文最大长度（MQTT 剩余长度上限）。
+const maxPacketLen = 268435455
+
+// packet 为解码后的 MQTT 报文：固定头的类型与标志，以及剩余部分。
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// ConnackError 为 CONNACK 返回的非 0 码。
+type ConnackError struct {
+	Code byte
+}
+
+func (e *ConnackError) Error() string {
+	msg := map[byte]string{
+		1: "unacceptable protocol version",
+		2: "identifier rejected",
+		3: "server unavailable",
+		4: "bad user name or password",
+		5: "not authorized",
+	}[e.Code]
+	if msg == "" {
+		msg = "unknown"
+	}
+	return fmt.Sprintf("mqtt: connection refused (%d %s)", e.Code, msg)
+}
+
+func readPacket(r *bufio.Reader) (*packet, error) {
+	h, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return nil, errors.New("mqtt: malformed remaining length")
+		}
+		mult *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &packet{typ: h >> 4, flags: h & 0x0f, body: body}, nil
+}
+
+// encode 组装固定头与剩余部分。
+func (p *packet) encode() ([]byte, error) {
+	n := len(p.body)
+	if n > maxPacketLen {
+		return nil, errors.New("mqtt: packet too large")
+	}
+	b := []byte{p.typ<<4 | p.flags}
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, p.body...), nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("mqtt: short string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("mqtt: short string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// connectPacket 构造 CONNECT：clean session、用户名与密码，keepAlive 单位为秒。
+func connectPacket(clientID, username, password string, keepAlive uint16) *packet {
+	var b []byte
+	b = appendString(b, "MQTT")
+	b = append(b, 4) // 3.1.1
+	flags := byte(0x02)
+	if username != "" {
+		flags |= 0x80
+	}
+	if password != "" {
+		flags |= 0x40
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint16(b, keepAlive)
+	b = appendString(b, clientID)
+	if username != "" {
+		b = appendString(b, username)
+	}
+	if password != "" {
+		b = appendString(b, password)
+	}
+	return &packet{typ: pktConnect, body: b}
+}
+
+// subscribePacket 以 QoS 1 订阅 topics。
+func subscribePacket(id uint16, topics ...string) *packet {
+	b := binary.BigEndian.AppendUint16(nil, id)
+	for _, t := range topics {
+		b = appendString(b, t)
+		b = append(b, 1)
+	}
+	return &packet{typ: pktSubscribe, flags: 0x02, body: b}
+}
+
+func unsubscribePacket(id uint16, topics ...string) *packet {
+	b := binary.BigEndian.AppendUint16(nil, id)
+	for _, t := range topics {
+		b = appendString(b, t)
+	}
+	return &packet{typ: pktUnsubscribe, flags: 0x02, body: b}
+}
+
+func pubackPacket(id uint16) *packet {
+	return &packet{typ: pktPuback, body: binary.BigEndian.AppendUint16(nil, id)}
+}
+
+// publish 为收到的 PUBLISH 报文。
+type publish struct {
+	topic   string
+	id      uint16 // QoS > 0 时的报文 ID
+	qos     byte
+	payload []byte
+}
+
+func parsePublish(p *packet) (*publish, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return nil, err
+	}
+	pub := &publish{topic: topic, qos: (p.flags >> 1) & 0x03}
+	if pub.qos > 0 {
+		if len(rest) < 2 {
+			return nil, errors.New("mqtt: short publish")
+		}
+		pub.id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	pub.payload = rest
+	return pub, nil
+}
+
+// publishPacket 构造 PUBLISH（用于测试中的 broker 替身）。
+func publishPacket(topic string, qos byte, id uint16, payload []byte) *packet {
+	b := appendString(nil, topic)
+	if qos > 0 {
+		b = binary.BigEndian.AppendUint16(b, id)
+	}
+	return &packet{typ: pktPublish, flags: qos << 1, body: append(b, payload...)}
+}
+
+// topicMatch 按 MQTT 通配符（+ 单层，# 多层）匹配 topic。
+func topicMatch(filter, topic string) bool {
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
@@ -0,0 +1,386 @@
+// Package subscribe 通过小米云端推送 broker（MIPS，{cloud_server}-ha.mqtt.io.mi.com:8883，MQTT 3.1.1 over TLS）
+// 订阅设备属性变化、事件与在线状态，使用 OAuth access token 登录，结果以类型化消息投递到 channel。
+// Ref: https://github.com/XiaoMi/ha_xiaomi_home/blob/main/custom_components/xiaomi_home/miot/miot_mips.py
+package subscribe
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+// 默认参数。
+const (
+	DefaultMQTTHost       = "ha.mqtt.io.mi.com"
+	DefaultPort           = 8883
+	DefaultKeepAlive      = 60 * time.Second
+	DefaultReconnectDelay = time.Second
+	maxReconnectDelay     = time.Minute
+	defaultBuffer         = 64
+)
+
+// Subscriber 维护到推送 broker 的连接：断线后按指数退避重连并重新订阅。
+// 订阅可在 Run 之前或运行中添加，订阅的 ctx 结束时取消订阅并关闭对应 channel。
+type Subscriber struct {
+	Addr     string      // broker 地址 host:port
+	TLS      *tls.Config // nil 时使用明文 TCP（测试用）
+	ClientID string
+	Username string
+	// Password 每次连接前调用以获取 access token（可在其中刷新）
+	Password       func(ctx context.Context) (string, error)
+	KeepAlive      time.Duration
+	ReconnectDelay time.Duration
+	Buffer         int // 每个订阅 channel 的缓冲，满时丢弃新消息
+
+	mu     sync.Mutex
+	subs   map[int]*subscription
+	refs   map[string]int // topic filter → 订阅数
+	nextID int
+	conn   net.Conn
+
+	writeMu sync.Mutex
+	pktID   uint16
+	dropped atomic.Uint64
+}
+
+type subscription struct {
+	filter  string
+	deliver func(topic string, payload []byte)
+	close   func()
+}
+
+// New 创建 Subscriber，password 提供登录密码（access token）。
+func New(addr, clientID, username string, password func(ctx context.Context) (string, error)) *Subscriber {
+	return &Subscriber{
+		Addr:           addr,
+		ClientID:       clientID,
+		Username:       username,
+		Password:       password,
+		KeepAlive:      DefaultKeepAlive,
+		ReconnectDelay: DefaultReconnectDelay,
+		Buffer:         defaultBuffer,
+	}
+}
+
+// NewFromHAClient 按 OAuth 配置创建连接云端 broker 的 Subscriber：
+// 用户名为 OAuth client id，密码为 access token，地址为 {cloud_server}-{oauth.mqtt_host}:8883。
+func NewFromHAClient(c *miaccount.HAClient) *Subscriber {
+	cfg := config.Get().OAuth
+	cloud := cfg.CloudServer
+	if c.OAuthToken != nil && c.OAuthToken.CloudServer != "" {
+		cloud = c.OAuthToken.CloudServer
+	}
+	if cloud == "" {
+		cloud = "cn"
+	}
+	mqttHost := cfg.MQTTHost
+	if mqttHost == "" {
+		mqttHost = DefaultMQTTHost
+	}
+	host := cloud + "-" + mqttHost
+	s := New(net.JoinHostPort(host, fmt.Sprint(DefaultPort)), "miflow."+randomID(), c.ClientID, c.ValidToken)
+	s.TLS = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	return s
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Dropped 返回因 channel 已满而丢弃的消息数。
+func (s *Subscriber) Dropped() uint64 { return s.dropped.Load() }
+
+func (s *Subscriber) buffer() int {
+	if s.Buffer > 0 {
+		return s.Buffer
+	}
+	return defaultBuffer
+}
+
+// Properties 订阅属性变化，siid 或 piid 为 0 时订阅该设备全部属性。
+func (s *Subscriber) Properties(ctx context.Context, did string, siid, piid int) <-chan PropertyChanged {
+	ch := make(chan PropertyChanged, s.buffer())
+	s.add(ctx, PropertyTopic(did, siid, piid), func(topic string, payload []byte) {
+		if m, err := parsePropertyChanged(topic, payload); err == nil {
+			select {
+			case ch <- m:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}, func() { close(ch) })
+	return ch
+}
+
+// Events 订阅事件，siid 或 eiid 为 0 时订阅该设备全部事件。
+func (s *Subscriber) Events(ctx context.Context, did string, siid, eiid int) <-chan EventOccurred {
+	ch := make(chan EventOccurred, s.buffer())
+	s.add(ctx, EventTopic(did, siid, eiid), func(topic string, payload []byte) {
+		if m, err := parseEventOccurred(topic, payload); err == nil {
+			select {
+			case ch <- m:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}, func() { close(ch) })
+	return ch
+}
+
+// Online 订阅设备上下线。
+func (s *Subscriber) Online(ctx context.Context, did string) <-chan DeviceOnlineChanged {
+	ch := make(chan DeviceOnlineChanged, s.buffer())
+	s.add(ctx, StateTopic(did), func(topic string, payload []byte) {
+		if m, err := parseOnlineChanged(topic, payload); err == nil {
+			select {
+			case ch <- m:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+	}, func() { close(ch) })
+	return ch
+}
+
+// add 登记订阅；该 filter 首次出现且已连接时立即发送 SUBSCRIBE。
+func (s *Subscriber) add(ctx context.Context, filter string, deliver func(string, []byte), closeFn func()) {
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[int]*subscription)
+		s.refs = make(map[string]int)
+	}
+	s.nextID++
+	id := s.nextID
+	s.subs[id] = &subscription{filter: filter, deliver: deliver, close: closeFn}
+	s.refs[filter]++
+	// 与 session 在同一把锁下读取 conn：未连接时由 session 连接后统一订阅，避免重复 SUBSCRIBE
+	conn := s.conn
+	first := s.refs[filter] == 1
+	s.mu.Unlock()
+	if first && conn != nil {
+		_ = writePacket(conn, &s.writeMu, subscribePacket(s.nextPacketID(), filter))
+	}
+	context.AfterFunc(ctx, func() { s.remove(id) })
+}
+
+// remove 取消订阅并关闭 channel；该 filter 无其他订阅时发送 UNSUBSCRIBE。
+func (s *Subscriber) remove(id int) {
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.subs, id)
+	sub.close()
+	s.refs[sub.filter]--
+	last := s.refs[sub.filter] == 0
+	if last {
+		delete(s.refs, sub.filter)
+	}
+	conn := s.conn
+	s.mu.Unlock()
+	if last && conn != nil {
+		_ = writePacket(conn, &s.writeMu, unsubscribePacket(s.nextPacketID(), sub.filter))
+	}
+}
+
+// closeAll 关闭全部订阅 channel（Run 返回时调用）。
+func (s *Subscriber) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sub := range s.subs {
+		sub.close()
+		delete(s.subs, id)
+	}
+	s.refs = make(map[string]int)
+}
+
+func (s *Subscriber) dispatch(topic string, payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		if topicMatch(sub.filter, topic) {
+			sub.deliver(topic, payload)
+		}
+	}
+}
+
+func (s *Subscriber) nextPacketID() uint16 {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.pktID++
+	if s.pktID == 0 {
+		s.pktID = 1
+	}
+	return s.pktID
+}
+
+func writePacket(conn net.Conn, mu *sync.Mutex, p *packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	_, err = conn.Write(b)
+	return err
+}
+
+// Run 连接 broker 并处理推送，直到 ctx 结束或登录被拒绝；断线按指数退避重连。
+// 返回时关闭所有订阅 channel。登录被拒（用户名或密码错误、未授权）时返回的错误包装 miaccount.ErrTokenRevoked。
+func (s *Subscriber) Run(ctx context.Context) error {
+	defer s.closeAll()
+	base := s.ReconnectDelay
+	if base <= 0 {
+		base = DefaultReconnectDelay
+	}
+	delay := base
+	for {
+		connected, err := s.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var ce *ConnackError
+		if errors.As(err, &ce) && (ce.Code == 4 || ce.Code == 5) {
+			return fmt.Errorf("%w: %v", miaccount.ErrTokenRevoked, err)
+		}
+		if errors.Is(err, miaccount.ErrTokenRevoked) {
+			return err
+		}
+		if connected {
+			delay = base
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// session 完成一次连接：CONNECT、重新订阅、保活与读循环。connected 表示是否曾连接成功。
+func (s *Subscriber) session(ctx context.Context) (connected bool, err error) {
+	password := ""
+	if s.Password != nil {
+		if password, err = s.Password(ctx); err != nil {
+			return false, err
+		}
+	}
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		_ = writePacket(conn, &s.writeMu, &packet{typ: pktDisconnect})
+		_ = conn.Close()
+	})
+	defer stop()
+
+	keepAlive := s.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	r := bufio.NewReader(conn)
+	if err := writePacket(conn, &s.writeMu, connectPacket(s.ClientID, s.Username, password, uint16(keepAlive/time.Second))); err != nil {
+		return false, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(keepAlive))
+	ack, err := readPacket(r)
+	if err != nil {
+		return false, err
+	}
+	if ack.typ != pktConnack || len(ack.body) < 2 {
+		return false, errors.New("mqtt: expected CONNACK")
+	}
+	if ack.body[1] != 0 {
+		return false, &ConnackError{Code: ack.body[1]}
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	filters := make([]string, 0, len(s.refs))
+	for f := range s.refs {
+		filters = append(filters, f)
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.conn == conn {
+			s.conn = nil
+		}
+		s.mu.Unlock()
+	}()
+	if len(filters) > 0 {
+		if err := writePacket(conn, &s.writeMu, subscribePacket(s.nextPacketID(), filters...)); err != nil {
+			return true, err
+		}
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(keepAlive / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if writePacket(conn, &s.writeMu, &packet{typ: pktPingreq}) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		// 保活周期内至少会收到 PINGRESP
+		_ = conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		p, err := readPacket(r)
+		if err != nil {
+			return true, err
+		}
+		switch p.typ {
+		case pktPublish:
+			pub, err := parsePublish(p)
+			if err != nil {
+				return true, err
+			}
+			if pub.qos > 0 {
+				if err := writePacket(conn, &s.writeMu, pubackPacket(pub.id)); err != nil {
+					return true, err
+				}
+			}
+			s.dispatch(pub.topic, pub.payload)
+		case pktSuback, pktUnsuback, pktPingresp:
+		default:
+			return true, fmt.Errorf("mqtt: unexpected packet type %d", p.typ)
+		}
+	}
+}
+
+func (s *Subscriber) dial(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	if s.TLS != nil {
+		return (&tls.Dialer{NetDialer: d, Config: s.TLS}).DialContext(ctx, "tcp", s.Addr)
+	}
+	return d.DialContext(ctx, "tcp", s.Addr)
+}
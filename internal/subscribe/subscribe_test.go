@@ -0,0 +1,296 @@
+package subscribe
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/miaccount"
+)
+
+// broker 为本地 MQTT broker 替身：校验密码，记录订阅，可向当前连接推送消息。
+type broker struct {
+	t        *testing.T
+	ln       net.Listener
+	password string
+
+	mu     sync.Mutex
+	conns  []net.Conn
+	subs   []string
+	unsubs []string
+	acks   int
+	subbed chan string
+}
+
+func newBroker(t *testing.T, password string) *broker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{t: t, ln: ln, password: password, subbed: make(chan string, 16)}
+	t.Cleanup(func() {
+		ln.Close()
+		b.mu.Lock()
+		for _, c := range b.conns {
+			c.Close()
+		}
+		b.mu.Unlock()
+	})
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, c)
+			b.mu.Unlock()
+			go b.serve(c)
+		}
+	}()
+	return b
+}
+
+func (b *broker) send(c net.Conn, p *packet) {
+	raw, _ := p.encode()
+	_, _ = c.Write(raw)
+}
+
+func (b *broker) serve(c net.Conn) {
+	r := bufio.NewReader(c)
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.typ {
+		case pktConnect:
+			// 跳过协议名、级别、标志、保活与 client id、用户名，取密码
+			_, rest, _ := readString(p.body)
+			rest = rest[4:]
+			_, rest, _ = readString(rest)
+			_, rest, _ = readString(rest)
+			pw, _, _ := readString(rest)
+			code := byte(0)
+			if pw != b.password {
+				code = 4
+			}
+			b.send(c, &packet{typ: pktConnack, body: []byte{0, code}})
+		case pktSubscribe:
+			id := binary.BigEndian.Uint16(p.body)
+			rest := p.body[2:]
+			var codes []byte
+			for len(rest) > 0 {
+				var topic string
+				topic, rest, _ = readString(rest)
+				rest = rest[1:]
+				codes = append(codes, 1)
+				b.mu.Lock()
+				b.subs = append(b.subs, topic)
+				b.mu.Unlock()
+				b.subbed <- topic
+			}
+			b.send(c, &packet{typ: pktSuback, body: append(binary.BigEndian.AppendUint16(nil, id), codes...)})
+		case pktUnsubscribe:
+			id := binary.BigEndian.Uint16(p.body)
+			topic, _, _ := readString(p.body[2:])
+			b.mu.Lock()
+			b.unsubs = append(b.unsubs, topic)
+			b.mu.Unlock()
+			b.send(c, &packet{typ: pktUnsuback, body: binary.BigEndian.AppendUint16(nil, id)})
+		case pktPuback:
+			b.mu.Lock()
+			b.acks++
+			b.mu.Unlock()
+		case pktPingreq:
+			b.send(c, &packet{typ: pktPingresp})
+		case pktDisconnect:
+			return
+		}
+	}
+}
+
+// publish 向最新连接推送消息。
+func (b *broker) publish(topic string, qos byte, payload string) {
+	b.mu.Lock()
+	c := b.conns[len(b.conns)-1]
+	b.mu.Unlock()
+	b.send(c, publishPacket(topic, qos, 7, []byte(payload)))
+}
+
+// drop 断开当前所有连接，模拟网络中断。
+func (b *broker) drop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.Close()
+	}
+}
+
+func (b *broker) waitSub(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-b.subbed:
+		if got != want {
+			t.Fatalf("subscribed %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout waiting for SUBSCRIBE %s", want)
+	}
+}
+
+func newTestSubscriber(b *broker) *Subscriber {
+	s := New(b.ln.Addr().String(), "test", "2882303761520251711", func(context.Context) (string, error) { return "token", nil })
+	s.ReconnectDelay = 10 * time.Millisecond
+	return s
+}
+
+func TestSubscribeDelivers(t *testing.T) {
+	b := newBroker(t, "token")
+	s := newTestSubscriber(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	props := s.Properties(ctx, "123", 2, 1)
+	events := s.Events(ctx, "123", 0, 0)
+	online := s.Online(ctx, "123")
+	go s.Run(ctx)
+	// 连接后一次性订阅 Run 之前登记的全部 topic
+	want := map[string]bool{"device/123/up/properties_changed/2/1": true, "device/123/up/event_occured/#": true, "device/123/state/#": true}
+	for range want {
+		select {
+		case got := <-b.subbed:
+			if !want[got] {
+				t.Errorf("unexpected SUBSCRIBE %s", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for SUBSCRIBE")
+		}
+	}
+
+	b.publish("device/123/up/properties_changed/2/1", 1, `{"params":{"did":"123","siid":2,"piid":1,"value":true}}`)
+	b.publish("device/123/up/properties_changed/3/1", 0, `{"params":{"did":"123","siid":3,"piid":1,"value":1}}`)
+	b.publish("device/123/up/event_occured/5/1", 0, `{"params":{"did":"123","siid":5,"eiid":1,"arguments":[{"piid":1,"value":"hi"}]}}`)
+	b.publish("device/123/state/online", 0, `{"device_id":"123","event":"offline"}`)
+
+	select {
+	case m := <-props:
+		if m.DID != "123" || m.SIID != 2 || m.PIID != 1 || m.Value != true {
+			t.Errorf("PropertyChanged = %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no PropertyChanged")
+	}
+	select {
+	case m := <-events:
+		if m.SIID != 5 || m.EIID != 1 || len(m.Arguments) != 1 || m.Arguments[0].Value != "hi" {
+			t.Errorf("EventOccurred = %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no EventOccurred")
+	}
+	select {
+	case m := <-online:
+		if m.DID != "123" || m.Online {
+			t.Errorf("DeviceOnlineChanged = %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no DeviceOnlineChanged")
+	}
+	// 3-1 不在订阅范围内
+	select {
+	case m := <-props:
+		t.Errorf("unexpected %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+	b.mu.Lock()
+	if b.acks != 1 {
+		t.Errorf("PUBACK count = %d, want 1", b.acks)
+	}
+	b.mu.Unlock()
+}
+
+func TestSubscribeReconnectResubscribes(t *testing.T) {
+	b := newBroker(t, "token")
+	s := newTestSubscriber(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// 运行中添加订阅
+	subCtx, unsub := context.WithCancel(ctx)
+	props := s.Properties(subCtx, "9", 0, 0)
+	b.waitSub(t, "device/9/up/properties_changed/#")
+
+	b.drop()
+	b.waitSub(t, "device/9/up/properties_changed/#")
+	b.publish("device/9/up/properties_changed/2/2", 0, `{"params":{"value":42}}`)
+	select {
+	case m := <-props:
+		if m.DID != "9" || m.SIID != 2 || m.PIID != 2 || m.Value != float64(42) {
+			t.Errorf("PropertyChanged = %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message after reconnect")
+	}
+
+	unsub()
+	select {
+	case _, ok := <-props:
+		if ok {
+			t.Error("channel should be closed after unsubscribe")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel not closed")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		n := len(b.unsubs)
+		b.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("UNSUBSCRIBE not sent")
+}
+
+func TestSubscribeBadToken(t *testing.T) {
+	b := newBroker(t, "other")
+	s := newTestSubscriber(b)
+	ch := s.Online(context.Background(), "1")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := s.Run(ctx)
+	if !errors.Is(err, miaccount.ErrTokenRevoked) {
+		t.Fatalf("Run = %v, want ErrTokenRevoked", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("channels should be closed when Run returns")
+	}
+}
+
+func TestTopicMatch(t *testing.T) {
+	cases := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"device/1/up/properties_changed/#", "device/1/up/properties_changed/2/1", true},
+		{"device/1/up/properties_changed/2/1", "device/1/up/properties_changed/2/1", true},
+		{"device/1/up/properties_changed/2/1", "device/1/up/properties_changed/2/2", false},
+		{"device/+/state/#", "device/7/state/online", true},
+		{"device/1/state/#", "device/2/state/online", false},
+		{"a/b", "a/b/c", false},
+	}
+	for _, c := range cases {
+		if got := topicMatch(c.filter, c.topic); got != c.want {
+			t.Errorf("topicMatch(%q, %q) = %v", c.filter, c.topic, got)
+		}
+	}
+}
@@ -21,16 +21,20 @@ const (
 	StepTypePlayURL StepType = "play_url"
 	StepTypeMiIO    StepType = "miio"
 	StepTypeDelay   StepType = "delay"
+	StepTypeScene   StepType = "scene"
 )
 
 // Step describes one action in a workflow.
 type Step struct {
 	Type       StepType `json:"type"`
 	Label      string   `json:"label,omitempty"`
-	Device     string   `json:"device,omitempty"`
+	Device     string   `json:"device,omitempty"` // did、名称或 @群组名
+	Room       string   `json:"room,omitempty"`   // miio 步骤：对该房间内设备逐个执行
+	Model      string   `json:"model,omitempty"`  // miio 步骤：按型号关键字筛选，如 light
 	Text       string   `json:"text,omitempty"`
 	URL        string   `json:"url,omitempty"`
 	MiIOText   string   `json:"miio_text,omitempty"`
+	Scene      string   `json:"scene,omitempty"` // scene 步骤：场景 ID 或名称
 	DurationMS int      `json:"duration_ms,omitempty"`
 }
 
@@ -56,7 +60,7 @@ func NewStore(dataDir string) (*Store, error) {
 		return nil, err
 	}
 	dbPath := filepath.Join(dataDir, "miflow.db")
-	db, err := sql.Open("sqlite", dbPath+"?_journal_mode=WAL")
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,544 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zeusro/miflow/internal/device"
+)
+
+// Capability 为设备的一项能力，具体能力见 Switchable、Dimmable 等接口。
+type Capability interface {
+	// Name 返回能力名称，如 switchable、dimmable，供 web 等列出。
+	Name() string
+}
+
+// Switchable 开关、插座、灯的开关。
+type Switchable interface {
+	Capability
+	SetOn(ctx context.Context, on bool) error
+	On(ctx context.Context) (bool, error)
+	Toggle(ctx context.Context) error
+}
+
+// Dimmable 亮度 0-100。
+type Dimmable interface {
+	Capability
+	SetBrightness(ctx context.Context, level int) error
+	Brightness(ctx context.Context) (int, error)
+}
+
+// ColorTemperature 色温（K），设置值按 SPEC 的取值范围截断。
+type ColorTemperature interface {
+	Capability
+	SetColorTemperature(ctx context.Context, kelvin int) error
+	ColorTemperature(ctx context.Context) (int, error)
+	// Range 返回 SPEC 中的色温范围，SPEC 不可用时为 0, 0（不截断）。
+	Range() (min, max int)
+}
+
+// Color RGB 颜色，MIoT 以 uint32 0xRRGGBB 表示；HSV 中 h 为 0-360，s、v 为 0-1。
+type Color interface {
+	Capability
+	SetRGB(ctx context.Context, r, g, b uint8) error
+	RGB(ctx context.Context) (r, g, b uint8, err error)
+	SetHSV(ctx context.Context, h, s, v float64) error
+	HSV(ctx context.Context) (h, s, v float64, err error)
+}
+
+// LightMode 灯光模式，取值见 Modes（来自 SPEC value-list）。
+type LightMode interface {
+	Capability
+	SetMode(ctx context.Context, mode int) error
+	Mode(ctx context.Context) (int, error)
+	Modes() []device.ValueItem
+}
+
+// MediaPlayer 播放控制。
+type MediaPlayer interface {
+	Capability
+	Play(ctx context.Context) error
+	Pause(ctx context.Context) error
+	Next(ctx context.Context) error
+	Previous(ctx context.Context) error
+}
+
+// VolumeControl 音量 0-100 与静音。
+type VolumeControl interface {
+	Capability
+	SetVolume(ctx context.Context, level int) error
+	Volume(ctx context.Context) (int, error)
+	SetMute(ctx context.Context, mute bool) error
+	Muted(ctx context.Context) (bool, error)
+}
+
+// TextToSpeech 音箱播报。
+type TextToSpeech interface {
+	Capability
+	Speak(ctx context.Context, text string) error
+}
+
+// OccupancySensor 人体存在传感器，Occupancy 返回原始状态值（如 0 无人、1 有人）。
+type OccupancySensor interface {
+	Capability
+	Occupancy(ctx context.Context) (interface{}, error)
+}
+
+// MultiChannelSwitch 多通道开关，通道从 0 开始。
+type MultiChannelSwitch interface {
+	Capability
+	Channels() int
+	SetChannel(ctx context.Context, channel int, on bool) error
+	Channel(ctx context.Context, channel int) (bool, error)
+}
+
+// Device 为按型号规格解析一次后的设备，能力通过类型断言或 As 获取：
+//
+//	dev, _ := c.Device("客厅灯")
+//	if d, ok := ctrl.As[ctrl.Dimmable](dev); ok {
+//		d.SetBrightness(ctx, 60)
+//	}
+type Device struct {
+	DID   string `json:"did"`
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	Spec  Spec   `json:"-"`
+
+	caps []Capability
+}
+
+// Capabilities 返回设备支持的全部能力。
+func (d *Device) Capabilities() []Capability { return d.caps }
+
+// Names 返回能力名称列表。
+func (d *Device) Names() []string {
+	names := make([]string, len(d.caps))
+	for i, c := range d.caps {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// UnsupportedError 表示请求本身无法执行：设备没有所需的动作、属性或通道，或取值、参数不合法；重试无用。
+type UnsupportedError struct {
+	Msg string
+}
+
+func (e *UnsupportedError) Error() string { return e.Msg }
+
+func unsupported(format string, a ...interface{}) error {
+	return &UnsupportedError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// As 返回 d 中第一个实现 T 的能力。
+func As[T Capability](d *Device) (T, bool) {
+	for _, c := range d.caps {
+		if t, ok := c.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Device 解析 did（或设备名称）的型号规格并返回其能力，规格来源同 spec（静态 Specs 优先）。
+func (c *Controller) Device(did string) (*Device, error) {
+	return c.DeviceContext(context.Background(), did)
+}
+
+// DeviceContext 同 Device，支持 ctx 取消。群组不支持，请对成员逐个获取。
+func (c *Controller) DeviceContext(ctx context.Context, did string) (*Device, error) {
+	if err := noGroup(did); err != nil {
+		return nil, err
+	}
+	d, err := c.API.GetContext(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	s := spec(d.Model)
+	// SPEC 只用于取值范围与枚举列表，型号没有这类能力时获取失败可忽略
+	ms, err := c.API.LoadSpecContext(ctx, d.Model)
+	if err != nil && (needsSpec(s) || ctx.Err() != nil) {
+		return nil, fmt.Errorf("ctrl: spec of %s: %w", d.Model, err)
+	}
+	return NewDevice(c.API, d.DID, d.Name, d.Model, s, ms), nil
+}
+
+// needsSpec 报告 s 中是否有需要 SPEC 取值范围或 value-list 的能力。
+func needsSpec(s Spec) bool {
+	return s.PiidColorTemperature != 0 || s.PiidMode != 0 || s.PiidClimateMode != 0 ||
+		s.PiidTargetTemperature != 0 || s.PiidTargetHumidity != 0 || s.PiidFanLevel != 0 || s.SiidVacuum != 0
+}
+
+// NewDevice 按规格 s 组装设备能力；ms 为型号 SPEC，可为 nil。
+func NewDevice(api *device.API, did, name, model string, s Spec, ms *device.ModelSpec) *Device {
+	d := &Device{DID: did, Name: name, Model: model, Spec: s}
+	b := base{api: api, did: did}
+	if siid := OnService(s); siid != 0 && s.PiidOn != 0 {
+		aiid := 0
+		if s.SiidSwitch != 0 {
+			aiid = s.AiidToggle
+		}
+		d.caps = append(d.caps, &switchable{b, siid, s.PiidOn, aiid})
+	}
+	if len(s.SwitchChannels) > 1 && s.PiidOn != 0 {
+		d.caps = append(d.caps, &multiChannel{b, s.SwitchChannels, s.PiidOn})
+	}
+	if s.SiidLight != 0 && s.PiidBrightness != 0 {
+		d.caps = append(d.caps, &dimmable{b, s.SiidLight, s.PiidBrightness})
+	}
+	if s.SiidLight != 0 && s.PiidColorTemperature != 0 {
+		ct := &colorTemperature{base: b, siid: s.SiidLight, piid: s.PiidColorTemperature}
+		if p := specProp(ms, s.SiidLight, s.PiidColorTemperature); p != nil && p.ValueRange != nil {
+			ct.min, ct.max = int(p.ValueRange.Min), int(p.ValueRange.Max)
+		}
+		d.caps = append(d.caps, ct)
+	}
+	if s.SiidLight != 0 && s.PiidColor != 0 {
+		d.caps = append(d.caps, &color{b, s.SiidLight, s.PiidColor})
+	}
+	if s.SiidLight != 0 && s.PiidMode != 0 {
+		m := &lightMode{base: b, siid: s.SiidLight, piid: s.PiidMode}
+		if p := specProp(ms, s.SiidLight, s.PiidMode); p != nil {
+			m.modes = p.ValueList
+		}
+		d.caps = append(d.caps, m)
+	}
+	d.caps = append(d.caps, climateCaps(b, s, ms)...)
+	if s.SiidVacuum != 0 {
+		d.caps = append(d.caps, newVacuum(b, s, ms))
+	}
+	if s.SiidPlayControl != 0 {
+		d.caps = append(d.caps, &mediaPlayer{b, s.SiidPlayControl, s.AiidPlay, s.AiidPause, s.AiidNext, s.AiidPrevious})
+	}
+	if s.SiidSpeaker != 0 && s.PiidVolume != 0 {
+		d.caps = append(d.caps, &volumeControl{b, s.SiidSpeaker, s.PiidVolume, s.PiidMute})
+	}
+	if s.SiidVoiceAssistant != 0 && s.AiidExecuteText != 0 {
+		d.caps = append(d.caps, &textToSpeech{b, s.SiidVoiceAssistant, s.AiidExecuteText})
+	}
+	if s.SiidOccupancy != 0 && s.PiidStatus != 0 {
+		d.caps = append(d.caps, &occupancySensor{b, s.SiidOccupancy, s.PiidStatus})
+	}
+	return d
+}
+
+func specProp(ms *device.ModelSpec, siid, piid int) *device.PropSpec {
+	if ms == nil {
+		return nil
+	}
+	return ms.Property(siid, piid)
+}
+
+type base struct {
+	api *device.API
+	did string
+}
+
+func (b base) set(ctx context.Context, siid, piid int, v interface{}) error {
+	_, err := b.api.SetPropsContext(ctx, b.did, [][3]interface{}{{siid, piid, v}})
+	return err
+}
+
+// get 读取单个属性；设备未返回值（结果为空或该项失败）时返回错误，不当作零值。
+func (b base) get(ctx context.Context, siid, piid int) (interface{}, error) {
+	vals, err := b.api.GetPropsContext(ctx, b.did, [][2]int{{siid, piid}})
+	if err != nil {
+		return nil, err
+	}
+	if len(vals) == 0 || vals[0] == nil {
+		return nil, fmt.Errorf("ctrl: %s property %d-%d: no value", b.did, siid, piid)
+	}
+	return vals[0], nil
+}
+
+// getBool、getInt、getFloat 在值类型不符时返回错误，避免把读取失败当作 false 或 0（如 Toggle 误开）。
+func (b base) getBool(ctx context.Context, siid, piid int) (bool, error) {
+	v, err := b.get(ctx, siid, piid)
+	if err != nil {
+		return false, err
+	}
+	on, ok := v.(bool)
+	if !ok {
+		return false, b.typeError(siid, piid, v, "bool")
+	}
+	return on, nil
+}
+
+func (b base) getInt(ctx context.Context, siid, piid int) (int, error) {
+	v, err := b.get(ctx, siid, piid)
+	if err != nil {
+		return 0, err
+	}
+	switch x := v.(type) {
+	case float64:
+		return int(x), nil
+	case int:
+		return x, nil
+	case uint32:
+		return int(x), nil
+	}
+	return 0, b.typeError(siid, piid, v, "number")
+}
+
+func (b base) getFloat(ctx context.Context, siid, piid int) (float64, error) {
+	v, err := b.get(ctx, siid, piid)
+	if err != nil {
+		return 0, err
+	}
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case int:
+		return float64(x), nil
+	}
+	return 0, b.typeError(siid, piid, v, "number")
+}
+
+func (b base) typeError(siid, piid int, v interface{}, want string) error {
+	return fmt.Errorf("ctrl: %s property %d-%d: got %T %v, want %s", b.did, siid, piid, v, v, want)
+}
+
+func (b base) action(ctx context.Context, siid, aiid int, in []interface{}, what string) error {
+	if aiid == 0 {
+		return unsupported("ctrl: %s has no %s action", b.did, what)
+	}
+	_, err := b.api.ActionContext(ctx, b.did, siid, aiid, in)
+	return err
+}
+
+// checkEnum 在 values 非空时检查 v 为其中之一。
+func checkEnum(did, what string, values []device.ValueItem, v int) error {
+	if len(values) == 0 {
+		return nil
+	}
+	for _, x := range values {
+		if x.Value == v {
+			return nil
+		}
+	}
+	return unsupported("ctrl: %s has no %s %d", did, what, v)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+type switchable struct {
+	base
+	siid, piid, aiidToggle int
+}
+
+func (s *switchable) Name() string { return "switchable" }
+
+func (s *switchable) SetOn(ctx context.Context, on bool) error {
+	return s.set(ctx, s.siid, s.piid, on)
+}
+
+func (s *switchable) On(ctx context.Context) (bool, error) { return s.getBool(ctx, s.siid, s.piid) }
+
+// Toggle 有 toggle 动作时直接执行，否则读取后取反。
+func (s *switchable) Toggle(ctx context.Context) error {
+	if s.aiidToggle != 0 {
+		return s.action(ctx, s.siid, s.aiidToggle, nil, "toggle")
+	}
+	on, err := s.On(ctx)
+	if err != nil {
+		return err
+	}
+	return s.SetOn(ctx, !on)
+}
+
+type multiChannel struct {
+	base
+	siids []int
+	piid  int
+}
+
+func (m *multiChannel) Name() string  { return "multi-channel-switch" }
+func (m *multiChannel) Channels() int { return len(m.siids) }
+
+func (m *multiChannel) channel(ch int) (int, error) {
+	if ch < 0 || ch >= len(m.siids) {
+		return 0, unsupported("ctrl: channel %d out of range [0,%d)", ch, len(m.siids))
+	}
+	return m.siids[ch], nil
+}
+
+func (m *multiChannel) SetChannel(ctx context.Context, ch int, on bool) error {
+	siid, err := m.channel(ch)
+	if err != nil {
+		return err
+	}
+	return m.set(ctx, siid, m.piid, on)
+}
+
+func (m *multiChannel) Channel(ctx context.Context, ch int) (bool, error) {
+	siid, err := m.channel(ch)
+	if err != nil {
+		return false, err
+	}
+	return m.getBool(ctx, siid, m.piid)
+}
+
+type dimmable struct {
+	base
+	siid, piid int
+}
+
+func (d *dimmable) Name() string { return "dimmable" }
+
+func (d *dimmable) SetBrightness(ctx context.Context, level int) error {
+	return d.set(ctx, d.siid, d.piid, clamp(level, 0, 100))
+}
+
+func (d *dimmable) Brightness(ctx context.Context) (int, error) { return d.getInt(ctx, d.siid, d.piid) }
+
+type colorTemperature struct {
+	base
+	siid, piid int
+	min, max   int
+}
+
+func (c *colorTemperature) Name() string { return "color-temperature" }
+
+func (c *colorTemperature) Range() (int, int) { return c.min, c.max }
+
+func (c *colorTemperature) SetColorTemperature(ctx context.Context, kelvin int) error {
+	if c.max > 0 {
+		kelvin = clamp(kelvin, c.min, c.max)
+	}
+	return c.set(ctx, c.siid, c.piid, kelvin)
+}
+
+func (c *colorTemperature) ColorTemperature(ctx context.Context) (int, error) {
+	return c.getInt(ctx, c.siid, c.piid)
+}
+
+type color struct {
+	base
+	siid, piid int
+}
+
+func (c *color) Name() string { return "color" }
+
+func (c *color) SetRGB(ctx context.Context, r, g, b uint8) error {
+	return c.set(ctx, c.siid, c.piid, PackRGB(r, g, b))
+}
+
+func (c *color) RGB(ctx context.Context) (uint8, uint8, uint8, error) {
+	v, err := c.getInt(ctx, c.siid, c.piid)
+	r, g, b := UnpackRGB(uint32(v))
+	return r, g, b, err
+}
+
+func (c *color) SetHSV(ctx context.Context, h, s, v float64) error {
+	r, g, b := HSVToRGB(h, s, v)
+	return c.SetRGB(ctx, r, g, b)
+}
+
+func (c *color) HSV(ctx context.Context) (float64, float64, float64, error) {
+	r, g, b, err := c.RGB(ctx)
+	h, s, v := RGBToHSV(r, g, b)
+	return h, s, v, err
+}
+
+type lightMode struct {
+	base
+	siid, piid int
+	modes      []device.ValueItem
+}
+
+func (m *lightMode) Name() string { return "light-mode" }
+
+func (m *lightMode) Modes() []device.ValueItem { return m.modes }
+
+// SetMode 在已知 value-list 时拒绝列表外的取值。
+func (m *lightMode) SetMode(ctx context.Context, mode int) error {
+	if err := checkEnum(m.did, "mode", m.modes, mode); err != nil {
+		return err
+	}
+	return m.set(ctx, m.siid, m.piid, mode)
+}
+
+func (m *lightMode) Mode(ctx context.Context) (int, error) { return m.getInt(ctx, m.siid, m.piid) }
+
+type mediaPlayer struct {
+	base
+	siid, play, pause, next, previous int
+}
+
+func (m *mediaPlayer) Name() string { return "media-player" }
+
+func (m *mediaPlayer) Play(ctx context.Context) error {
+	return m.action(ctx, m.siid, m.play, nil, "play")
+}
+
+func (m *mediaPlayer) Pause(ctx context.Context) error {
+	return m.action(ctx, m.siid, m.pause, nil, "pause")
+}
+
+func (m *mediaPlayer) Next(ctx context.Context) error {
+	return m.action(ctx, m.siid, m.next, nil, "next")
+}
+
+func (m *mediaPlayer) Previous(ctx context.Context) error {
+	return m.action(ctx, m.siid, m.previous, nil, "previous")
+}
+
+type volumeControl struct {
+	base
+	siid, volume, mute int
+}
+
+func (v *volumeControl) Name() string { return "volume-control" }
+
+func (v *volumeControl) SetVolume(ctx context.Context, level int) error {
+	return v.set(ctx, v.siid, v.volume, clamp(level, 0, 100))
+}
+
+func (v *volumeControl) Volume(ctx context.Context) (int, error) {
+	return v.getInt(ctx, v.siid, v.volume)
+}
+
+func (v *volumeControl) SetMute(ctx context.Context, mute bool) error {
+	if v.mute == 0 {
+		return unsupported("ctrl: %s has no mute", v.did)
+	}
+	return v.set(ctx, v.siid, v.mute, mute)
+}
+
+func (v *volumeControl) Muted(ctx context.Context) (bool, error) {
+	if v.mute == 0 {
+		return false, unsupported("ctrl: %s has no mute", v.did)
+	}
+	return v.getBool(ctx, v.siid, v.mute)
+}
+
+type textToSpeech struct {
+	base
+	siid, aiid int
+}
+
+func (t *textToSpeech) Name() string { return "text-to-speech" }
+
+func (t *textToSpeech) Speak(ctx context.Context, text string) error {
+	return t.action(ctx, t.siid, t.aiid, []interface{}{text}, "TTS")
+}
+
+type occupancySensor struct {
+	base
+	siid, piid int
+}
+
+func (o *occupancySensor) Name() string { return "occupancy-sensor" }
+
+func (o *occupancySensor) Occupancy(ctx context.Context) (interface{}, error) {
+	return o.get(ctx, o.siid, o.piid)
+}
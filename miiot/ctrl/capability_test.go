@@ -0,0 +1,394 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/internal/device/devicetest"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// TestMain 让 SPEC 只来自空的临时缓存与内置种子，测试不联网。
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "miflow-specs")
+	if err != nil {
+		panic(err)
+	}
+	s := specs.Default()
+	s.Dir, s.InstancesPath, s.Offline = dir, "", true
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newFakeController(model string) (*Controller, *devicetest.Transport) {
+	cloud := devicetest.New(device.TransportCloud, map[string]interface{}{"did": "1", "name": "测试设备", "model": model})
+	return New(device.NewAPIWithTransports(device.PolicyCloud, cloud)), cloud
+}
+
+func TestDeviceCapabilities(t *testing.T) {
+	tests := []struct {
+		model string
+		want  []string
+	}{
+		{"chuangmi.plug.v3", []string{"switchable"}},
+		{"opple.light.bydceiling", []string{"switchable", "dimmable", "color-temperature", "light-mode"}},
+		{"lemesh.switch.sw3f13", []string{"switchable", "multi-channel-switch"}},
+		{"xiaomi.wifispeaker.oh2", []string{"media-player", "volume-control", "text-to-speech"}},
+		{"linp.sensor_occupy.hb01", []string{"occupancy-sensor"}},
+	}
+	for _, tt := range tests {
+		c, _ := newFakeController(tt.model)
+		d, err := c.Device("测试设备")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.model, err)
+		}
+		if d.DID != "1" || d.Model != tt.model {
+			t.Errorf("%s: device = %+v", tt.model, d)
+		}
+		if got := d.Names(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: capabilities = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+	c, _ := newFakeController("chuangmi.plug.v3")
+	if _, err := c.Device("@客厅"); err == nil {
+		t.Error("group should fail")
+	}
+
+	// 色温范围需要 SPEC，离线且种子中没有时报错而不是返回没有范围的能力
+	Specs["test.light.ct"] = Spec{SiidLight: 2, PiidOn: 1, PiidColorTemperature: 3}
+	defer delete(Specs, "test.light.ct")
+	c, _ = newFakeController("test.light.ct")
+	if _, err := c.Device("1"); err == nil {
+		t.Error("missing spec for color temperature should fail")
+	}
+}
+
+func TestDeviceCapabilityCalls(t *testing.T) {
+	ctx := context.Background()
+	c, cloud := newFakeController("opple.light.bydceiling")
+	d, err := c.Device("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.Spec
+	if _, ok := As[VolumeControl](d); ok {
+		t.Error("light should not have VolumeControl")
+	}
+	dim, ok := As[Dimmable](d)
+	if !ok {
+		t.Fatal("light should be Dimmable")
+	}
+	dim.SetBrightness(ctx, 150)
+	if v, _ := dim.Brightness(ctx); v != 100 {
+		t.Errorf("brightness = %d, want clamped 100", v)
+	}
+	sw := d.Capabilities()[0].(Switchable)
+	// 读不到开关状态或类型不符时 Toggle 报错，不能当作关而打开
+	on := [2]int{s.SiidLight, s.PiidOn}
+	for _, v := range []interface{}{nil, "off"} {
+		cloud.Props[on] = v
+		if err := sw.Toggle(ctx); err == nil || cloud.Props[on] != v {
+			t.Errorf("toggle with %v = %v, props %v", v, err, cloud.Props[on])
+		}
+	}
+	cloud.Props[on] = false
+	if err := sw.Toggle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := sw.On(ctx); !on || cloud.Props[[2]int{s.SiidLight, s.PiidOn}] != true {
+		t.Errorf("toggle without action should read and invert: %v", cloud.Props)
+	}
+	if _, err := c.GetBrightness("1", "opple.light.bydceiling"); err != nil {
+		t.Errorf("GetBrightness = %v", err)
+	}
+	cloud.Props[[2]int{s.SiidLight, s.PiidBrightness}] = true
+	if _, err := c.GetBrightness("1", "opple.light.bydceiling"); err == nil {
+		t.Error("GetBrightness with bool value should fail")
+	}
+
+	c, cloud = newFakeController("lemesh.switch.sw3f13")
+	d, _ = c.Device("1")
+	s = d.Spec
+	sw, _ = As[Switchable](d)
+	sw.Toggle(ctx)
+	if len(cloud.Actions) != 1 || cloud.Actions[0] != [2]int{s.SiidSwitch, s.AiidToggle} {
+		t.Errorf("toggle actions = %v", cloud.Actions)
+	}
+	mc, _ := As[MultiChannelSwitch](d)
+	if err := mc.SetChannel(ctx, mc.Channels()-1, true); err != nil {
+		t.Fatal(err)
+	}
+	if on, _ := mc.Channel(ctx, mc.Channels()-1); !on {
+		t.Error("last channel should be on")
+	}
+	if err := mc.SetChannel(ctx, mc.Channels(), true); err == nil {
+		t.Error("out of range channel should fail")
+	}
+}
+
+// colorLightSpec 为带色温、颜色与模式的灯 SPEC。
+func colorLightSpec() (Spec, *device.ModelSpec) {
+	s := Spec{SiidLight: 2, PiidOn: 1, PiidMode: 2, PiidBrightness: 3, PiidColorTemperature: 4, PiidColor: 5}
+	ms := &device.ModelSpec{Services: []device.ServiceSpec{{IID: 2, Properties: []device.PropSpec{
+		{IID: 2, ValueList: []device.ValueItem{{Value: 0, Description: "Day"}, {Value: 1, Description: "Night"}}},
+		{IID: 4, ValueRange: &device.ValueRange{Min: 2700, Max: 6500, Step: 1}},
+	}}}}
+	return s, ms
+}
+
+func TestLightColor(t *testing.T) {
+	ctx := context.Background()
+	c, cloud := newFakeController("test.light.color")
+	s, ms := colorLightSpec()
+	d := NewDevice(c.API, "1", "彩灯", "test.light.color", s, ms)
+
+	ct, _ := As[ColorTemperature](d)
+	if min, max := ct.Range(); min != 2700 || max != 6500 {
+		t.Errorf("range = %d-%d", min, max)
+	}
+	ct.SetColorTemperature(ctx, 9000)
+	if cloud.Props[[2]int{2, 4}] != 6500 {
+		t.Errorf("color temperature = %v, want clamped 6500", cloud.Props[[2]int{2, 4}])
+	}
+
+	col, ok := As[Color](d)
+	if !ok {
+		t.Fatal("light should have Color")
+	}
+	col.SetHSV(ctx, 120, 1, 1)
+	if cloud.Props[[2]int{2, 5}] != uint32(0x00FF00) {
+		t.Errorf("color = %#v, want 0x00ff00", cloud.Props[[2]int{2, 5}])
+	}
+	cloud.Props[[2]int{2, 5}] = float64(0xFF8000)
+	if r, g, b, _ := col.RGB(ctx); r != 255 || g != 128 || b != 0 {
+		t.Errorf("rgb = %d,%d,%d", r, g, b)
+	}
+
+	mode, _ := As[LightMode](d)
+	if len(mode.Modes()) != 2 {
+		t.Errorf("modes = %v", mode.Modes())
+	}
+	if err := mode.SetMode(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mode.SetMode(ctx, 7); err == nil {
+		t.Error("mode outside value-list should fail")
+	}
+}
+
+func TestColorConversion(t *testing.T) {
+	if c := PackRGB(0x12, 0x34, 0x56); c != 0x123456 {
+		t.Errorf("PackRGB = %#x", c)
+	}
+	if r, g, b := UnpackRGB(0xFF123456); r != 0x12 || g != 0x34 || b != 0x56 {
+		t.Errorf("UnpackRGB = %x %x %x", r, g, b)
+	}
+	for _, tc := range []struct {
+		h, s, v float64
+		rgb     uint32
+	}{
+		{0, 1, 1, 0xFF0000}, {120, 1, 1, 0x00FF00}, {240, 1, 1, 0x0000FF},
+		{60, 1, 1, 0xFFFF00}, {0, 0, 0.5, 0x808080}, {-60, 1, 1, 0xFF00FF},
+	} {
+		r, g, b := HSVToRGB(tc.h, tc.s, tc.v)
+		if got := PackRGB(r, g, b); got != tc.rgb {
+			t.Errorf("HSVToRGB(%g, %g, %g) = %#06x, want %#06x", tc.h, tc.s, tc.v, got, tc.rgb)
+		}
+	}
+	if h, s, v := RGBToHSV(0, 255, 255); h != 180 || s != 1 || v != 1 {
+		t.Errorf("RGBToHSV(cyan) = %g %g %g", h, s, v)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	defer func(d time.Duration) { TransitionInterval = d }(TransitionInterval)
+	TransitionInterval = time.Millisecond
+	var got []int
+	var at []time.Duration
+	start := time.Now()
+	set := func(ctx context.Context, v int) error {
+		got = append(got, v)
+		at = append(at, time.Since(start))
+		return nil
+	}
+	if err := Transition(context.Background(), 0, 100, 4*time.Millisecond, set); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{25, 50, 75, 100}) {
+		t.Errorf("steps = %v", got)
+	}
+	// 第 i 步不早于 i·d/n，第一步也要等一个间隔
+	for i, d := range at {
+		if want := time.Duration(i+1) * time.Millisecond; d < want {
+			t.Errorf("step %d at %v, want >= %v", i+1, d, want)
+		}
+	}
+
+	got = nil
+	Transition(context.Background(), 50, 52, 4*time.Millisecond, set)
+	if !reflect.DeepEqual(got, []int{51, 52}) {
+		t.Errorf("repeated values should be skipped: %v", got)
+	}
+
+	got = nil
+	Transition(context.Background(), 10, 90, 0, set)
+	if !reflect.DeepEqual(got, []int{90}) {
+		t.Errorf("zero duration = %v, want [90]", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	got = nil
+	err := Transition(ctx, 0, 100, time.Second, func(ctx context.Context, v int) error {
+		got = append(got, v)
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || len(got) != 1 {
+		t.Errorf("cancel: %v after %v", err, got)
+	}
+
+	c, cloud := newFakeController("opple.light.bydceiling")
+	d, _ := c.Device("1")
+	cloud.Props[[2]int{2, 3}] = float64(20)
+	dim, _ := As[Dimmable](d)
+	if err := FadeBrightness(context.Background(), dim, 80, 3*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if cloud.Props[[2]int{2, 3}] != 80 {
+		t.Errorf("brightness after fade = %v", cloud.Props[[2]int{2, 3}])
+	}
+}
+
+func TestClimateCapabilities(t *testing.T) {
+	ctx := context.Background()
+	c, cloud := newFakeController("zhimi.airpurifier.ma4")
+	d, err := c.Device("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"switchable", "climate-mode", "fan-speed", "filter-life", "environment-sensor"}
+	if got := d.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("air purifier capabilities = %v, want %v", got, want)
+	}
+	sw, _ := As[Switchable](d)
+	sw.SetOn(ctx, true)
+	if cloud.Props[[2]int{2, 2}] != true {
+		t.Errorf("power = %v", cloud.Props)
+	}
+	cloud.Props[[2]int{3, 6}] = float64(35)
+	env, _ := As[EnvironmentSensor](d)
+	if pm, err := env.PM25(ctx); err != nil || pm != 35 {
+		t.Errorf("PM25 = %v, %v", pm, err)
+	}
+
+	s := Spec{SiidClimate: 2, PiidOn: 1, PiidClimateMode: 2, PiidTargetTemperature: 4, SiidFanControl: 3, PiidFanLevel: 2, PiidOscillation: 4,
+		SiidEnvironment: 5, PiidTemperature: 1}
+	ms := &device.ModelSpec{Services: []device.ServiceSpec{
+		{IID: 2, Properties: []device.PropSpec{
+			{IID: 2, ValueList: []device.ValueItem{{Value: 1, Description: "Cool"}, {Value: 2, Description: "Heat"}}},
+			{IID: 4, ValueRange: &device.ValueRange{Min: 16, Max: 31, Step: 0.5}},
+		}},
+		{IID: 3, Properties: []device.PropSpec{{IID: 2, ValueRange: &device.ValueRange{Min: 1, Max: 5, Step: 1}}}},
+	}}
+	d = NewDevice(c.API, "1", "空调", "test.aircondition.v1", s, ms)
+	th, _ := As[Thermostat](d)
+	th.SetTargetTemperature(ctx, 35)
+	if cloud.Props[[2]int{2, 4}] != 31.0 {
+		t.Errorf("target temperature = %v, want clamped 31", cloud.Props[[2]int{2, 4}])
+	}
+	mode, _ := As[ClimateMode](d)
+	if err := mode.SetClimateMode(ctx, 3); err == nil {
+		t.Error("mode outside value-list should fail")
+	}
+	fan, _ := As[FanSpeed](d)
+	fan.SetFanLevel(ctx, 9)
+	if cloud.Props[[2]int{3, 2}] != 5 {
+		t.Errorf("fan level = %v, want clamped 5", cloud.Props[[2]int{3, 2}])
+	}
+	osc, _ := As[Oscillation](d)
+	osc.SetOscillation(ctx, true)
+	if on, _ := osc.Oscillating(ctx); !on {
+		t.Error("oscillation should be on")
+	}
+	env, _ = As[EnvironmentSensor](d)
+	if _, err := env.PM25(ctx); err == nil {
+		t.Error("missing PM2.5 sensor should fail")
+	}
+	if _, ok := As[LightMode](d); ok {
+		t.Error("climate mode should not satisfy LightMode")
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	ctx := context.Background()
+	c, cloud := newFakeController("roidmi.vacuum.v60")
+	d, err := c.Device("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Names(); !reflect.DeepEqual(got, []string{"vacuum"}) {
+		t.Errorf("capabilities = %v", got)
+	}
+	v, _ := As[Vacuum](d)
+	s := d.Spec
+	v.Start(ctx)
+	v.Dock(ctx)
+	if err := v.CleanRooms(ctx, []int{10, 11}); err != nil {
+		t.Fatal(err)
+	}
+	var unsupportedErr *UnsupportedError
+	if err := v.CleanRooms(ctx, nil); err == nil || errors.As(err, &unsupportedErr) {
+		t.Errorf("CleanRooms(nil) = %v, want argument error", err)
+	}
+	want := [][2]int{{s.SiidVacuum, s.AiidVacuumStart}, {s.SiidBattery, s.AiidStartCharge}, {s.SiidVacuum, s.AiidVacuumRoom}}
+	if !reflect.DeepEqual(cloud.Actions, want) {
+		t.Errorf("actions = %v, want %v", cloud.Actions, want)
+	}
+	if in := cloud.Ins[2]; len(in) != 1 || in[0] != "10,11" {
+		t.Errorf("room sweep in = %#v", in)
+	}
+	var ue *UnsupportedError
+	if err := v.Pause(ctx); !errors.As(err, &ue) {
+		t.Errorf("v60 has no pause action, got %v", err)
+	}
+	if _, ok := As[FanSpeed](d); !ok {
+		t.Error("vacuum should also satisfy FanSpeed")
+	}
+
+	cloud.Props[[2]int{s.SiidVacuum, s.PiidVacuumStatus}] = float64(2)
+	cloud.Props[[2]int{s.SiidBattery, s.PiidBatteryLevel}] = float64(87)
+	cloud.Props[[2]int{s.SiidVacuum, s.PiidVacuumFanLevel}] = float64(1)
+	ms := &device.ModelSpec{Services: []device.ServiceSpec{{IID: 2, Properties: []device.PropSpec{
+		{IID: 1, ValueList: []device.ValueItem{{Value: 1, Description: "Idle"}, {Value: 2, Description: "Sweeping"}}},
+		{IID: 4, ValueList: []device.ValueItem{{Value: 0, Description: "Silent"}, {Value: 1, Description: "Basic"}}},
+	}}}}
+	ms.Labels = map[string]string{"service:002:property:001:valuelist:001": "清扫中"}
+	v, _ = As[Vacuum](NewDevice(c.API, "1", "扫地机", "roidmi.vacuum.v60", s, ms))
+	st, err := v.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Status != 2 || st.StatusText != "清扫中" || st.Battery != 87 || st.FanLevel != 1 || !st.Rooms {
+		t.Errorf("state = %+v", st)
+	}
+	if err := v.SetFanLevel(ctx, 3); err == nil {
+		t.Error("fan level outside value-list should fail")
+	}
+
+	// SPEC 有 pause-sweeping 的型号可暂停
+	s.AiidVacuumPause = 4
+	v, _ = As[Vacuum](NewDevice(c.API, "1", "扫地机", "test.vacuum.pause", s, ms))
+	cloud.Actions = nil
+	if err := v.Pause(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]int{{s.SiidVacuum, 4}}; !reflect.DeepEqual(cloud.Actions, want) {
+		t.Errorf("pause actions = %v, want %v", cloud.Actions, want)
+	}
+}
@@ -0,0 +1,162 @@
+package ctrl
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/zeusro/miflow/internal/device"
+)
+
+// Issue 为 Specs 中某字段与当前 SPEC 不一致之处。
+type Issue struct {
+	Field   string `json:"field"`             // 如 PiidBrightness、SwitchChannels[1]
+	IID     string `json:"iid"`               // Specs 中的值，如 2.3
+	Reason  string `json:"reason"`            // not found 或 is <名称>, want <名称>
+	Suggest string `json:"suggest,omitempty"` // 当前 SPEC 中期望名称所在的 iid
+}
+
+// CheckResult 为一个型号的检查结果。
+type CheckResult struct {
+	Model  string  `json:"model"`
+	URN    string  `json:"urn,omitempty"`
+	Issues []Issue `json:"issues,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// CheckSpec 检查 s 中的非零 siid/piid/aiid 在 spec 中存在且 URN 名称符合字段含义，
+// 用于发现固件升级后 SPEC 重新编号导致的静默失效。
+func CheckSpec(s Spec, spec *device.ModelSpec) []Issue {
+	v := reflect.ValueOf(s)
+	get := func(f string) int { return int(v.FieldByName(f).Int()) }
+	var issues []Issue
+	// 期望的名称取自 ResolveSpec 的类型规则；PiidOn 属于 OnService，单独处理
+	for _, r := range serviceRules {
+		siid := get(r.field)
+		if siid == 0 {
+			continue
+		}
+		issues = append(issues, checkIID(spec, r.field, "service", siid, 0, r.types)...)
+		for _, e := range r.props {
+			if iid := get(e.field); iid != 0 && e.field != "PiidOn" {
+				issues = append(issues, checkIID(spec, e.field, "property", siid, iid, e.types)...)
+			}
+		}
+		for _, e := range r.actions {
+			if iid := get(e.field); iid != 0 {
+				issues = append(issues, checkIID(spec, e.field, "action", siid, iid, e.types)...)
+			}
+		}
+	}
+	if on := get("PiidOn"); on != 0 {
+		siid := OnService(s)
+		issues = append(issues, checkIID(spec, "PiidOn", "property", siid, on, []string{"on"})...)
+	}
+	for i, siid := range s.SwitchChannels {
+		issues = append(issues, checkIID(spec, fmt.Sprintf("SwitchChannels[%d]", i), "service", siid, 0, []string{"switch"})...)
+	}
+	return issues
+}
+
+// checkIID 检查一个元素；siid 所在服务缺失时只报告服务。
+func checkIID(spec *device.ModelSpec, field, element string, siid, iid int, names []string) []Issue {
+	is := Issue{Field: field, IID: fmt.Sprint(siid)}
+	if element != "service" {
+		is.IID = fmt.Sprintf("%d.%d", siid, iid)
+	}
+	var found string
+	svc := spec.Service(siid)
+	switch {
+	case svc == nil:
+	case element == "service":
+		found = svc.Name()
+	case element == "property":
+		if p := spec.Property(siid, iid); p != nil {
+			found = p.Name()
+		}
+	case element == "action":
+		if a := spec.Action(siid, iid); a != nil {
+			found = a.Name()
+		}
+	}
+	for _, n := range names {
+		if found == n {
+			return nil
+		}
+	}
+	if svc == nil && element != "service" {
+		is.Reason = fmt.Sprintf("service %d not found", siid)
+	} else if found == "" {
+		is.Reason = element + " not found"
+	} else {
+		is.Reason = fmt.Sprintf("is %s, want %s", found, names[0])
+	}
+	is.Suggest = locate(spec, element, names)
+	return []Issue{is}
+}
+
+// locate 返回 spec 中第一个名称匹配的元素 iid。
+func locate(spec *device.ModelSpec, element string, names []string) string {
+	match := func(name string) bool {
+		for _, n := range names {
+			if name == n {
+				return true
+			}
+		}
+		return false
+	}
+	for _, svc := range spec.Services {
+		if element == "service" && match(svc.Name()) {
+			return fmt.Sprint(svc.IID)
+		}
+		if element == "property" {
+			for i := range svc.Properties {
+				if match(svc.Properties[i].Name()) {
+					return fmt.Sprintf("%d.%d", svc.IID, svc.Properties[i].IID)
+				}
+			}
+		}
+		if element == "action" {
+			for i := range svc.Actions {
+				if match(svc.Actions[i].Name()) {
+					return fmt.Sprintf("%d.%d", svc.IID, svc.Actions[i].IID)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// CheckSpecs 对 m list 中每个在 Specs 中有静态条目的型号，按当前 SPEC 执行 CheckSpec。
+func (c *Controller) CheckSpecs(ctx context.Context) ([]CheckResult, error) {
+	devs, err := c.API.ListContext(ctx, "", false, 0)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var models []string
+	for _, d := range devs {
+		if d == nil || seen[d.Model] {
+			continue
+		}
+		seen[d.Model] = true
+		if _, ok := Specs[d.Model]; ok {
+			models = append(models, d.Model)
+		}
+	}
+	sort.Strings(models)
+	out := make([]CheckResult, 0, len(models))
+	for _, model := range models {
+		r := CheckResult{Model: model}
+		spec, err := c.API.LoadSpec(model)
+		if err != nil {
+			r.Error = err.Error()
+		} else {
+			r.URN = spec.Type
+			r.Issues = CheckSpec(Specs[model], spec)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
@@ -0,0 +1,47 @@
+package ctrl
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device"
+)
+
+func switchSpec(siid int) *device.ModelSpec {
+	return &device.ModelSpec{Services: []device.ServiceSpec{
+		{IID: 1, Type: "urn:miot-spec-v2:service:device-information:00007801:test:1"},
+		{IID: siid, Type: "urn:miot-spec-v2:service:switch:0000780C:test:1",
+			Properties: []device.PropSpec{{IID: 1, Type: "urn:miot-spec-v2:property:on:00000006:test:1"}},
+			Actions:    []device.ActionSpec{{IID: 1, Type: "urn:miot-spec-v2:action:toggle:00002811:test:1"}}},
+	}}
+}
+
+func TestCheckSpec(t *testing.T) {
+	s := Spec{SiidSwitch: 2, PiidOn: 1, AiidToggle: 1}
+	if issues := CheckSpec(s, switchSpec(2)); len(issues) != 0 {
+		t.Errorf("matching spec: %+v", issues)
+	}
+
+	// 固件升级后开关服务移到 siid 3，siid 2 变为指示灯
+	moved := switchSpec(3)
+	moved.Services = append(moved.Services, device.ServiceSpec{IID: 2, Type: "urn:miot-spec-v2:service:indicator-light:00007803:test:1"})
+	issues := CheckSpec(s, moved)
+	if len(issues) != 3 {
+		t.Fatalf("got %d issues, want 3: %+v", len(issues), issues)
+	}
+	want := []Issue{
+		{Field: "SiidSwitch", IID: "2", Reason: "is indicator-light, want switch", Suggest: "3"},
+		{Field: "AiidToggle", IID: "2.1", Reason: "action not found", Suggest: "3.1"},
+		{Field: "PiidOn", IID: "2.1", Reason: "property not found", Suggest: "3.1"},
+	}
+	for i, w := range want {
+		if issues[i] != w {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], w)
+		}
+	}
+
+	ch := Spec{SiidSwitch: 2, PiidOn: 1, SwitchChannels: []int{2, 4}}
+	issues = CheckSpec(ch, switchSpec(2))
+	if len(issues) != 1 || issues[0].Field != "SwitchChannels[1]" || issues[0].Reason != "service not found" {
+		t.Errorf("channels: %+v", issues)
+	}
+}
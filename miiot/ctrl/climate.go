@@ -0,0 +1,239 @@
+package ctrl
+
+import (
+	"context"
+
+	"github.com/zeusro/miflow/internal/device"
+)
+
+// ClimateMode 空调、风扇、净化器等的工作模式，取值见 ClimateModes（来自 SPEC value-list）。
+type ClimateMode interface {
+	Capability
+	SetClimateMode(ctx context.Context, mode int) error
+	ClimateMode(ctx context.Context) (int, error)
+	ClimateModes() []device.ValueItem
+}
+
+// Thermostat 目标温度（℃），设置值按 SPEC 的取值范围截断。
+type Thermostat interface {
+	Capability
+	SetTargetTemperature(ctx context.Context, celsius float64) error
+	TargetTemperature(ctx context.Context) (float64, error)
+	// TargetTemperatureRange 返回 SPEC 中的范围，SPEC 不可用时为 0, 0（不截断）。
+	TargetTemperatureRange() (min, max float64)
+}
+
+// Humidistat 目标湿度（%），设置值按 SPEC 的取值范围截断。
+type Humidistat interface {
+	Capability
+	SetTargetHumidity(ctx context.Context, percent int) error
+	TargetHumidity(ctx context.Context) (int, error)
+}
+
+// FanSpeed 风速档位，取值见 FanLevels（value-list 型号）；value-range 型号按范围截断。
+type FanSpeed interface {
+	Capability
+	SetFanLevel(ctx context.Context, level int) error
+	FanLevel(ctx context.Context) (int, error)
+	FanLevels() []device.ValueItem
+}
+
+// Oscillation 摆风（horizontal-swing 或 vertical-swing）。
+type Oscillation interface {
+	Capability
+	SetOscillation(ctx context.Context, on bool) error
+	Oscillating(ctx context.Context) (bool, error)
+}
+
+// FilterLife 滤芯剩余寿命（%）。
+type FilterLife interface {
+	Capability
+	FilterLife(ctx context.Context) (int, error)
+}
+
+// EnvironmentSensor 环境传感器：温度（℃）、相对湿度（%）、PM2.5（μg/m³），型号没有的读数返回错误。
+type EnvironmentSensor interface {
+	Capability
+	Temperature(ctx context.Context) (float64, error)
+	Humidity(ctx context.Context) (float64, error)
+	PM25(ctx context.Context) (float64, error)
+}
+
+// climateCaps 按 s 中的 Climate、FanControl、Filter、Environment 字段组装能力。
+func climateCaps(b base, s Spec, ms *device.ModelSpec) []Capability {
+	var caps []Capability
+	if s.SiidClimate != 0 && s.PiidClimateMode != 0 {
+		m := &climateMode{base: b, siid: s.SiidClimate, piid: s.PiidClimateMode}
+		if p := specProp(ms, s.SiidClimate, s.PiidClimateMode); p != nil {
+			m.modes = p.ValueList
+		}
+		caps = append(caps, m)
+	}
+	if s.SiidClimate != 0 && s.PiidTargetTemperature != 0 {
+		t := &thermostat{base: b, siid: s.SiidClimate, piid: s.PiidTargetTemperature}
+		if p := specProp(ms, s.SiidClimate, s.PiidTargetTemperature); p != nil && p.ValueRange != nil {
+			t.min, t.max = p.ValueRange.Min, p.ValueRange.Max
+		}
+		caps = append(caps, t)
+	}
+	if s.SiidClimate != 0 && s.PiidTargetHumidity != 0 {
+		h := &humidistat{base: b, siid: s.SiidClimate, piid: s.PiidTargetHumidity, max: 100}
+		if p := specProp(ms, s.SiidClimate, s.PiidTargetHumidity); p != nil && p.ValueRange != nil {
+			h.min, h.max = int(p.ValueRange.Min), int(p.ValueRange.Max)
+		}
+		caps = append(caps, h)
+	}
+	if s.SiidFanControl != 0 && s.PiidFanLevel != 0 {
+		f := &fanSpeed{base: b, siid: s.SiidFanControl, piid: s.PiidFanLevel}
+		if p := specProp(ms, s.SiidFanControl, s.PiidFanLevel); p != nil {
+			f.levels = p.ValueList
+			if p.ValueRange != nil {
+				f.min, f.max = int(p.ValueRange.Min), int(p.ValueRange.Max)
+			}
+		}
+		caps = append(caps, f)
+	}
+	if s.SiidFanControl != 0 && s.PiidOscillation != 0 {
+		caps = append(caps, &oscillation{b, s.SiidFanControl, s.PiidOscillation})
+	}
+	if s.SiidFilter != 0 && s.PiidFilterLife != 0 {
+		caps = append(caps, &filterLife{b, s.SiidFilter, s.PiidFilterLife})
+	}
+	if s.SiidEnvironment != 0 && (s.PiidTemperature != 0 || s.PiidHumidity != 0 || s.PiidPM25 != 0) {
+		caps = append(caps, &environment{b, s.SiidEnvironment, s.PiidTemperature, s.PiidHumidity, s.PiidPM25})
+	}
+	return caps
+}
+
+type climateMode struct {
+	base
+	siid, piid int
+	modes      []device.ValueItem
+}
+
+func (m *climateMode) Name() string { return "climate-mode" }
+
+func (m *climateMode) ClimateModes() []device.ValueItem { return m.modes }
+
+func (m *climateMode) SetClimateMode(ctx context.Context, mode int) error {
+	if err := checkEnum(m.did, "mode", m.modes, mode); err != nil {
+		return err
+	}
+	return m.set(ctx, m.siid, m.piid, mode)
+}
+
+func (m *climateMode) ClimateMode(ctx context.Context) (int, error) {
+	return m.getInt(ctx, m.siid, m.piid)
+}
+
+type thermostat struct {
+	base
+	siid, piid int
+	min, max   float64
+}
+
+func (t *thermostat) Name() string { return "thermostat" }
+
+func (t *thermostat) TargetTemperatureRange() (float64, float64) { return t.min, t.max }
+
+func (t *thermostat) SetTargetTemperature(ctx context.Context, celsius float64) error {
+	if t.max > 0 {
+		celsius = max(t.min, min(t.max, celsius))
+	}
+	return t.set(ctx, t.siid, t.piid, celsius)
+}
+
+func (t *thermostat) TargetTemperature(ctx context.Context) (float64, error) {
+	return t.getFloat(ctx, t.siid, t.piid)
+}
+
+type humidistat struct {
+	base
+	siid, piid int
+	min, max   int
+}
+
+func (h *humidistat) Name() string { return "humidistat" }
+
+func (h *humidistat) SetTargetHumidity(ctx context.Context, percent int) error {
+	return h.set(ctx, h.siid, h.piid, clamp(percent, h.min, h.max))
+}
+
+func (h *humidistat) TargetHumidity(ctx context.Context) (int, error) {
+	return h.getInt(ctx, h.siid, h.piid)
+}
+
+type fanSpeed struct {
+	base
+	siid, piid int
+	levels     []device.ValueItem
+	min, max   int
+}
+
+func (f *fanSpeed) Name() string { return "fan-speed" }
+
+func (f *fanSpeed) FanLevels() []device.ValueItem { return f.levels }
+
+func (f *fanSpeed) SetFanLevel(ctx context.Context, level int) error {
+	if err := checkEnum(f.did, "fan level", f.levels, level); err != nil {
+		return err
+	}
+	if f.max > 0 {
+		level = clamp(level, f.min, f.max)
+	}
+	return f.set(ctx, f.siid, f.piid, level)
+}
+
+func (f *fanSpeed) FanLevel(ctx context.Context) (int, error) { return f.getInt(ctx, f.siid, f.piid) }
+
+type oscillation struct {
+	base
+	siid, piid int
+}
+
+func (o *oscillation) Name() string { return "oscillation" }
+
+func (o *oscillation) SetOscillation(ctx context.Context, on bool) error {
+	return o.set(ctx, o.siid, o.piid, on)
+}
+
+func (o *oscillation) Oscillating(ctx context.Context) (bool, error) {
+	return o.getBool(ctx, o.siid, o.piid)
+}
+
+type filterLife struct {
+	base
+	siid, piid int
+}
+
+func (f *filterLife) Name() string { return "filter-life" }
+
+func (f *filterLife) FilterLife(ctx context.Context) (int, error) {
+	return f.getInt(ctx, f.siid, f.piid)
+}
+
+type environment struct {
+	base
+	siid, temperature, humidity, pm25 int
+}
+
+func (e *environment) Name() string { return "environment-sensor" }
+
+func (e *environment) read(ctx context.Context, piid int, what string) (float64, error) {
+	if piid == 0 {
+		return 0, unsupported("ctrl: %s has no %s sensor", e.did, what)
+	}
+	return e.getFloat(ctx, e.siid, piid)
+}
+
+func (e *environment) Temperature(ctx context.Context) (float64, error) {
+	return e.read(ctx, e.temperature, "temperature")
+}
+
+func (e *environment) Humidity(ctx context.Context) (float64, error) {
+	return e.read(ctx, e.humidity, "humidity")
+}
+
+func (e *environment) PM25(ctx context.Context) (float64, error) {
+	return e.read(ctx, e.pm25, "PM2.5")
+}
@@ -0,0 +1,66 @@
+package ctrl
+
+import "math"
+
+// PackRGB 将 RGB 打包为 MIoT color 属性的 uint32（0xRRGGBB）。
+func PackRGB(r, g, b uint8) uint32 {
+	return uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+}
+
+// UnpackRGB 为 PackRGB 的逆运算，忽略高 8 位。
+func UnpackRGB(c uint32) (r, g, b uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+// HSVToRGB 将 h（0-360）、s、v（0-1）转为 RGB，超出范围的值先截断。
+func HSVToRGB(h, s, v float64) (r, g, b uint8) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s, v = math.Max(0, math.Min(1, s)), math.Max(0, math.Min(1, v))
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf = c, x
+	case h < 120:
+		rf, gf = x, c
+	case h < 180:
+		gf, bf = c, x
+	case h < 240:
+		gf, bf = x, c
+	case h < 300:
+		rf, bf = x, c
+	default:
+		rf, bf = c, x
+	}
+	m := v - c
+	to8 := func(f float64) uint8 { return uint8(math.Round((f + m) * 255)) }
+	return to8(rf), to8(gf), to8(bf)
+}
+
+// RGBToHSV 为 HSVToRGB 的逆运算，灰色的 h 为 0。
+func RGBToHSV(r, g, b uint8) (h, s, v float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(rf, math.Max(gf, bf))
+	min := math.Min(rf, math.Min(gf, bf))
+	d := max - min
+	switch {
+	case d == 0:
+	case max == rf:
+		h = 60 * math.Mod((gf-bf)/d, 6)
+	case max == gf:
+		h = 60 * ((bf-rf)/d + 2)
+	default:
+		h = 60 * ((rf-gf)/d + 4)
+	}
+	if h < 0 {
+		h += 360
+	}
+	if max > 0 {
+		s = d / max
+	}
+	return h, s, max
+}
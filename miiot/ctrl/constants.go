@@ -7,8 +7,11 @@ type Spec struct {
 	PiidOn     int
 	AiidToggle int
 	// Light 灯光（部分型号）
-	SiidLight      int
-	PiidBrightness int
+	SiidLight            int
+	PiidBrightness       int
+	PiidColorTemperature int
+	PiidColor            int // RGB，uint32 0xRRGGBB
+	PiidMode             int
 	// Speaker 音箱
 	SiidVoiceAssistant int
 	AiidExecuteText    int
@@ -26,6 +29,34 @@ type Spec struct {
 	// Occupancy  occupancy sensor
 	SiidOccupancy int
 	PiidStatus    int
+	// Climate 空调、暖风机、风扇、加湿器、净化器的主服务；PiidOn 同样适用
+	SiidClimate           int
+	PiidClimateMode       int
+	PiidTargetTemperature int
+	PiidTargetHumidity    int
+	// 风速与摆风：空调为 fan-control 服务，风扇、加湿器、净化器为主服务
+	SiidFanControl  int
+	PiidFanLevel    int
+	PiidOscillation int // horizontal-swing 或 vertical-swing
+	SiidFilter      int
+	PiidFilterLife  int // 滤芯剩余 %
+	// Environment 环境传感器
+	SiidEnvironment int
+	PiidTemperature int
+	PiidHumidity    int // relative-humidity %
+	PiidPM25        int // pm2.5-density μg/m³
+	// Vacuum 扫地机器人
+	SiidVacuum         int
+	PiidVacuumStatus   int
+	PiidVacuumFanLevel int // 吸力档位：fan-level 或 mode
+	AiidVacuumStart    int // start-sweep
+	AiidVacuumStop     int // stop-sweeping
+	AiidVacuumPause    int // pause-sweeping
+	AiidVacuumRoom     int // start-room-sweep，参数为房间 ID 列表
+	// Battery 电池与回充
+	SiidBattery      int
+	PiidBatteryLevel int
+	AiidStartCharge  int // start-charge
 	// 多通道开关的 siid 列表（如 lemesh.switch.sw3f13 左中右）
 	SwitchChannels []int
 }
@@ -55,7 +86,7 @@ var Specs = map[string]Spec{
 	},
 	// Light
 	"opple.light.bydceiling": {
-		SiidLight: 2, PiidOn: 1, PiidBrightness: 3,
+		SiidLight: 2, PiidOn: 1, PiidMode: 2, PiidBrightness: 3, PiidColorTemperature: 4,
 	},
 	"giot.light.v5ssm": {
 		SiidLight: 2, PiidOn: 1, PiidBrightness: 2,
@@ -84,4 +115,33 @@ var Specs = map[string]Spec{
 	"linp.sensor_occupy.hb01": {
 		SiidOccupancy: 2, PiidStatus: 1,
 	},
+	// Climate
+	"zhimi.airpurifier.ma4": {
+		SiidClimate: 2, PiidOn: 2, PiidClimateMode: 5,
+		SiidFanControl: 2, PiidFanLevel: 4,
+		SiidEnvironment: 3, PiidPM25: 6, PiidHumidity: 7, PiidTemperature: 8,
+		SiidFilter: 4, PiidFilterLife: 3,
+	},
+	"zhimi.humidifier.ca4": {
+		SiidClimate: 2, PiidOn: 1, PiidTargetHumidity: 6,
+		SiidFanControl: 2, PiidFanLevel: 5,
+		SiidEnvironment: 3, PiidTemperature: 7, PiidHumidity: 9,
+	},
+	"zhimi.heater.mc2": {
+		SiidClimate: 2, PiidOn: 1, PiidTargetTemperature: 5,
+		SiidEnvironment: 4, PiidTemperature: 7,
+	},
+	"dmaker.fan.p5": {
+		SiidClimate: 2, PiidOn: 1, PiidClimateMode: 4,
+		SiidFanControl: 2, PiidFanLevel: 2, PiidOscillation: 3,
+	},
+	// Vacuum
+	"mijia.vacuum.v2": {
+		SiidVacuum: 2, PiidVacuumStatus: 1, PiidVacuumFanLevel: 6, AiidVacuumStart: 1, AiidVacuumStop: 2,
+		SiidBattery: 3, PiidBatteryLevel: 1, AiidStartCharge: 1,
+	},
+	"roidmi.vacuum.v60": {
+		SiidVacuum: 2, PiidVacuumStatus: 1, PiidVacuumFanLevel: 4, AiidVacuumStart: 1, AiidVacuumStop: 2, AiidVacuumRoom: 3,
+		SiidBattery: 3, PiidBatteryLevel: 1, AiidStartCharge: 1,
+	},
 }
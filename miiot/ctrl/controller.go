@@ -1,6 +1,7 @@
 package ctrl
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/zeusro/miflow/internal/device"
@@ -28,52 +29,109 @@ func spec(model string) Spec {
 	return Spec{}
 }
 
+// OnService 返回 PiidOn 所在的服务：SiidSwitch，其次 SiidLight、SiidClimate。
+func OnService(s Spec) int {
+	switch {
+	case s.SiidSwitch != 0:
+		return s.SiidSwitch
+	case s.SiidLight != 0:
+		return s.SiidLight
+	}
+	return s.SiidClimate
+}
+
+// forGroup 当 did 为 @群组 时，对每个成员按其型号并发执行 fn，ok 表示已按群组处理。
+// 部分成员失败时返回 *device.GroupError，含每个成员的结果。
+func (c *Controller) forGroup(ctx context.Context, did string, fn func(ctx context.Context, did, model string) error) (bool, error) {
+	name, ok := device.GroupRef(did)
+	if !ok {
+		return false, nil
+	}
+	_, err := c.API.FanOutContext(ctx, name, func(ctx context.Context, d *device.Device) (interface{}, error) {
+		return nil, fn(ctx, d.DID, d.Model)
+	})
+	return true, err
+}
+
+// noGroup 读取类方法不支持群组，请用 API.FanOutContext 逐个读取。
+func noGroup(did string) error {
+	if _, ok := device.GroupRef(did); ok {
+		return fmt.Errorf("ctrl: %s is a group, read members via API.FanOutContext", did)
+	}
+	return nil
+}
+
 // SetOn 设置开关/插座/灯的开状态。
 func (c *Controller) SetOn(did, model string, on bool) error {
-	s := spec(model)
-	if s.SiidSwitch == 0 && s.SiidLight == 0 {
-		return fmt.Errorf("ctrl: model %s has no switch/light service", model)
+	return c.SetOnContext(context.Background(), did, model, on)
+}
+
+// SetOnContext 同 SetOn，支持 ctx 取消。
+func (c *Controller) SetOnContext(ctx context.Context, did, model string, on bool) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.SetOnContext(ctx, did, model, on)
+	}); ok {
+		return err
 	}
-	siid := s.SiidSwitch
-	if siid == 0 {
-		siid = s.SiidLight
+	s := spec(model)
+	siid := OnService(s)
+	if siid == 0 || s.PiidOn == 0 {
+		return fmt.Errorf("ctrl: model %s has no on/off property (switch, light or climate service)", model)
 	}
-	_, err := c.API.SetProps(did, [][3]interface{}{{siid, s.PiidOn, on}})
+	_, err := c.API.SetPropsContext(ctx, did, [][3]interface{}{{siid, s.PiidOn, on}})
 	return err
 }
 
 // GetOn 获取开关/插座/灯的开状态。
 func (c *Controller) GetOn(did, model string) (bool, error) {
-	s := spec(model)
-	siid := s.SiidSwitch
-	if siid == 0 {
-		siid = s.SiidLight
-	}
-	if siid == 0 {
-		return false, fmt.Errorf("ctrl: model %s has no switch/light service", model)
-	}
-	vals, err := c.API.GetProps(did, [][2]int{{siid, s.PiidOn}})
-	if err != nil || len(vals) == 0 {
+	return c.GetOnContext(context.Background(), did, model)
+}
+
+// GetOnContext 同 GetOn，支持 ctx 取消。
+func (c *Controller) GetOnContext(ctx context.Context, did, model string) (bool, error) {
+	if err := noGroup(did); err != nil {
 		return false, err
 	}
-	if b, ok := vals[0].(bool); ok {
-		return b, nil
+	s := spec(model)
+	siid := OnService(s)
+	if siid == 0 || s.PiidOn == 0 {
+		return false, fmt.Errorf("ctrl: model %s has no on/off property (switch, light or climate service)", model)
 	}
-	return false, nil
+	return base{c.API, did}.getBool(ctx, siid, s.PiidOn)
 }
 
 // Toggle 切换开关。
 func (c *Controller) Toggle(did, model string) error {
+	return c.ToggleContext(context.Background(), did, model)
+}
+
+// ToggleContext 同 Toggle，支持 ctx 取消。
+func (c *Controller) ToggleContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.ToggleContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidSwitch == 0 || s.AiidToggle == 0 {
 		return fmt.Errorf("ctrl: model %s has no toggle action", model)
 	}
-	_, err := c.API.Action(did, s.SiidSwitch, s.AiidToggle, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidSwitch, s.AiidToggle, nil)
 	return err
 }
 
 // SetBrightness 设置亮度 0-100。
 func (c *Controller) SetBrightness(did, model string, level int) error {
+	return c.SetBrightnessContext(context.Background(), did, model, level)
+}
+
+// SetBrightnessContext 同 SetBrightness，支持 ctx 取消。
+func (c *Controller) SetBrightnessContext(ctx context.Context, did, model string, level int) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.SetBrightnessContext(ctx, did, model, level)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidLight == 0 || s.PiidBrightness == 0 {
 		return fmt.Errorf("ctrl: model %s has no brightness", model)
@@ -84,41 +142,59 @@ func (c *Controller) SetBrightness(did, model string, level int) error {
 	if level > 100 {
 		level = 100
 	}
-	_, err := c.API.SetProps(did, [][3]interface{}{{s.SiidLight, s.PiidBrightness, level}})
+	_, err := c.API.SetPropsContext(ctx, did, [][3]interface{}{{s.SiidLight, s.PiidBrightness, level}})
 	return err
 }
 
 // GetBrightness 获取亮度。
 func (c *Controller) GetBrightness(did, model string) (int, error) {
+	return c.GetBrightnessContext(context.Background(), did, model)
+}
+
+// GetBrightnessContext 同 GetBrightness，支持 ctx 取消。
+func (c *Controller) GetBrightnessContext(ctx context.Context, did, model string) (int, error) {
+	if err := noGroup(did); err != nil {
+		return 0, err
+	}
 	s := spec(model)
 	if s.SiidLight == 0 || s.PiidBrightness == 0 {
 		return 0, fmt.Errorf("ctrl: model %s has no brightness", model)
 	}
-	vals, err := c.API.GetProps(did, [][2]int{{s.SiidLight, s.PiidBrightness}})
-	if err != nil || len(vals) == 0 {
-		return 0, err
-	}
-	switch v := vals[0].(type) {
-	case float64:
-		return int(v), nil
-	case int:
-		return v, nil
-	}
-	return 0, nil
+	return base{c.API, did}.getInt(ctx, s.SiidLight, s.PiidBrightness)
 }
 
 // TTS 音箱 TTS 播报。
 func (c *Controller) TTS(did, model, text string) error {
+	return c.TTSContext(context.Background(), did, model, text)
+}
+
+// TTSContext 同 TTS，支持 ctx 取消。
+func (c *Controller) TTSContext(ctx context.Context, did, model, text string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.TTSContext(ctx, did, model, text)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidVoiceAssistant == 0 || s.AiidExecuteText == 0 {
 		return fmt.Errorf("ctrl: model %s has no TTS", model)
 	}
-	_, err := c.API.Action(did, s.SiidVoiceAssistant, s.AiidExecuteText, []interface{}{text})
+	_, err := c.API.ActionContext(ctx, did, s.SiidVoiceAssistant, s.AiidExecuteText, []interface{}{text})
 	return err
 }
 
 // SetVolume 设置音量 0-100。
 func (c *Controller) SetVolume(did, model string, level int) error {
+	return c.SetVolumeContext(context.Background(), did, model, level)
+}
+
+// SetVolumeContext 同 SetVolume，支持 ctx 取消。
+func (c *Controller) SetVolumeContext(ctx context.Context, did, model string, level int) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.SetVolumeContext(ctx, did, model, level)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidSpeaker == 0 || s.PiidVolume == 0 {
 		return fmt.Errorf("ctrl: model %s has no volume", model)
@@ -129,112 +205,179 @@ func (c *Controller) SetVolume(did, model string, level int) error {
 	if level > 100 {
 		level = 100
 	}
-	_, err := c.API.SetProps(did, [][3]interface{}{{s.SiidSpeaker, s.PiidVolume, level}})
+	_, err := c.API.SetPropsContext(ctx, did, [][3]interface{}{{s.SiidSpeaker, s.PiidVolume, level}})
 	return err
 }
 
 // GetVolume 获取音量。
 func (c *Controller) GetVolume(did, model string) (int, error) {
+	return c.GetVolumeContext(context.Background(), did, model)
+}
+
+// GetVolumeContext 同 GetVolume，支持 ctx 取消。
+func (c *Controller) GetVolumeContext(ctx context.Context, did, model string) (int, error) {
+	if err := noGroup(did); err != nil {
+		return 0, err
+	}
 	s := spec(model)
 	if s.SiidSpeaker == 0 || s.PiidVolume == 0 {
 		return 0, fmt.Errorf("ctrl: model %s has no volume", model)
 	}
-	vals, err := c.API.GetProps(did, [][2]int{{s.SiidSpeaker, s.PiidVolume}})
-	if err != nil || len(vals) == 0 {
-		return 0, err
-	}
-	switch v := vals[0].(type) {
-	case float64:
-		return int(v), nil
-	case int:
-		return v, nil
-	}
-	return 0, nil
+	return base{c.API, did}.getInt(ctx, s.SiidSpeaker, s.PiidVolume)
 }
 
 // SetMute 设置静音。
 func (c *Controller) SetMute(did, model string, mute bool) error {
+	return c.SetMuteContext(context.Background(), did, model, mute)
+}
+
+// SetMuteContext 同 SetMute，支持 ctx 取消。
+func (c *Controller) SetMuteContext(ctx context.Context, did, model string, mute bool) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.SetMuteContext(ctx, did, model, mute)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidSpeaker == 0 || s.PiidMute == 0 {
 		return fmt.Errorf("ctrl: model %s has no mute", model)
 	}
-	_, err := c.API.SetProps(did, [][3]interface{}{{s.SiidSpeaker, s.PiidMute, mute}})
+	_, err := c.API.SetPropsContext(ctx, did, [][3]interface{}{{s.SiidSpeaker, s.PiidMute, mute}})
 	return err
 }
 
 // GetMute 获取静音状态。
 func (c *Controller) GetMute(did, model string) (bool, error) {
+	return c.GetMuteContext(context.Background(), did, model)
+}
+
+// GetMuteContext 同 GetMute，支持 ctx 取消。
+func (c *Controller) GetMuteContext(ctx context.Context, did, model string) (bool, error) {
+	if err := noGroup(did); err != nil {
+		return false, err
+	}
 	s := spec(model)
 	if s.SiidSpeaker == 0 || s.PiidMute == 0 {
 		return false, fmt.Errorf("ctrl: model %s has no mute", model)
 	}
-	vals, err := c.API.GetProps(did, [][2]int{{s.SiidSpeaker, s.PiidMute}})
-	if err != nil || len(vals) == 0 {
-		return false, err
-	}
-	if b, ok := vals[0].(bool); ok {
-		return b, nil
-	}
-	return false, nil
+	return base{c.API, did}.getBool(ctx, s.SiidSpeaker, s.PiidMute)
 }
 
 // Play 播放。
 func (c *Controller) Play(did, model string) error {
+	return c.PlayContext(context.Background(), did, model)
+}
+
+// PlayContext 同 Play，支持 ctx 取消。
+func (c *Controller) PlayContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.PlayContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidPlayControl == 0 || s.AiidPlay == 0 {
 		return fmt.Errorf("ctrl: model %s has no play action", model)
 	}
-	_, err := c.API.Action(did, s.SiidPlayControl, s.AiidPlay, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidPlayControl, s.AiidPlay, nil)
 	return err
 }
 
 // Pause 暂停。
 func (c *Controller) Pause(did, model string) error {
+	return c.PauseContext(context.Background(), did, model)
+}
+
+// PauseContext 同 Pause，支持 ctx 取消。
+func (c *Controller) PauseContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.PauseContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidPlayControl == 0 || s.AiidPause == 0 {
 		return fmt.Errorf("ctrl: model %s has no pause action", model)
 	}
-	_, err := c.API.Action(did, s.SiidPlayControl, s.AiidPause, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidPlayControl, s.AiidPause, nil)
 	return err
 }
 
 // Next 下一曲。
 func (c *Controller) Next(did, model string) error {
+	return c.NextContext(context.Background(), did, model)
+}
+
+// NextContext 同 Next，支持 ctx 取消。
+func (c *Controller) NextContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.NextContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidPlayControl == 0 || s.AiidNext == 0 {
 		return fmt.Errorf("ctrl: model %s has no next action", model)
 	}
-	_, err := c.API.Action(did, s.SiidPlayControl, s.AiidNext, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidPlayControl, s.AiidNext, nil)
 	return err
 }
 
 // Previous 上一曲。
 func (c *Controller) Previous(did, model string) error {
+	return c.PreviousContext(context.Background(), did, model)
+}
+
+// PreviousContext 同 Previous，支持 ctx 取消。
+func (c *Controller) PreviousContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.PreviousContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidPlayControl == 0 || s.AiidPrevious == 0 {
 		return fmt.Errorf("ctrl: model %s has no previous action", model)
 	}
-	_, err := c.API.Action(did, s.SiidPlayControl, s.AiidPrevious, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidPlayControl, s.AiidPrevious, nil)
 	return err
 }
 
 // TVTurnOff 电视关机。
 func (c *Controller) TVTurnOff(did, model string) error {
+	return c.TVTurnOffContext(context.Background(), did, model)
+}
+
+// TVTurnOffContext 同 TVTurnOff，支持 ctx 取消。
+func (c *Controller) TVTurnOffContext(ctx context.Context, did, model string) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.TVTurnOffContext(ctx, did, model)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if s.SiidTV == 0 || s.AiidTurnOff == 0 {
 		return fmt.Errorf("ctrl: model %s has no turn off action", model)
 	}
-	_, err := c.API.Action(did, s.SiidTV, s.AiidTurnOff, nil)
+	_, err := c.API.ActionContext(ctx, did, s.SiidTV, s.AiidTurnOff, nil)
 	return err
 }
 
 // GetOccupancy 获取 occupancy 状态。
 func (c *Controller) GetOccupancy(did, model string) (interface{}, error) {
+	return c.GetOccupancyContext(context.Background(), did, model)
+}
+
+// GetOccupancyContext 同 GetOccupancy，支持 ctx 取消。
+func (c *Controller) GetOccupancyContext(ctx context.Context, did, model string) (interface{}, error) {
+	if err := noGroup(did); err != nil {
+		return nil, err
+	}
 	s := spec(model)
 	if s.SiidOccupancy == 0 || s.PiidStatus == 0 {
 		return nil, fmt.Errorf("ctrl: model %s has no occupancy", model)
 	}
-	vals, err := c.API.GetProps(did, [][2]int{{s.SiidOccupancy, s.PiidStatus}})
+	vals, err := c.API.GetPropsContext(ctx, did, [][2]int{{s.SiidOccupancy, s.PiidStatus}})
 	if err != nil || len(vals) == 0 {
 		return nil, err
 	}
@@ -243,14 +386,24 @@ func (c *Controller) GetOccupancy(did, model string) (interface{}, error) {
 
 // SetSwitchChannel 多通道开关指定通道。
 func (c *Controller) SetSwitchChannel(did, model string, channel int, on bool) error {
+	return c.SetSwitchChannelContext(context.Background(), did, model, channel, on)
+}
+
+// SetSwitchChannelContext 同 SetSwitchChannel，支持 ctx 取消。
+func (c *Controller) SetSwitchChannelContext(ctx context.Context, did, model string, channel int, on bool) error {
+	if ok, err := c.forGroup(ctx, did, func(ctx context.Context, did, model string) error {
+		return c.SetSwitchChannelContext(ctx, did, model, channel, on)
+	}); ok {
+		return err
+	}
 	s := spec(model)
 	if len(s.SwitchChannels) == 0 {
-		return c.SetOn(did, model, on)
+		return c.SetOnContext(ctx, did, model, on)
 	}
 	if channel < 0 || channel >= len(s.SwitchChannels) {
 		return fmt.Errorf("ctrl: channel %d out of range [0,%d)", channel, len(s.SwitchChannels))
 	}
 	siid := s.SwitchChannels[channel]
-	_, err := c.API.SetProps(did, [][3]interface{}{{siid, s.PiidOn, on}})
+	_, err := c.API.SetPropsContext(ctx, did, [][3]interface{}{{siid, s.PiidOn, on}})
 	return err
 }
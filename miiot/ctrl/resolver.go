@@ -1,6 +1,8 @@
 package ctrl
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -13,7 +15,7 @@ var (
 )
 
 // ResolveSpec 根据 model 从 miot-spec.org 解析规格，映射为 Spec。
-// 先查 model->URN，再拉取 instance，按服务/属性/动作描述匹配标准能力。
+// 先查 model->URN，再拉取 instance，按服务/属性/动作的标准类型 URN 匹配能力，厂商自定义类型回退到描述匹配。
 func ResolveSpec(model string) (Spec, error) {
 	resolveCacheMu.RLock()
 	if s, ok := resolveCache[model]; ok {
@@ -44,156 +46,208 @@ func ResolveSpec(model string) (Spec, error) {
 	return s, nil
 }
 
-func parseInstanceToSpec(m map[string]interface{}) (Spec, error) {
+// SpecFromInstance 将 miot-spec.org instance JSON 映射为 Spec，规则同 ResolveSpec。
+func SpecFromInstance(m map[string]interface{}) (Spec, error) {
+	return parseInstanceToSpec(m)
+}
+
+// Match 记录 Spec 的一个字段由哪条规则解析得到。
+type Match struct {
+	Field string `json:"field"` // 如 SiidLight、PiidBrightness
+	IID   string `json:"iid"`   // 如 2、2.3
+	Rule  string `json:"rule"`  // 如 type service:light、description "main light"
+}
+
+// ExplainInstance 同 SpecFromInstance，并返回各字段匹配的规则。
+func ExplainInstance(m map[string]interface{}) (Spec, []Match, error) {
 	s := Spec{}
-	svcs, _ := m["services"].([]interface{})
-	for _, x := range svcs {
+	var matches []Match
+	v := reflect.ValueOf(&s).Elem()
+	set := func(field, iid, rule string, id int) {
+		if f := v.FieldByName(field); f.Int() == 0 {
+			f.SetInt(int64(id))
+			matches = append(matches, Match{Field: field, IID: iid, Rule: rule})
+		}
+	}
+	for _, x := range toSlice(m["services"]) {
 		sm, ok := x.(map[string]interface{})
 		if !ok {
 			continue
 		}
-		desc := strings.ToLower(getStr(sm, "description"))
-		svcType := strings.ToLower(getStr(sm, "type"))
 		siid := int(getFloat(sm, "iid"))
-
-		// Switch / Outlet
-		if strings.Contains(desc, "switch") || desc == "outlet" {
-			if s.SiidSwitch == 0 {
-				s.SiidSwitch = siid
+		for _, r := range serviceRules {
+			rule, ok := r.match(sm, "service")
+			if !ok {
+				continue
 			}
-			for _, p := range toSlice(sm["properties"]) {
-				pm, _ := p.(map[string]interface{})
-				if pm == nil {
-					continue
-				}
-				pdesc := strings.ToLower(getStr(pm, "description"))
-				if strings.Contains(pdesc, "switch status") || pdesc == "on" {
-					s.PiidOn = int(getFloat(pm, "iid"))
-					break
-				}
+			set(r.field, fmt.Sprint(siid), rule, siid)
+			if v.FieldByName(r.field).Int() != int64(siid) {
+				// 同类服务已由前面的 siid 匹配，属性与动作不能跨服务取
+				continue
 			}
-			for _, a := range toSlice(sm["actions"]) {
-				am, _ := a.(map[string]interface{})
-				if am == nil {
-					continue
+			for _, e := range r.props {
+				if pm, rule, ok := e.find(toSlice(sm["properties"]), "property"); ok {
+					piid := int(getFloat(pm, "iid"))
+					set(e.field, fmt.Sprintf("%d.%d", siid, piid), rule, piid)
 				}
-				if strings.ToLower(getStr(am, "description")) == "toggle" {
-					s.AiidToggle = int(getFloat(am, "iid"))
-					break
-				}
-			}
-		}
-
-		// Light (排除 night-light 等)
-		if strings.Contains(desc, "light") && !strings.Contains(desc, "night") {
-			if s.SiidLight == 0 {
-				s.SiidLight = siid
 			}
-			for _, p := range toSlice(sm["properties"]) {
-				pm, _ := p.(map[string]interface{})
-				if pm == nil {
-					continue
-				}
-				pdesc := strings.ToLower(getStr(pm, "description"))
-				if (pdesc == "on" || strings.Contains(pdesc, "switch status")) && s.PiidOn == 0 {
-					s.PiidOn = int(getFloat(pm, "iid"))
-				}
-				if strings.Contains(pdesc, "brightness") {
-					s.PiidBrightness = int(getFloat(pm, "iid"))
+			for _, e := range r.actions {
+				if am, rule, ok := e.find(toSlice(sm["actions"]), "action"); ok {
+					aiid := int(getFloat(am, "iid"))
+					set(e.field, fmt.Sprintf("%d.%d", siid, aiid), rule, aiid)
 				}
 			}
 		}
+	}
+	return s, matches, nil
+}
 
-		// Speaker
-		if desc == "speaker" {
-			s.SiidSpeaker = siid
-			for _, p := range toSlice(sm["properties"]) {
-				pm, _ := p.(map[string]interface{})
-				if pm == nil {
-					continue
-				}
-				pdesc := strings.ToLower(getStr(pm, "description"))
-				if pdesc == "volume" {
-					s.PiidVolume = int(getFloat(pm, "iid"))
-				}
-				if pdesc == "mute" {
-					s.PiidMute = int(getFloat(pm, "iid"))
-				}
-			}
-		}
+// ExplainSpec 按 model 获取 SPEC 并执行 ExplainInstance，不使用 ResolveSpec 的缓存。
+func ExplainSpec(model string) (Spec, []Match, error) {
+	urn, err := specs.URNWithScrape(model)
+	if err != nil {
+		return Spec{}, nil, err
+	}
+	raw, err := specs.FetchInstance(urn)
+	if err != nil {
+		return Spec{}, nil, err
+	}
+	return ExplainInstance(raw)
+}
 
-		// Play Control
-		if strings.Contains(desc, "play control") {
-			s.SiidPlayControl = siid
-			for _, a := range toSlice(sm["actions"]) {
-				am, _ := a.(map[string]interface{})
-				if am == nil {
-					continue
-				}
-				adesc := strings.ToLower(getStr(am, "description"))
-				aiid := int(getFloat(am, "iid"))
-				switch adesc {
-				case "play":
-					s.AiidPlay = aiid
-				case "pause":
-					s.AiidPause = aiid
-				case "next":
-					s.AiidNext = aiid
-				case "previous":
-					s.AiidPrevious = aiid
-				}
-			}
-		}
+func parseInstanceToSpec(m map[string]interface{}) (Spec, error) {
+	s, _, err := ExplainInstance(m)
+	return s, err
+}
 
-		// Intelligent Speaker / Voice Assistant (TTS)
-		if strings.Contains(desc, "intelligent") || strings.Contains(desc, "voice") {
-			s.SiidVoiceAssistant = siid
-			for _, a := range toSlice(sm["actions"]) {
-				am, _ := a.(map[string]interface{})
-				if am == nil {
-					continue
-				}
-				adesc := strings.ToLower(getStr(am, "description"))
-				if strings.Contains(adesc, "play text") || strings.Contains(adesc, "execute text") {
-					s.AiidExecuteText = int(getFloat(am, "iid"))
-					break
-				}
-			}
-		}
+// rule 按标准类型 URN 的名称（urn:miot-spec-v2:<kind>:<名称>:…）匹配；
+// 只有厂商自定义类型（非 miot-spec-v2）才回退到小写描述匹配 desc。
+type rule struct {
+	field string
+	types []string
+	desc  func(d string) bool
+}
 
-		// TV (television / tv-switch)
-		if strings.Contains(desc, "television") || strings.Contains(desc, "tv") {
-			s.SiidTV = siid
-			for _, a := range toSlice(sm["actions"]) {
-				am, _ := a.(map[string]interface{})
-				if am == nil {
-					continue
-				}
-				adesc := strings.ToLower(getStr(am, "description"))
-				if adesc == "turn off" || adesc == "tv-switchon" {
-					s.AiidTurnOff = int(getFloat(am, "iid"))
-					break
-				}
+// serviceRule 为一种服务能力及其属性、动作。字段已有值时不覆盖（先匹配者优先）。
+type serviceRule struct {
+	rule
+	props, actions []rule
+}
+
+var serviceRules = []serviceRule{
+	{rule{"SiidSwitch", []string{"switch"}, func(d string) bool { return strings.Contains(d, "switch") || d == "outlet" }},
+		[]rule{{"PiidOn", []string{"on"}, func(d string) bool { return strings.Contains(d, "switch status") || d == "on" }}},
+		[]rule{{"AiidToggle", []string{"toggle"}, equals("toggle")}}},
+	{rule{"SiidLight", []string{"light"}, func(d string) bool { return strings.Contains(d, "light") && !strings.Contains(d, "night") }},
+		[]rule{
+			{"PiidOn", []string{"on"}, func(d string) bool { return d == "on" || strings.Contains(d, "switch status") }},
+			{"PiidBrightness", []string{"brightness"}, func(d string) bool { return strings.Contains(d, "brightness") }},
+			{"PiidColorTemperature", []string{"color-temperature"}, func(d string) bool { return strings.Contains(d, "color temperature") }},
+			{"PiidColor", []string{"color"}, equals("color")},
+			{"PiidMode", []string{"mode"}, equals("mode")},
+		}, nil},
+	{rule{"SiidSpeaker", []string{"speaker"}, equals("speaker")},
+		[]rule{{"PiidVolume", []string{"volume"}, equals("volume")}, {"PiidMute", []string{"mute"}, equals("mute")}}, nil},
+	{rule{"SiidPlayControl", []string{"play-control"}, func(d string) bool { return strings.Contains(d, "play control") }},
+		nil, []rule{
+			{"AiidPlay", []string{"play"}, equals("play")},
+			{"AiidPause", []string{"pause"}, equals("pause")},
+			{"AiidNext", []string{"next"}, equals("next")},
+			{"AiidPrevious", []string{"previous"}, equals("previous")},
+		}},
+	{rule{"SiidVoiceAssistant", []string{"intelligent-speaker", "voice-assistant"}, func(d string) bool {
+		return strings.Contains(d, "intelligent") || strings.Contains(d, "voice")
+	}},
+		nil, []rule{{"AiidExecuteText", []string{"play-text", "execute-text-directive"}, func(d string) bool {
+			return strings.Contains(d, "play text") || strings.Contains(d, "execute text")
+		}}}},
+	{rule{"SiidTV", []string{"television"}, func(d string) bool { return strings.Contains(d, "television") || strings.Contains(d, "tv") }},
+		nil, []rule{{"AiidTurnOff", []string{"turn-off"}, func(d string) bool { return d == "turn off" || d == "tv-switchon" }}}},
+	{rule{"SiidOccupancy", []string{"occupancy-sensor"}, func(d string) bool { return strings.Contains(d, "occupancy") }},
+		[]rule{{"PiidStatus", []string{"occupancy-status"}, func(d string) bool { return strings.Contains(d, "occupancy") || d == "status" }}}, nil},
+	{rule{"SiidClimate", []string{"air-conditioner", "heater", "fan", "humidifier", "air-purifier"}, func(d string) bool {
+		return d == "air conditioner" || d == "heater" || d == "fan" || d == "humidifier" || d == "air purifier"
+	}},
+		[]rule{
+			{"PiidOn", []string{"on"}, func(d string) bool { return d == "on" || d == "switch status" }},
+			{"PiidClimateMode", []string{"mode"}, equals("mode")},
+			{"PiidTargetTemperature", []string{"target-temperature"}, equals("target temperature")},
+			{"PiidTargetHumidity", []string{"target-humidity"}, equals("target humidity")},
+		}, nil},
+	{rule{"SiidFanControl", []string{"fan-control", "fan", "humidifier", "air-purifier"}, func(d string) bool {
+		return d == "fan control" || d == "fan" || d == "humidifier" || d == "air purifier"
+	}},
+		[]rule{
+			{"PiidFanLevel", []string{"fan-level"}, equals("fan level")},
+			{"PiidOscillation", []string{"horizontal-swing", "vertical-swing"}, func(d string) bool { return strings.Contains(d, "swing") }},
+		}, nil},
+	{rule{"SiidFilter", []string{"filter"}, equals("filter")},
+		[]rule{{"PiidFilterLife", []string{"filter-life-level"}, equals("filter life level")}}, nil},
+	{rule{"SiidVacuum", []string{"vacuum"}, func(d string) bool { return strings.Contains(d, "vacuum") || d == "robot cleaner" }},
+		[]rule{
+			{"PiidVacuumStatus", []string{"status"}, equals("status")},
+			{"PiidVacuumFanLevel", []string{"fan-level", "mode"}, func(d string) bool { return d == "fan level" || d == "mode" }},
+		}, []rule{
+			{"AiidVacuumStart", []string{"start-sweep"}, func(d string) bool { return d == "start sweep" || d == "start cleaning" }},
+			{"AiidVacuumStop", []string{"stop-sweeping"}, func(d string) bool { return d == "stop sweeping" || d == "stop cleaning" }},
+			{"AiidVacuumPause", []string{"pause-sweeping", "pause"}, func(d string) bool { return strings.HasPrefix(d, "pause") }},
+			{"AiidVacuumRoom", []string{"start-room-sweep"}, func(d string) bool { return strings.Contains(d, "room") }},
+		}},
+	{rule{"SiidBattery", []string{"battery"}, equals("battery")},
+		[]rule{{"PiidBatteryLevel", []string{"battery-level"}, equals("battery level")}},
+		[]rule{{"AiidStartCharge", []string{"start-charge"}, func(d string) bool { return d == "start charge" || d == "back to charge" }}}},
+	{rule{"SiidEnvironment", []string{"environment"}, equals("environment")},
+		[]rule{
+			{"PiidTemperature", []string{"temperature"}, equals("temperature")},
+			{"PiidHumidity", []string{"relative-humidity"}, func(d string) bool { return strings.Contains(d, "humidity") }},
+			{"PiidPM25", []string{"pm2.5-density"}, func(d string) bool { return strings.Contains(d, "pm2.5") }},
+		}, nil},
+}
+
+func equals(s string) func(string) bool {
+	return func(d string) bool { return d == s }
+}
+
+// match 检查元素 x 是否符合规则，返回规则描述。
+func (r rule) match(x map[string]interface{}, kind string) (string, bool) {
+	typ := getStr(x, "type")
+	parts := strings.Split(typ, ":")
+	if len(parts) > 3 && parts[1] == "miot-spec-v2" {
+		for _, t := range r.types {
+			if parts[3] == t {
+				return "type " + kind + ":" + t, true
 			}
 		}
+		return "", false
+	}
+	desc := getStr(x, "description")
+	if r.desc != nil && r.desc(strings.ToLower(desc)) {
+		return fmt.Sprintf("description %q", desc), true
+	}
+	return "", false
+}
 
-		// Occupancy Sensor
-		if strings.Contains(desc, "occupancy") || strings.Contains(svcType, "occupancy") {
-			s.SiidOccupancy = siid
-			for _, p := range toSlice(sm["properties"]) {
-				pm, _ := p.(map[string]interface{})
-				if pm == nil {
-					continue
-				}
-				pdesc := strings.ToLower(getStr(pm, "description"))
-				if strings.Contains(pdesc, "occupancy") || pdesc == "status" {
-					s.PiidStatus = int(getFloat(pm, "iid"))
-					break
-				}
-			}
+// find 返回 xs 中第一个符合规则的元素；按类型匹配的优先于描述回退。
+func (r rule) find(xs []interface{}, kind string) (map[string]interface{}, string, bool) {
+	var fallback map[string]interface{}
+	var fallbackRule string
+	for _, x := range xs {
+		xm, _ := x.(map[string]interface{})
+		if xm == nil {
+			continue
+		}
+		rule, ok := r.match(xm, kind)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(rule, "type ") {
+			return xm, rule, true
+		}
+		if fallback == nil {
+			fallback, fallbackRule = xm, rule
 		}
 	}
-	return s, nil
+	return fallback, fallbackRule, fallback != nil
 }
 
 func getStr(m map[string]interface{}, k string) string {
@@ -1,6 +1,7 @@
 package ctrl
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -92,3 +93,111 @@ func TestSpec_DynamicFallback(t *testing.T) {
 		t.Error("l05b should have speaker spec")
 	}
 }
+
+func TestExplainInstance(t *testing.T) {
+	raw := map[string]interface{}{"services": []interface{}{
+		map[string]interface{}{"iid": 1.0, "type": "urn:miot-spec-v2:service:indicator-light:00007803:test:1", "description": "Indicator Light",
+			"properties": []interface{}{map[string]interface{}{"iid": 1.0, "type": "urn:miot-spec-v2:property:on:00000006:test:1", "description": "Switch Status"}}},
+		map[string]interface{}{"iid": 2.0, "type": "urn:miot-spec-v2:service:switch:0000780C:test:1", "description": "Outlet Switch",
+			"properties": []interface{}{map[string]interface{}{"iid": 1.0, "type": "urn:miot-spec-v2:property:on:00000006:test:1", "description": "Power"}},
+			"actions":    []interface{}{map[string]interface{}{"iid": 1.0, "type": "urn:miot-spec-v2:action:toggle:00002811:test:1", "description": "Toggle"}}},
+		map[string]interface{}{"iid": 3.0, "type": "urn:test-spec:service:main-light:00007801:test:1", "description": "Main Light",
+			"properties": []interface{}{
+				map[string]interface{}{"iid": 1.0, "type": "urn:test-spec:property:lightness:00000001:test:1", "description": "Brightness"},
+				map[string]interface{}{"iid": 2.0, "type": "urn:miot-spec-v2:property:brightness:0000000D:test:1", "description": "Dimmer"},
+			}},
+	}}
+	s, matches, err := ExplainInstance(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SiidSwitch != 2 || s.PiidOn != 1 || s.AiidToggle != 1 || s.SiidLight != 3 || s.PiidBrightness != 2 {
+		t.Errorf("spec = %+v", s)
+	}
+	want := []Match{
+		{"SiidSwitch", "2", "type service:switch"},
+		{"PiidOn", "2.1", "type property:on"},
+		{"AiidToggle", "2.1", "type action:toggle"},
+		{"SiidLight", "3", `description "Main Light"`},
+		{"PiidBrightness", "3.2", "type property:brightness"},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("matches = %+v", matches)
+	}
+	for i, w := range want {
+		if matches[i] != w {
+			t.Errorf("match %d = %+v, want %+v", i, matches[i], w)
+		}
+	}
+}
+
+func TestExplainInstance_Climate(t *testing.T) {
+	prop := func(iid float64, name string) interface{} {
+		return map[string]interface{}{"iid": iid, "type": "urn:miot-spec-v2:property:" + name + ":00000000:test:1"}
+	}
+	svc := func(iid float64, name string, props ...interface{}) interface{} {
+		return map[string]interface{}{"iid": iid, "type": "urn:miot-spec-v2:service:" + name + ":00000000:test:1", "properties": props}
+	}
+	// 空调：风速在 fan-control 服务；第二个 environment 服务不应覆盖第一个
+	raw := map[string]interface{}{"services": []interface{}{
+		svc(2, "air-conditioner", prop(1, "on"), prop(2, "mode"), prop(4, "target-temperature")),
+		svc(3, "fan-control", prop(2, "fan-level"), prop(4, "vertical-swing")),
+		svc(4, "environment", prop(7, "temperature")),
+		svc(5, "environment", prop(1, "relative-humidity")),
+		svc(6, "filter", prop(1, "filter-life-level")),
+	}}
+	s, _, err := ExplainInstance(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Spec{
+		SiidClimate: 2, PiidOn: 1, PiidClimateMode: 2, PiidTargetTemperature: 4,
+		SiidFanControl: 3, PiidFanLevel: 2, PiidOscillation: 4,
+		SiidEnvironment: 4, PiidTemperature: 7,
+		SiidFilter: 6, PiidFilterLife: 1,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("air conditioner:\n got %+v\nwant %+v", s, want)
+	}
+
+	// 净化器：风速在主服务
+	raw = map[string]interface{}{"services": []interface{}{
+		svc(2, "air-purifier", prop(2, "on"), prop(4, "fan-level"), prop(5, "mode")),
+		svc(3, "environment", prop(6, "pm2.5-density"), prop(7, "relative-humidity"), prop(8, "temperature")),
+	}}
+	s, _, _ = ExplainInstance(raw)
+	want = Spec{
+		SiidClimate: 2, PiidOn: 2, PiidClimateMode: 5, SiidFanControl: 2, PiidFanLevel: 4,
+		SiidEnvironment: 3, PiidPM25: 6, PiidHumidity: 7, PiidTemperature: 8,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("air purifier:\n got %+v\nwant %+v", s, want)
+	}
+}
+
+func TestExplainInstance_Vacuum(t *testing.T) {
+	elem := func(kind string, iid float64, name string) interface{} {
+		return map[string]interface{}{"iid": iid, "type": "urn:miot-spec-v2:" + kind + ":" + name + ":00000000:test:1"}
+	}
+	raw := map[string]interface{}{"services": []interface{}{
+		map[string]interface{}{"iid": 2.0, "type": "urn:miot-spec-v2:service:vacuum:00007810:test:1",
+			"properties": []interface{}{elem("property", 1, "status"), elem("property", 4, "mode")},
+			"actions": []interface{}{elem("action", 1, "start-sweep"), elem("action", 2, "stop-sweeping"),
+				elem("action", 3, "start-room-sweep"), elem("action", 4, "pause-sweeping")}},
+		map[string]interface{}{"iid": 3.0, "type": "urn:miot-spec-v2:service:battery:00007805:test:1",
+			"properties": []interface{}{elem("property", 1, "battery-level")},
+			"actions":    []interface{}{elem("action", 1, "start-charge")}},
+	}}
+	s, _, err := ExplainInstance(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Spec{
+		SiidVacuum: 2, PiidVacuumStatus: 1, PiidVacuumFanLevel: 4,
+		AiidVacuumStart: 1, AiidVacuumStop: 2, AiidVacuumRoom: 3, AiidVacuumPause: 4,
+		SiidBattery: 3, PiidBatteryLevel: 1, AiidStartCharge: 1,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("vacuum:\n got %+v\nwant %+v", s, want)
+	}
+}
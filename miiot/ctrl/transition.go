@@ -0,0 +1,98 @@
+package ctrl
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// TransitionInterval 为渐变时两次写入的间隔；过小会触发云端限流。
+var TransitionInterval = 500 * time.Millisecond
+
+// Transition 在 d 内把值从 from 逐步变到 to，约每 TransitionInterval 调用一次 set，最后一次在 d 时且为 to；
+// 取整后与上一次（初始为 from）相同的值不重复写入。d 不超过一个间隔时直接设置 to；ctx 取消时停在当前值并返回 ctx.Err()。
+func Transition(ctx context.Context, from, to int, d time.Duration, set func(ctx context.Context, v int) error) error {
+	last := from
+	return steps(ctx, d, func(ctx context.Context, f float64) error {
+		v := lerp(from, to, f)
+		if v == last {
+			return nil
+		}
+		last = v
+		return set(ctx, v)
+	})
+}
+
+// FadeBrightness 读取当前亮度后在 d 内渐变到 level。
+func FadeBrightness(ctx context.Context, dim Dimmable, level int, d time.Duration) error {
+	from, err := dim.Brightness(ctx)
+	if err != nil {
+		return err
+	}
+	return Transition(ctx, from, clamp(level, 0, 100), d, dim.SetBrightness)
+}
+
+// FadeColorTemperature 读取当前色温后在 d 内渐变到 kelvin（按 Range 截断）。
+func FadeColorTemperature(ctx context.Context, ct ColorTemperature, kelvin int, d time.Duration) error {
+	from, err := ct.ColorTemperature(ctx)
+	if err != nil {
+		return err
+	}
+	if min, max := ct.Range(); max > 0 {
+		kelvin = clamp(kelvin, min, max)
+	}
+	return Transition(ctx, from, kelvin, d, ct.SetColorTemperature)
+}
+
+// FadeRGB 读取当前颜色后在 d 内按 RGB 分量线性渐变到 (r, g, b)。
+func FadeRGB(ctx context.Context, c Color, r, g, b uint8, d time.Duration) error {
+	r0, g0, b0, err := c.RGB(ctx)
+	if err != nil {
+		return err
+	}
+	last := [3]uint8{r0, g0, b0}
+	return steps(ctx, d, func(ctx context.Context, f float64) error {
+		v := [3]uint8{uint8(lerp(int(r0), int(r), f)), uint8(lerp(int(g0), int(g), f)), uint8(lerp(int(b0), int(b), f))}
+		if v == last {
+			return nil
+		}
+		last = v
+		return c.SetRGB(ctx, v[0], v[1], v[2])
+	})
+}
+
+// steps 按 TransitionInterval 将 d 分为 n 段，第 i 段在开始后 i·d/n 时以进度 f = i/n 调用 set，
+// 最后一次（f = 1）在 d 时；n 为 1 时立即调用。
+func steps(ctx context.Context, d time.Duration, set func(ctx context.Context, f float64) error) error {
+	n := 1
+	if TransitionInterval > 0 && d > TransitionInterval {
+		n = int(d / TransitionInterval)
+	}
+	if n == 1 {
+		return set(ctx, 1)
+	}
+	start := time.Now()
+	t := time.NewTimer(d / time.Duration(n))
+	defer t.Stop()
+	for i := 1; i <= n; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := set(ctx, float64(i)/float64(n)); err != nil {
+			return err
+		}
+		if i < n {
+			t.Reset(time.Until(start.Add(d * time.Duration(i+1) / time.Duration(n))))
+		}
+	}
+	return nil
+}
+
+func lerp(from, to int, f float64) int {
+	return from + int(math.Round(float64(to-from)*f))
+}
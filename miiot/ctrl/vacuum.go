@@ -0,0 +1,168 @@
+package ctrl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// Vacuum 扫地机器人（vacuum 与 battery 服务）。吸力档位方法与 FanSpeed 相同，
+// 因此 As[FanSpeed] 同样可用；型号缺少的动作或属性返回错误。
+type Vacuum interface {
+	Capability
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	// Pause 需要 SPEC 有 pause-sweeping；mijia.vacuum.v2、roidmi.vacuum.v60 没有，返回 UnsupportedError。
+	Pause(ctx context.Context) error
+	// Dock 回充（battery 服务的 start-charge）。
+	Dock(ctx context.Context) error
+	// Status 返回状态值，含义见 Statuses（来自 SPEC value-list）。
+	Status(ctx context.Context) (int, error)
+	Statuses() []device.ValueItem
+	SetFanLevel(ctx context.Context, level int) error
+	FanLevel(ctx context.Context) (int, error)
+	FanLevels() []device.ValueItem
+	// Battery 返回电量 %。
+	Battery(ctx context.Context) (int, error)
+	// CleanRooms 按房间清扫，需要 SPEC 有 start-room-sweep 动作；房间 ID 见米家 App 地图。
+	CleanRooms(ctx context.Context, rooms []int) error
+	SupportsRooms() bool
+	// State 一次读取状态、吸力与电量。
+	State(ctx context.Context) (*VacuumState, error)
+}
+
+// VacuumState 为 Vacuum.State 的结果，缺少的读数为 0。
+type VacuumState struct {
+	Status     int    `json:"status"`
+	StatusText string `json:"status_text,omitempty"`
+	FanLevel   int    `json:"fan_level,omitempty"`
+	Battery    int    `json:"battery,omitempty"`
+	Rooms      bool   `json:"rooms"` // 是否支持按房间清扫
+}
+
+type vacuum struct {
+	base
+	siid, status, fan                 int
+	start, stop, pause, room          int
+	batterySiid, battery, startCharge int
+	statuses, fanLevels               []device.ValueItem
+}
+
+func newVacuum(b base, s Spec, ms *device.ModelSpec) *vacuum {
+	v := &vacuum{
+		base: b, siid: s.SiidVacuum, status: s.PiidVacuumStatus, fan: s.PiidVacuumFanLevel,
+		start: s.AiidVacuumStart, stop: s.AiidVacuumStop, pause: s.AiidVacuumPause, room: s.AiidVacuumRoom,
+		batterySiid: s.SiidBattery, battery: s.PiidBatteryLevel, startCharge: s.AiidStartCharge,
+	}
+	// 状态与档位描述按 API 的 locale 翻译
+	labels := func(piid int) []device.ValueItem {
+		p := specProp(ms, v.siid, piid)
+		if p == nil {
+			return nil
+		}
+		out := make([]device.ValueItem, len(p.ValueList))
+		for i, x := range p.ValueList {
+			out[i] = device.ValueItem{Value: x.Value, Description: ms.Text(specs.ValueKey(v.siid, piid, i), x.Description)}
+		}
+		return out
+	}
+	v.statuses, v.fanLevels = labels(v.status), labels(v.fan)
+	return v
+}
+
+func (v *vacuum) Name() string { return "vacuum" }
+
+// State 读取状态、吸力与电量，型号没有的读数跳过。
+func (v *vacuum) State(ctx context.Context) (*VacuumState, error) {
+	st := &VacuumState{Rooms: v.SupportsRooms()}
+	var err error
+	if st.Status, err = v.Status(ctx); err != nil {
+		return nil, err
+	}
+	for _, x := range v.statuses {
+		if x.Value == st.Status {
+			st.StatusText = x.Description
+		}
+	}
+	if v.fan != 0 {
+		if st.FanLevel, err = v.FanLevel(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if v.battery != 0 {
+		if st.Battery, err = v.Battery(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+func (v *vacuum) Start(ctx context.Context) error {
+	return v.action(ctx, v.siid, v.start, nil, "start-sweep")
+}
+
+func (v *vacuum) Stop(ctx context.Context) error {
+	return v.action(ctx, v.siid, v.stop, nil, "stop-sweeping")
+}
+
+func (v *vacuum) Pause(ctx context.Context) error {
+	return v.action(ctx, v.siid, v.pause, nil, "pause-sweeping")
+}
+
+func (v *vacuum) Dock(ctx context.Context) error {
+	return v.action(ctx, v.batterySiid, v.startCharge, nil, "start-charge")
+}
+
+func (v *vacuum) Statuses() []device.ValueItem { return v.statuses }
+
+func (v *vacuum) Status(ctx context.Context) (int, error) {
+	if v.status == 0 {
+		return 0, unsupported("ctrl: %s has no vacuum status", v.did)
+	}
+	return v.getInt(ctx, v.siid, v.status)
+}
+
+func (v *vacuum) FanLevels() []device.ValueItem { return v.fanLevels }
+
+func (v *vacuum) SetFanLevel(ctx context.Context, level int) error {
+	if v.fan == 0 {
+		return unsupported("ctrl: %s has no fan level", v.did)
+	}
+	if err := checkEnum(v.did, "fan level", v.fanLevels, level); err != nil {
+		return err
+	}
+	return v.set(ctx, v.siid, v.fan, level)
+}
+
+func (v *vacuum) FanLevel(ctx context.Context) (int, error) {
+	if v.fan == 0 {
+		return 0, unsupported("ctrl: %s has no fan level", v.did)
+	}
+	return v.getInt(ctx, v.siid, v.fan)
+}
+
+func (v *vacuum) Battery(ctx context.Context) (int, error) {
+	if v.battery == 0 {
+		return 0, unsupported("ctrl: %s has no battery level", v.did)
+	}
+	return v.getInt(ctx, v.batterySiid, v.battery)
+}
+
+func (v *vacuum) SupportsRooms() bool { return v.room != 0 }
+
+// CleanRooms 以逗号分隔的房间 ID 字符串作为 start-room-sweep 的参数。
+func (v *vacuum) CleanRooms(ctx context.Context, rooms []int) error {
+	if len(rooms) == 0 {
+		// 参数错误而非能力缺失，不用 UnsupportedError
+		return errors.New("ctrl: room ids required")
+	}
+	ids := make([]string, len(rooms))
+	for i, r := range rooms {
+		ids[i] = strconv.Itoa(r)
+	}
+	return v.action(ctx, v.siid, v.room, []interface{}{strings.Join(ids, ",")}, "start-room-sweep")
+}
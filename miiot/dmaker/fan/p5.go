@@ -0,0 +1,44 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:fan:0000A005:dmaker-p5:1
+
+package fan
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelP5 为 dmaker.fan.p5 的型号。
+const ModelP5 = "dmaker.fan.p5"
+
+// URNP5 为生成时使用的 SPEC 类型。
+const URNP5 = "urn:miot-spec-v2:device:fan:0000A005:dmaker-p5:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationP5                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerP5     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelP5            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberP5     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionP5 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Fan（fan，siid=2）
+const (
+	SiidFanP5                miiot.Siid = 2
+	PiidFanOnP5              miiot.Piid = 1 // Switch Status，bool，read/write/notify
+	PiidFanFanLevelP5        miiot.Piid = 2 // Fan Level，uint8，read/write/notify
+	PiidFanHorizontalSwingP5 miiot.Piid = 3 // Horizontal Swing，bool，read/write/notify
+	PiidFanModeP5            miiot.Piid = 4 // Mode，uint8，read/write/notify
+)
+
+// FanFanLevelP5 的取值（value-list）。
+const (
+	FanFanLevelLevel1P5 = 1 // Level1
+	FanFanLevelLevel2P5 = 2 // Level2
+	FanFanLevelLevel3P5 = 3 // Level3
+	FanFanLevelLevel4P5 = 4 // Level4
+)
+
+// FanModeP5 的取值（value-list）。
+const (
+	FanModeStraightWindP5 = 0 // Straight Wind
+	FanModeNaturalWindP5  = 1 // Natural Wind
+)
@@ -0,0 +1,31 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package fan
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedP5(t *testing.T) {
+	s, ok := ctrl.Specs[ModelP5]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelP5)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidClimate", s.SiidClimate, int(SiidFanP5)},
+		{"PiidClimateMode", s.PiidClimateMode, int(PiidFanModeP5)},
+		{"SiidFanControl", s.SiidFanControl, int(SiidFanP5)},
+		{"PiidFanLevel", s.PiidFanLevel, int(PiidFanFanLevelP5)},
+		{"PiidOscillation", s.PiidOscillation, int(PiidFanHorizontalSwingP5)},
+		{"PiidOn", s.PiidOn, int(PiidFanOnP5)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+// Package gen 根据 MIoT SPEC 生成 miiot/<vendor>/<category>/<model>.go 常量包，
+// 并更新 miiot.Models 与 ctrl.Specs，供 cmd/miiot-gen 使用。
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/miiot"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+// Header 为生成文件的首行，不含此行的文件视为手写，默认不覆盖。
+const Header = "// Code generated by miiot-gen; DO NOT EDIT."
+
+// Model 为一个待生成的型号。
+type Model struct {
+	Model string
+	URN   string
+	Spec  *device.ModelSpec
+	Ctrl  ctrl.Spec // 写入 ctrl.Specs 的能力映射
+
+	suffix string
+	idents map[[3]int]string // {kind, siid, iid} → 常量名，kind 见 kindXxx
+}
+
+const (
+	kindService = iota
+	kindProp
+	kindAction
+	kindEvent
+)
+
+// New 准备生成 model 的常量，Ctrl 由 ctrl.SpecFromInstance 的结果与多通道开关检测得到。
+func New(model, urn string, spec *device.ModelSpec, cs ctrl.Spec) (*Model, error) {
+	if miiot.ModelToPath(model) == "" {
+		return nil, fmt.Errorf("gen: invalid model %q (want vendor.category.suffix)", model)
+	}
+	var channels []int
+	for _, svc := range spec.Services {
+		if svc.Name() == "switch" {
+			channels = append(channels, svc.IID)
+		}
+	}
+	if len(channels) > 1 && len(cs.SwitchChannels) == 0 {
+		cs.SwitchChannels = channels
+	}
+	m := &Model{Model: model, URN: urn, Spec: spec, Ctrl: cs, suffix: Ident(model[strings.LastIndex(model, ".")+1:])}
+	m.assignIdents()
+	return m, nil
+}
+
+// Path 返回生成文件相对仓库根目录的路径，如 miiot/linp/sensor_occupy/hb01.go。
+func (m *Model) Path() string {
+	return filepath.Join("miiot", filepath.FromSlash(miiot.ModelToPath(m.Model)))
+}
+
+// TestPath 返回生成的测试文件路径，如 miiot/linp/sensor_occupy/hb01_gen_test.go。
+func (m *Model) TestPath() string {
+	return strings.TrimSuffix(m.Path(), ".go") + "_gen_test.go"
+}
+
+// Ident 将 URN 名称或描述转为导出标识符，如 occupancy-sensor → OccupancySensor。
+func Ident(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// assignIdents 为服务、属性、动作、事件分配常量名；同名服务（如多键开关）追加 siid。
+func (m *Model) assignIdents() {
+	m.idents = make(map[[3]int]string)
+	count := make(map[string]int)
+	for _, svc := range m.Spec.Services {
+		count[serviceIdent(&svc)]++
+	}
+	for i := range m.Spec.Services {
+		svc := &m.Spec.Services[i]
+		sname := serviceIdent(svc)
+		if count[sname] > 1 {
+			sname += strconv.Itoa(svc.IID)
+		}
+		m.idents[[3]int{kindService, svc.IID, 0}] = "Siid" + sname + m.suffix
+		used := make(map[string]bool)
+		name := func(kind int, prefix, urnName, desc string, iid int) {
+			n := Ident(urnName)
+			if n == "" {
+				n = Ident(desc)
+			}
+			if n == "" || used[prefix+n] {
+				n += strconv.Itoa(iid)
+			}
+			used[prefix+n] = true
+			m.idents[[3]int{kind, svc.IID, iid}] = prefix + sname + n + m.suffix
+		}
+		for _, p := range svc.Properties {
+			name(kindProp, "Piid", p.Name(), p.Description, p.IID)
+		}
+		for _, a := range svc.Actions {
+			name(kindAction, "Aiid", a.Name(), a.Description, a.IID)
+		}
+		for _, e := range svc.Events {
+			name(kindEvent, "Eiid", e.Name(), e.Description, e.IID)
+		}
+	}
+}
+
+func serviceIdent(svc *device.ServiceSpec) string {
+	if n := Ident(svc.Name()); n != "" {
+		return n
+	}
+	if n := Ident(svc.Description); n != "" {
+		return n
+	}
+	return "Service" + strconv.Itoa(svc.IID)
+}
+
+// PackageName 返回目录对应的包名：已有 .go 文件时沿用其包名，否则取目录名（关键字追加 _，如 switch_）。
+func PackageName(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	sort.Strings(files)
+	for _, f := range files {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		if data, err := os.ReadFile(f); err == nil {
+			if m := packageRE.FindSubmatch(data); m != nil {
+				return string(m[1])
+			}
+		}
+	}
+	name := strings.NewReplacer("-", "_", ".", "_").Replace(filepath.Base(dir))
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
+
+var packageRE = regexp.MustCompile(`(?m)^package (\w+)`)
+
+// Source 生成常量文件内容。
+func (m *Model) Source(pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n// Source: %s\n\npackage %s\n\n", Header, m.URN, pkg)
+	b.WriteString("import \"github.com/zeusro/miflow/miiot\"\n\n")
+	fmt.Fprintf(&b, "// Model%s 为 %s 的型号。\nconst Model%s = %q\n\n", m.suffix, m.Model, m.suffix, m.Model)
+	fmt.Fprintf(&b, "// URN%s 为生成时使用的 SPEC 类型。\nconst URN%s = %q\n", m.suffix, m.suffix, m.URN)
+	for _, svc := range m.Spec.Services {
+		fmt.Fprintf(&b, "\n// %s（%s，siid=%d）\nconst (\n", m.describe(specs.ServiceKey(svc.IID), svc.Description), svc.Name(), svc.IID)
+		fmt.Fprintf(&b, "\t%s miiot.Siid = %d\n", m.idents[[3]int{kindService, svc.IID, 0}], svc.IID)
+		for _, p := range svc.Properties {
+			fmt.Fprintf(&b, "\t%s miiot.Piid = %d // %s\n", m.idents[[3]int{kindProp, svc.IID, p.IID}], p.IID, m.propComment(svc.IID, &p))
+		}
+		for _, a := range svc.Actions {
+			fmt.Fprintf(&b, "\t%s miiot.Aiid = %d // %s%s\n", m.idents[[3]int{kindAction, svc.IID, a.IID}], a.IID, m.describe(specs.ActionKey(svc.IID, a.IID), a.Description), piids(" in", a.In)+piids(" out", a.Out))
+		}
+		for _, e := range svc.Events {
+			fmt.Fprintf(&b, "\t%s miiot.Eiid = %d // %s%s\n", m.idents[[3]int{kindEvent, svc.IID, e.IID}], e.IID, m.describe(specs.EventKey(svc.IID, e.IID), e.Description), piids(" arguments", e.Arguments))
+		}
+		b.WriteString(")\n")
+		for _, p := range svc.Properties {
+			if len(p.ValueList) == 0 {
+				continue
+			}
+			base := strings.TrimSuffix(strings.TrimPrefix(m.idents[[3]int{kindProp, svc.IID, p.IID}], "Piid"), m.suffix)
+			fmt.Fprintf(&b, "\n// %s%s 的取值（value-list）。\nconst (\n", base, m.suffix)
+			used := make(map[string]bool)
+			for i, it := range p.ValueList {
+				n := Ident(it.Description)
+				if n == "" || used[n] {
+					n = "Value" + strings.ReplaceAll(strconv.Itoa(it.Value), "-", "Minus")
+				}
+				used[n] = true
+				fmt.Fprintf(&b, "\t%s%s%s = %d // %s\n", base, n, m.suffix, it.Value, m.describe(specs.ValueKey(svc.IID, p.IID, i), it.Description))
+			}
+			b.WriteString(")\n")
+		}
+	}
+	return format.Source(b.Bytes())
+}
+
+func comment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// describe 返回注释用的描述；Spec 经 Localize 设置了翻译时附加在英文之后，如 Switch Status（开关状态）。
+func (m *Model) describe(key, english string) string {
+	if t := m.Spec.Text(key, english); t != english {
+		return comment(english) + "（" + comment(t) + "）"
+	}
+	return comment(english)
+}
+
+func (m *Model) propComment(siid int, p *device.PropSpec) string {
+	parts := []string{m.describe(specs.PropertyKey(siid, p.IID), p.Description), p.Format, strings.Join(p.Access, "/")}
+	if r := p.ValueRange; r != nil {
+		parts = append(parts, fmt.Sprintf("[%v, %v] step %v", r.Min, r.Max, r.Step))
+	}
+	if p.Unit != "" {
+		parts = append(parts, p.Unit)
+	}
+	return strings.Join(parts, "，")
+}
+
+func piids(label string, ids []int) string {
+	if len(ids) == 0 {
+		return ""
+	}
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return label + " " + strings.Join(s, ",")
+}
+
+// ctrlFields 为 ctrl.Spec 中 iid 字段与其所属 siid 字段的对应关系，用于生成一致性测试。
+var ctrlFields = []struct {
+	field, siid string
+	kind        int
+}{
+	{"SiidSwitch", "", kindService},
+	{"AiidToggle", "SiidSwitch", kindAction},
+	{"SiidLight", "", kindService},
+	{"PiidBrightness", "SiidLight", kindProp},
+	{"PiidColorTemperature", "SiidLight", kindProp},
+	{"PiidColor", "SiidLight", kindProp},
+	{"PiidMode", "SiidLight", kindProp},
+	{"SiidVoiceAssistant", "", kindService},
+	{"AiidExecuteText", "SiidVoiceAssistant", kindAction},
+	{"SiidSpeaker", "", kindService},
+	{"PiidVolume", "SiidSpeaker", kindProp},
+	{"PiidMute", "SiidSpeaker", kindProp},
+	{"SiidPlayControl", "", kindService},
+	{"AiidPlay", "SiidPlayControl", kindAction},
+	{"AiidPause", "SiidPlayControl", kindAction},
+	{"AiidNext", "SiidPlayControl", kindAction},
+	{"AiidPrevious", "SiidPlayControl", kindAction},
+	{"SiidTV", "", kindService},
+	{"AiidTurnOff", "SiidTV", kindAction},
+	{"SiidOccupancy", "", kindService},
+	{"PiidStatus", "SiidOccupancy", kindProp},
+	{"SiidClimate", "", kindService},
+	{"PiidClimateMode", "SiidClimate", kindProp},
+	{"PiidTargetTemperature", "SiidClimate", kindProp},
+	{"PiidTargetHumidity", "SiidClimate", kindProp},
+	{"SiidFanControl", "", kindService},
+	{"PiidFanLevel", "SiidFanControl", kindProp},
+	{"PiidOscillation", "SiidFanControl", kindProp},
+	{"SiidFilter", "", kindService},
+	{"PiidFilterLife", "SiidFilter", kindProp},
+	{"SiidEnvironment", "", kindService},
+	{"PiidTemperature", "SiidEnvironment", kindProp},
+	{"PiidHumidity", "SiidEnvironment", kindProp},
+	{"PiidPM25", "SiidEnvironment", kindProp},
+	{"SiidVacuum", "", kindService},
+	{"PiidVacuumStatus", "SiidVacuum", kindProp},
+	{"PiidVacuumFanLevel", "SiidVacuum", kindProp},
+	{"AiidVacuumStart", "SiidVacuum", kindAction},
+	{"AiidVacuumStop", "SiidVacuum", kindAction},
+	{"AiidVacuumPause", "SiidVacuum", kindAction},
+	{"AiidVacuumRoom", "SiidVacuum", kindAction},
+	{"SiidBattery", "", kindService},
+	{"PiidBatteryLevel", "SiidBattery", kindProp},
+	{"AiidStartCharge", "SiidBattery", kindAction},
+}
+
+// ctrlChecks 返回 ctrl.Spec 非零字段对应的 {字段, 常量名}。PiidOn 属于 ctrl.OnService。
+func (m *Model) ctrlChecks() [][2]string {
+	v := reflect.ValueOf(m.Ctrl)
+	get := func(f string) int { return int(v.FieldByName(f).Int()) }
+	var out [][2]string
+	add := func(field string, key [3]int) {
+		if id, ok := m.idents[key]; ok {
+			out = append(out, [2]string{field, id})
+		}
+	}
+	for _, f := range ctrlFields {
+		iid := get(f.field)
+		if iid == 0 {
+			continue
+		}
+		if f.kind == kindService {
+			add(f.field, [3]int{kindService, iid, 0})
+		} else {
+			add(f.field, [3]int{f.kind, get(f.siid), iid})
+		}
+	}
+	if on := get("PiidOn"); on != 0 {
+		add("PiidOn", [3]int{kindProp, ctrl.OnService(m.Ctrl), on})
+	}
+	return out
+}
+
+// HasCtrl 报告型号是否有 ctrl 能力；没有时不生成 ctrl.Specs 条目与测试。
+func (m *Model) HasCtrl() bool {
+	return !reflect.ValueOf(m.Ctrl).IsZero()
+}
+
+// TestSource 生成表驱动测试：检查常量与 ctrl.Specs 中的条目一致。没有 ctrl 能力时返回 nil。
+func (m *Model) TestSource(pkg string) ([]byte, error) {
+	if !m.HasCtrl() {
+		return nil, nil
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n\npackage %s\n\n", Header, pkg)
+	b.WriteString("import (\n\t\"testing\"\n\n\t\"github.com/zeusro/miflow/miiot/ctrl\"\n)\n\n")
+	fmt.Fprintf(&b, "func TestGenerated%s(t *testing.T) {\n", m.suffix)
+	fmt.Fprintf(&b, "\ts, ok := ctrl.Specs[Model%s]\n\tif !ok {\n\t\tt.Fatalf(\"%%s not in ctrl.Specs\", Model%s)\n\t}\n", m.suffix, m.suffix)
+	checks := m.ctrlChecks()
+	if len(checks) == 0 {
+		b.WriteString("\t_ = s\n}\n")
+		return format.Source(b.Bytes())
+	}
+	b.WriteString("\tfor _, tc := range []struct {\n\t\tname      string\n\t\tgot, want int\n\t}{\n")
+	for _, c := range checks {
+		fmt.Fprintf(&b, "\t\t{%q, s.%s, int(%s)},\n", c[0], c[0], c[1])
+	}
+	b.WriteString("\t} {\n\t\tif tc.got != tc.want {\n\t\t\tt.Errorf(\"%s: ctrl.Specs=%d, spec=%d\", tc.name, tc.got, tc.want)\n\t\t}\n\t}\n}\n")
+	return format.Source(b.Bytes())
+}
+
+// CtrlEntry 生成 ctrl.Specs 中的条目。
+func (m *Model) CtrlEntry() string {
+	return fmt.Sprintf("\t%q: %s,\n", m.Model, m.ctrlValue())
+}
+
+// ctrlValue 生成 ctrl.Spec 的复合字面量，没有字段时为 {}。
+func (m *Model) ctrlValue() string {
+	v := reflect.ValueOf(m.Ctrl)
+	t := v.Type()
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Int:
+			if f.Int() != 0 {
+				fields = append(fields, fmt.Sprintf("%s: %d", t.Field(i).Name, f.Int()))
+			}
+		case reflect.Slice:
+			if f.Len() > 0 {
+				ids := make([]string, f.Len())
+				for j := range ids {
+					ids[j] = strconv.FormatInt(f.Index(j).Int(), 10)
+				}
+				fields = append(fields, fmt.Sprintf("%s: []int{%s}", t.Field(i).Name, strings.Join(ids, ", ")))
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return "{}"
+	}
+	return fmt.Sprintf("{\n\t\t%s,\n\t}", strings.Join(fields, ", "))
+}
+
+// AddCtrlSpec 在 ctrl/constants.go 源码的 Specs 中加入或更新 m 的条目：已存在且与 m.Ctrl 一致时不改动并返回 false，
+// 不一致时替换为生成的条目。没有 ctrl 能力的型号不加入。
+func AddCtrlSpec(src []byte, m *Model) ([]byte, bool, error) {
+	if !m.HasCtrl() {
+		return src, false, nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "constants.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, false, err
+	}
+	lit := specsLiteral(f)
+	if lit == nil {
+		return nil, false, fmt.Errorf("gen: ctrl.Specs not found")
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.BasicLit)
+		if !ok || key.Kind != token.STRING || key.Value != strconv.Quote(m.Model) {
+			continue
+		}
+		if old, err := specFromLiteral(kv.Value); err == nil && reflect.DeepEqual(old, m.Ctrl) {
+			return src, false, nil
+		}
+		start, end := fset.Position(kv.Value.Pos()).Offset, fset.Position(kv.Value.End()).Offset
+		out := append(append(append([]byte{}, src[:start]...), m.ctrlValue()...), src[end:]...)
+		out, err := format.Source(out)
+		return out, err == nil, err
+	}
+	return insertBeforeClose(src, "var Specs = map[string]Spec{", "\t// Generated by miiot-gen\n"+m.CtrlEntry())
+}
+
+// specsLiteral 返回 var Specs = map[string]Spec{...} 的复合字面量。
+func specsLiteral(f *ast.File) *ast.CompositeLit {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok || len(vs.Names) != 1 || vs.Names[0].Name != "Specs" || len(vs.Values) != 1 {
+				continue
+			}
+			lit, _ := vs.Values[0].(*ast.CompositeLit)
+			return lit
+		}
+	}
+	return nil
+}
+
+// specFromLiteral 将 Specs 条目的字面量（字段值为整数或 []int{...}）还原为 ctrl.Spec。
+func specFromLiteral(e ast.Expr) (ctrl.Spec, error) {
+	var s ctrl.Spec
+	lit, ok := e.(*ast.CompositeLit)
+	if !ok {
+		return s, fmt.Errorf("gen: not a composite literal")
+	}
+	v := reflect.ValueOf(&s).Elem()
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return s, fmt.Errorf("gen: unkeyed field")
+		}
+		name, _ := kv.Key.(*ast.Ident)
+		if name == nil || !v.FieldByName(name.Name).IsValid() {
+			return s, fmt.Errorf("gen: unknown field %v", kv.Key)
+		}
+		f := v.FieldByName(name.Name)
+		switch x := kv.Value.(type) {
+		case *ast.BasicLit:
+			n, err := strconv.Atoi(x.Value)
+			if err != nil || f.Kind() != reflect.Int {
+				return s, fmt.Errorf("gen: %s: unsupported value %s", name.Name, x.Value)
+			}
+			f.SetInt(int64(n))
+		case *ast.CompositeLit:
+			var ids []int
+			for _, it := range x.Elts {
+				bl, ok := it.(*ast.BasicLit)
+				if !ok {
+					return s, fmt.Errorf("gen: %s: unsupported element", name.Name)
+				}
+				n, err := strconv.Atoi(bl.Value)
+				if err != nil {
+					return s, err
+				}
+				ids = append(ids, n)
+			}
+			if f.Type() != reflect.TypeOf(ids) {
+				return s, fmt.Errorf("gen: %s: unsupported value", name.Name)
+			}
+			f.Set(reflect.ValueOf(ids))
+		default:
+			return s, fmt.Errorf("gen: %s: unsupported value", name.Name)
+		}
+	}
+	return s, nil
+}
+
+// AddModel 在 miiot/registry.go 源码的 Models 中加入 model，已存在时返回 false。
+func AddModel(src []byte, model string) ([]byte, bool, error) {
+	if bytes.Contains(src, []byte(strconv.Quote(model))) {
+		return src, false, nil
+	}
+	return insertBeforeClose(src, "var Models = []string{", "\t"+strconv.Quote(model)+",\n")
+}
+
+// AddSeed 在离线种子 miiot/specs/fixture.json 中加入或更新 m 的 model→URN、instance 与翻译表（可为 nil），
+// 使新型号离线也可解析；内容不变时返回 false。
+func AddSeed(src []byte, m *Model, raw map[string]interface{}, tr specs.Translation) ([]byte, bool, error) {
+	var b specs.Bundle
+	if err := json.Unmarshal(src, &b); err != nil {
+		return nil, false, fmt.Errorf("gen: seed: %w", err)
+	}
+	if b.Instances == nil {
+		b.Instances = make(map[string]string)
+	}
+	if b.Specs == nil {
+		b.Specs = make(map[string]map[string]interface{})
+	}
+	b.Instances[m.Model] = m.URN
+	b.Specs[m.URN] = raw
+	if len(tr) > 0 {
+		if b.Translations == nil {
+			b.Translations = make(map[string]specs.Translation)
+		}
+		b.Translations[m.URN] = tr
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return nil, false, err
+	}
+	return out.Bytes(), !bytes.Equal(out.Bytes(), src), nil
+}
+
+// insertBeforeClose 在 decl 开始的复合字面量的右花括号前插入 text 并格式化。
+func insertBeforeClose(src []byte, decl, text string) ([]byte, bool, error) {
+	start := bytes.Index(src, []byte(decl))
+	if start < 0 {
+		return nil, false, fmt.Errorf("gen: %q not found", decl)
+	}
+	end := bytes.Index(src[start:], []byte("\n}\n"))
+	if end < 0 {
+		return nil, false, fmt.Errorf("gen: end of %q not found", decl)
+	}
+	at := start + end + 1
+	out := append(append(append([]byte{}, src[:at]...), text...), src[at:]...)
+	out, err := format.Source(out)
+	return out, err == nil, err
+}
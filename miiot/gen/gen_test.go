@@ -0,0 +1,183 @@
+package gen
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
+)
+
+func loadModel(t *testing.T, model string) *Model {
+	t.Helper()
+	data, err := os.ReadFile("testdata/lemesh.switch.sw3f13.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := device.ParseModelSpec(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs, err := ctrl.SpecFromInstance(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(model, raw["type"].(string), spec, cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSource(t *testing.T) {
+	m := loadModel(t, "lemesh.switch.sw3f13")
+	if m.Path() != "miiot/lemesh/switch/sw3f13.go" || m.TestPath() != "miiot/lemesh/switch/sw3f13_gen_test.go" {
+		t.Errorf("paths: %s %s", m.Path(), m.TestPath())
+	}
+	src, err := m.Source("switch_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		Header,
+		"package switch_",
+		`const ModelSw3f13 = "lemesh.switch.sw3f13"`,
+		"SiidDeviceInformationSw3f13             miiot.Siid = 1",
+		"SiidSwitch2Sw3f13       miiot.Siid = 2",
+		"PiidSwitch3OnSw3f13     miiot.Piid = 1 // Switch Status，bool，read/write/notify",
+		"AiidSwitch4ToggleSw3f13 miiot.Aiid = 1 // Toggle",
+		"Switch2ModeNormalSwitchSw3f13   = 1 // Normal Switch",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("source missing %q:\n%s", want, src)
+		}
+	}
+	test, err := m.TestSource("switch_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(test), `{"PiidOn", s.PiidOn, int(PiidSwitch2OnSw3f13)},`) {
+		t.Errorf("test source:\n%s", test)
+	}
+	if want := "SiidSwitch: 2, PiidOn: 1, AiidToggle: 1, SwitchChannels: []int{2, 3, 4},"; !strings.Contains(m.CtrlEntry(), want) {
+		t.Errorf("ctrl entry: %s", m.CtrlEntry())
+	}
+}
+
+func TestSourceLocalized(t *testing.T) {
+	m := loadModel(t, "lemesh.switch.sw3f13")
+	m.Spec.Localize(specs.Translation{"zh_cn": {
+		specs.ServiceKey(2):     "左键",
+		specs.PropertyKey(2, 1): "开关状态",
+		specs.ValueKey(2, 2, 1): "普通开关",
+	}}, "zh_CN")
+	src, err := m.Source("switch_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Left Switch Service（左键）（switch，siid=2）",
+		"PiidSwitch2OnSw3f13     miiot.Piid = 1 // Switch Status（开关状态），bool，read/write/notify",
+		"Switch2ModeNormalSwitchSw3f13   = 1 // Normal Switch（普通开关）",
+		"PiidSwitch3OnSw3f13     miiot.Piid = 1 // Switch Status，bool，read/write/notify",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("source missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestUpdateRegistries(t *testing.T) {
+	reg, err := os.ReadFile("../registry.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err := AddModel(reg, "lemesh.switch.sw3f13"); err != nil || changed {
+		t.Errorf("existing model: changed=%v err=%v", changed, err)
+	}
+	out, changed, err := AddModel(reg, "vendor.light.new1")
+	if err != nil || !changed || !strings.Contains(string(out), "\t\"vendor.light.new1\",\n}") {
+		t.Errorf("AddModel: changed=%v err=%v", changed, err)
+	}
+
+	consts, err := os.ReadFile("../ctrl/constants.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err := AddCtrlSpec(consts, loadModel(t, "lemesh.switch.sw3f13")); err != nil || changed {
+		t.Errorf("unchanged ctrl.Specs entry should be kept: changed=%v err=%v", changed, err)
+	}
+	// 与 SPEC 不一致的旧条目被替换
+	out, changed, err = AddCtrlSpec(consts, loadModel(t, "bean.switch.bln31"))
+	if err != nil || !changed || !strings.Contains(string(out), "\"bean.switch.bln31\": {\n\t\tSiidSwitch: 2, PiidOn: 1, AiidToggle: 1, SwitchChannels: []int{2, 3, 4},\n\t},") {
+		t.Errorf("stale entry: changed=%v err=%v", changed, err)
+	}
+	if strings.Count(string(out), "\"bean.switch.bln31\"") != 1 {
+		t.Error("stale entry should be replaced, not duplicated")
+	}
+	// 没有 ctrl 能力的型号不写入 Specs，也不生成测试
+	empty := loadModel(t, "vendor.sensor.none1")
+	empty.Ctrl = ctrl.Spec{}
+	if _, changed, err := AddCtrlSpec(consts, empty); err != nil || changed {
+		t.Errorf("empty ctrl: changed=%v err=%v", changed, err)
+	}
+	if test, err := empty.TestSource("none1"); err != nil || test != nil {
+		t.Errorf("empty ctrl TestSource = %q, %v", test, err)
+	}
+	if got := empty.CtrlEntry(); got != "\t\"vendor.sensor.none1\": {},\n" {
+		t.Errorf("empty CtrlEntry = %q", got)
+	}
+	out, changed, err = AddCtrlSpec(consts, loadModel(t, "vendor.switch.new3"))
+	if err != nil || !changed || !strings.Contains(string(out), "\"vendor.switch.new3\": {\n\t\tSiidSwitch: 2,") {
+		t.Errorf("AddCtrlSpec: changed=%v err=%v\n%s", changed, err, out)
+	}
+}
+
+func TestAddSeed(t *testing.T) {
+	seed, err := os.ReadFile("../specs/fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := loadModel(t, "vendor.switch.new3")
+	raw := map[string]interface{}{"type": m.URN, "services": []interface{}{}}
+	tr := specs.Translation{"zh_cn": {"service:002": "开关"}}
+	out, changed, err := AddSeed(seed, m, raw, tr)
+	if err != nil || !changed {
+		t.Fatalf("AddSeed: changed=%v err=%v", changed, err)
+	}
+	var b specs.Bundle
+	if err := json.Unmarshal(out, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Instances["vendor.switch.new3"] != m.URN || b.Specs[m.URN] == nil || b.Translations[m.URN] == nil {
+		t.Errorf("seed entry missing: %v", b.Instances)
+	}
+	if b.Instances["xiaomi.wifispeaker.oh2"] == "" {
+		t.Error("existing seed entries should be kept")
+	}
+	// 已是生成格式的种子重复写入不变
+	if _, changed, _ := AddSeed(out, m, raw, tr); changed {
+		t.Error("AddSeed should be idempotent")
+	}
+}
+
+func TestIdent(t *testing.T) {
+	for in, want := range map[string]string{
+		"occupancy-sensor": "OccupancySensor",
+		"pm2.5-density":    "Pm25Density",
+		"No One":           "NoOne",
+		"关闭":               "",
+		"sensor_occupy":    "SensorOccupy",
+	} {
+		if got := Ident(in); got != want {
+			t.Errorf("Ident(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
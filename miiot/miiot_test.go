@@ -3,6 +3,10 @@ package miiot
 import (
 	"strings"
 	"testing"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
 )
 
 func TestModelToPath(t *testing.T) {
@@ -79,3 +83,44 @@ func TestGet(t *testing.T) {
 		t.Errorf("ProductURL = %s", prodURL)
 	}
 }
+
+func TestSeedResolvesModelsOffline(t *testing.T) {
+	// 空缓存目录 + 离线：只能来自内置种子
+	s := specs.NewStore(t.TempDir())
+	s.Offline = true
+	inst, err := s.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range Models {
+		urn, ok := inst[model]
+		if !ok {
+			t.Errorf("%s: not in seed instances", model)
+			continue
+		}
+		raw, err := s.Instance(urn)
+		if err != nil {
+			t.Errorf("%s: %v", model, err)
+			continue
+		}
+		if raw["type"] != urn {
+			t.Errorf("%s: instance type = %v, want %s", model, raw["type"], urn)
+		}
+		if tr, err := s.Translation(urn); err != nil || tr.Lookup("zh_CN", specs.ServiceKey(2)) == "" {
+			t.Errorf("%s: translation = %v, %v", model, tr, err)
+		}
+		spec, err := device.ParseModelSpec(raw)
+		if err != nil {
+			t.Errorf("%s: %v", model, err)
+			continue
+		}
+		if _, err := ctrl.SpecFromInstance(raw); err != nil {
+			t.Errorf("%s: %v", model, err)
+		}
+		if want, ok := ctrl.Specs[model]; ok {
+			if issues := ctrl.CheckSpec(want, spec); len(issues) != 0 {
+				t.Errorf("%s: ctrl.Specs disagrees with seed: %+v", model, issues)
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:vacuum:0000A006:mijia-v2:1
+
+package vacuum
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelV2 为 mijia.vacuum.v2 的型号。
+const ModelV2 = "mijia.vacuum.v2"
+
+// URNV2 为生成时使用的 SPEC 类型。
+const URNV2 = "urn:miot-spec-v2:device:vacuum:0000A006:mijia-v2:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationV2                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerV2     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelV2            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberV2     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionV2 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Robot Cleaner（vacuum，siid=2）
+const (
+	SiidVacuumV2             miiot.Siid = 2
+	PiidVacuumStatusV2       miiot.Piid = 1 // Status，uint8，read/notify
+	PiidVacuumFaultV2        miiot.Piid = 2 // Device Fault，uint8，read/notify
+	PiidVacuumModeV2         miiot.Piid = 6 // Mode，uint8，read/write/notify
+	AiidVacuumStartSweepV2   miiot.Aiid = 1 // Start Sweep
+	AiidVacuumStopSweepingV2 miiot.Aiid = 2 // Stop Sweeping
+)
+
+// VacuumStatusV2 的取值（value-list）。
+const (
+	VacuumStatusSweepingV2   = 1 // Sweeping
+	VacuumStatusIdleV2       = 2 // Idle
+	VacuumStatusPausedV2     = 3 // Paused
+	VacuumStatusErrorV2      = 4 // Error
+	VacuumStatusGoChargingV2 = 5 // Go Charging
+	VacuumStatusChargingV2   = 6 // Charging
+)
+
+// VacuumFaultV2 的取值（value-list）。
+const (
+	VacuumFaultNoFaultsV2       = 0 // No Faults
+	VacuumFaultLeftWheelErrorV2 = 1 // Left-wheel-error
+)
+
+// VacuumModeV2 的取值（value-list）。
+const (
+	VacuumModeSilentV2   = 0 // Silent
+	VacuumModeStandardV2 = 1 // Standard
+	VacuumModeMediumV2   = 2 // Medium
+	VacuumModeTurboV2    = 3 // Turbo
+)
+
+// Battery（battery，siid=3）
+const (
+	SiidBatteryV2              miiot.Siid = 3
+	PiidBatteryBatteryLevelV2  miiot.Piid = 1 // Battery Level，uint8，read/notify，[0, 100] step 1，percentage
+	PiidBatteryChargingStateV2 miiot.Piid = 2 // Charging State，uint8，read/notify
+	AiidBatteryStartChargeV2   miiot.Aiid = 1 // Start Charge
+)
+
+// BatteryChargingStateV2 的取值（value-list）。
+const (
+	BatteryChargingStateChargingV2      = 1 // Charging
+	BatteryChargingStateNotChargingV2   = 2 // Not Charging
+	BatteryChargingStateNotChargeableV2 = 3 // Not Chargeable
+)
@@ -0,0 +1,33 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package vacuum
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedV2(t *testing.T) {
+	s, ok := ctrl.Specs[ModelV2]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelV2)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidVacuum", s.SiidVacuum, int(SiidVacuumV2)},
+		{"PiidVacuumStatus", s.PiidVacuumStatus, int(PiidVacuumStatusV2)},
+		{"PiidVacuumFanLevel", s.PiidVacuumFanLevel, int(PiidVacuumModeV2)},
+		{"AiidVacuumStart", s.AiidVacuumStart, int(AiidVacuumStartSweepV2)},
+		{"AiidVacuumStop", s.AiidVacuumStop, int(AiidVacuumStopSweepingV2)},
+		{"SiidBattery", s.SiidBattery, int(SiidBatteryV2)},
+		{"PiidBatteryLevel", s.PiidBatteryLevel, int(PiidBatteryBatteryLevelV2)},
+		{"AiidStartCharge", s.AiidStartCharge, int(AiidBatteryStartChargeV2)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -4,8 +4,9 @@ const Model = "opple.light.bydceiling"
 
 // Light 服务 siid=2
 const (
-	SiidLight      = 2
-	PiidOn         = 1 // Switch Status
-	PiidMode       = 2 // Mode
-	PiidBrightness = 3
+	SiidLight            = 2
+	PiidOn               = 1 // Switch Status
+	PiidMode             = 2 // Mode
+	PiidBrightness       = 3
+	PiidColorTemperature = 4 // Color Temperature (K)
 )
@@ -11,4 +11,7 @@ func TestConstants(t *testing.T) {
 	if s.SiidLight != 2 || s.PiidOn != 1 || s.PiidBrightness != 3 {
 		t.Errorf("bydceiling: siid=%d on=%d brightness=%d", s.SiidLight, s.PiidOn, s.PiidBrightness)
 	}
+	if s.PiidMode != PiidMode || s.PiidColorTemperature != PiidColorTemperature {
+		t.Errorf("bydceiling: mode=%d color-temperature=%d", s.PiidMode, s.PiidColorTemperature)
+	}
 }
@@ -7,13 +7,15 @@ import (
 	"github.com/zeusro/miflow/miiot/specs"
 )
 
-// Models 为 m list 中所有型号，与 home.miot-spec.com 1:1 匹配。
+// Models 为 m list 中所有型号及有常量包的代表型号，与 home.miot-spec.com 1:1 匹配。
 var Models = []string{
 	"babai.plug.sk01a", "bean.switch.bln31", "bean.switch.bln33",
 	"chuangmi.plug.m3", "chuangmi.plug.v3", "giot.light.v5ssm",
 	"lemesh.switch.sw3f13", "linp.sensor_occupy.hb01",
 	"opple.light.bydceiling", "xiaomi.tv.eanfv1",
 	"xiaomi.wifispeaker.l05b", "xiaomi.wifispeaker.l05c", "xiaomi.wifispeaker.oh2",
+	"zhimi.airpurifier.ma4", "zhimi.humidifier.ca4", "zhimi.heater.mc2", "dmaker.fan.p5",
+	"mijia.vacuum.v2", "roidmi.vacuum.v60",
 }
 
 // ModelAPI 表示单个型号的规格 API。
@@ -0,0 +1,71 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:vacuum:0000A006:roidmi-v60:1
+
+package vacuum
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelV60 为 roidmi.vacuum.v60 的型号。
+const ModelV60 = "roidmi.vacuum.v60"
+
+// URNV60 为生成时使用的 SPEC 类型。
+const URNV60 = "urn:miot-spec-v2:device:vacuum:0000A006:roidmi-v60:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationV60                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerV60     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelV60            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberV60     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionV60 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Robot Cleaner（vacuum，siid=2）
+const (
+	SiidVacuumV60               miiot.Siid = 2
+	PiidVacuumStatusV60         miiot.Piid = 1  // Status，uint8，read/notify
+	PiidVacuumFaultV60          miiot.Piid = 2  // Device Fault，uint8，read/notify
+	PiidVacuumModeV60           miiot.Piid = 4  // Mode，uint8，read/write/notify
+	PiidVacuumRoomIdsV60        miiot.Piid = 10 // Room IDs，string，write
+	AiidVacuumStartSweepV60     miiot.Aiid = 1  // Start Sweep
+	AiidVacuumStopSweepingV60   miiot.Aiid = 2  // Stop Sweeping
+	AiidVacuumStartRoomSweepV60 miiot.Aiid = 3  // Start Room Sweep in 10
+)
+
+// VacuumStatusV60 的取值（value-list）。
+const (
+	VacuumStatusSweepingV60   = 1 // Sweeping
+	VacuumStatusIdleV60       = 2 // Idle
+	VacuumStatusPausedV60     = 3 // Paused
+	VacuumStatusErrorV60      = 4 // Error
+	VacuumStatusGoChargingV60 = 5 // Go Charging
+	VacuumStatusChargingV60   = 6 // Charging
+)
+
+// VacuumFaultV60 的取值（value-list）。
+const (
+	VacuumFaultNoFaultsV60 = 0 // No Faults
+)
+
+// VacuumModeV60 的取值（value-list）。
+const (
+	VacuumModeSilentV60    = 0 // Silent
+	VacuumModeBasicV60     = 1 // Basic
+	VacuumModeStrongV60    = 2 // Strong
+	VacuumModeFullSpeedV60 = 3 // Full Speed
+)
+
+// Battery（battery，siid=3）
+const (
+	SiidBatteryV60              miiot.Siid = 3
+	PiidBatteryBatteryLevelV60  miiot.Piid = 1 // Battery Level，uint8，read/notify，[0, 100] step 1，percentage
+	PiidBatteryChargingStateV60 miiot.Piid = 2 // Charging State，uint8，read/notify
+	AiidBatteryStartChargeV60   miiot.Aiid = 1 // Start Charge
+)
+
+// BatteryChargingStateV60 的取值（value-list）。
+const (
+	BatteryChargingStateChargingV60      = 1 // Charging
+	BatteryChargingStateNotChargingV60   = 2 // Not Charging
+	BatteryChargingStateNotChargeableV60 = 3 // Not Chargeable
+)
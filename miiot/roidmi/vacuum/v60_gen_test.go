@@ -0,0 +1,34 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package vacuum
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedV60(t *testing.T) {
+	s, ok := ctrl.Specs[ModelV60]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelV60)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidVacuum", s.SiidVacuum, int(SiidVacuumV60)},
+		{"PiidVacuumStatus", s.PiidVacuumStatus, int(PiidVacuumStatusV60)},
+		{"PiidVacuumFanLevel", s.PiidVacuumFanLevel, int(PiidVacuumModeV60)},
+		{"AiidVacuumStart", s.AiidVacuumStart, int(AiidVacuumStartSweepV60)},
+		{"AiidVacuumStop", s.AiidVacuumStop, int(AiidVacuumStopSweepingV60)},
+		{"AiidVacuumRoom", s.AiidVacuumRoom, int(AiidVacuumStartRoomSweepV60)},
+		{"SiidBattery", s.SiidBattery, int(SiidBatteryV60)},
+		{"PiidBatteryLevel", s.PiidBatteryLevel, int(PiidBatteryBatteryLevelV60)},
+		{"AiidStartCharge", s.AiidStartCharge, int(AiidBatteryStartChargeV60)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
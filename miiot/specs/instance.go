@@ -1,24 +1,15 @@
 package specs
 
-import (
-	"encoding/json"
-	"net/http"
-	"net/url"
-)
+import "context"
 
 const InstanceURL = "http://miot-spec.org/miot-spec-v2/instance"
 
-// FetchInstance 从 miot-spec.org 获取指定 URN 的完整规格 JSON（无需认证）。
+// FetchInstance 获取指定 URN 的完整规格 JSON（无需认证），经 Default 缓存，离线时只读缓存与种子。
 func FetchInstance(urn string) (map[string]interface{}, error) {
-	reqURL := InstanceURL + "?type=" + url.QueryEscape(urn)
-	resp, err := http.DefaultClient.Get(reqURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-	return result, nil
+	return Default().Instance(urn)
+}
+
+// FetchInstanceContext 同 FetchInstance，支持 ctx 取消。
+func FetchInstanceContext(ctx context.Context, urn string) (map[string]interface{}, error) {
+	return Default().InstanceContext(ctx, urn)
 }
@@ -1,15 +1,9 @@
 package specs
 
 import (
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"sync"
-
-	"github.com/zeusro/miflow/internal/config"
 )
 
 const (
@@ -24,7 +18,7 @@ var (
 	loadErr    error
 )
 
-// Load 从 miot-spec.org 加载 model->URN 映射，与 home.miot-spec.com 的规格链接一致。
+// Load 加载 model->URN 映射（经 Default 缓存），与 home.miot-spec.com 的规格链接一致。
 func Load() (map[string]string, error) {
 	loadOnce.Do(func() {
 		modelToURN, loadErr = loadInstances()
@@ -33,38 +27,7 @@ func Load() (map[string]string, error) {
 }
 
 func loadInstances() (map[string]string, error) {
-	cachePath := config.Get().MiIO.SpecsCachePath
-	if cachePath == "" {
-		cachePath = filepath.Join(os.TempDir(), "miservice_miot_specs.json")
-	}
-	if data, err := os.ReadFile(cachePath); err == nil {
-		var m map[string]string
-		if json.Unmarshal(data, &m) == nil && len(m) > 0 {
-			return m, nil
-		}
-	}
-	resp, err := http.DefaultClient.Get(InstancesURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	var inst struct {
-		Instances []struct {
-			Model string `json:"model"`
-			Type  string `json:"type"`
-		} `json:"instances"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&inst); err != nil {
-		return nil, err
-	}
-	m := make(map[string]string)
-	for _, i := range inst.Instances {
-		m[i.Model] = i.Type
-	}
-	if b, err := json.Marshal(m); err == nil {
-		os.WriteFile(cachePath, b, 0644)
-	}
-	return m, nil
+	return Default().Instances()
 }
 
 // SpecURL 返回 model 对应的 home.miot-spec.com 规格页 URL。
@@ -98,6 +61,9 @@ func URNWithScrape(model string) (string, error) {
 	}
 	urn, ok := m[model]
 	if !ok {
+		if Default().Offline {
+			return "", fmt.Errorf("%w: model %s", ErrOffline, model)
+		}
 		if scraped, err := ScrapeProductPage(model); err == nil && scraped[model] != "" {
 			return scraped[model], nil
 		}
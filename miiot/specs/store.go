@@ -0,0 +1,415 @@
+package specs
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+)
+
+// DefaultTTL 缓存的 SPEC 超过此时间后联网刷新，刷新失败时仍使用旧数据。
+const DefaultTTL = 7 * 24 * time.Hour
+
+// ErrOffline 表示离线模式下缓存与种子中均没有所需数据。
+var ErrOffline = errors.New("specs: not cached (offline)")
+
+// seedJSON 为内置种子，取自 fixture.json：miiot.Models 中型号的 model→URN 与翻译，以及手工精简的
+// instance，只保留 ctrl 能力与测试用到的服务、属性和取值（如音箱没有 siid 4 麦克风），不是完整 SPEC。
+// 离线时据此解析只能得到这些内容；需要完整离线数据时联网导出后导入缓存：
+//
+//	m spec export specs.json <model...> && m spec import specs.json
+//
+//go:embed fixture.json
+var seedJSON []byte
+
+// Bundle 为可导入/导出的 SPEC 包：model→URN 映射与按 URN（含版本后缀）的 instance JSON 及翻译表。
+type Bundle struct {
+	Instances    map[string]string                 `json:"instances"`
+	Specs        map[string]map[string]interface{} `json:"specs"`
+	Translations map[string]Translation            `json:"translations,omitempty"`
+}
+
+// Store 为本地 SPEC 缓存目录：instances.json 保存 model→URN，instance/<urn>.json 保存各版本 instance。
+// 查找顺序：未过期缓存 → 联网获取（Offline 时跳过）→ 过期缓存 → 内置种子。
+type Store struct {
+	Dir           string
+	InstancesPath string // model→URN 映射文件，默认 Dir/instances.json
+	TTL           time.Duration
+	Offline       bool
+	Client        *http.Client
+
+	mu   sync.Mutex
+	seed *Bundle
+}
+
+// NewStore 创建以 dir 为目录的缓存，TTL 为 DefaultTTL。
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir, TTL: DefaultTTL, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+var (
+	defaultStore *Store
+	defaultOnce  sync.Once
+)
+
+// Default 返回按配置创建的缓存：miio.specs_dir（默认用户缓存目录下 miflow/specs）、
+// miio.specs_ttl_hours（负数永不过期）、miio.specs_offline 或 MI_SPECS_OFFLINE=1。
+func Default() *Store {
+	defaultOnce.Do(func() {
+		cfg := config.Get().MiIO
+		dir := cfg.SpecsDir
+		if dir == "" {
+			base, err := os.UserCacheDir()
+			if err != nil {
+				base = os.TempDir()
+			}
+			dir = filepath.Join(base, "miflow", "specs")
+		}
+		defaultStore = NewStore(dir)
+		defaultStore.InstancesPath = cfg.SpecsCachePath
+		switch {
+		case cfg.SpecsTTLHours < 0:
+			defaultStore.TTL = 0
+		case cfg.SpecsTTLHours > 0:
+			defaultStore.TTL = time.Duration(cfg.SpecsTTLHours) * time.Hour
+		}
+		defaultStore.Offline = cfg.SpecsOffline
+	})
+	return defaultStore
+}
+
+// SetOffline 设置默认缓存的离线模式（如 m --offline）。
+func SetOffline(on bool) {
+	Default().Offline = on
+}
+
+func (s *Store) instancesPath() string {
+	if s.InstancesPath != "" {
+		return s.InstancesPath
+	}
+	return filepath.Join(s.Dir, "instances.json")
+}
+
+func (s *Store) instancePath(urn string) string {
+	return filepath.Join(s.Dir, "instance", strings.NewReplacer(":", "_", "/", "_").Replace(urn)+".json")
+}
+
+// Instances 返回 model→URN 映射，缓存中没有的型号以内置种子补全。
+func (s *Store) Instances() (map[string]string, error) {
+	return s.InstancesContext(context.Background())
+}
+
+// InstancesContext 同 Instances，支持 ctx 取消。
+func (s *Store) InstancesContext(ctx context.Context) (map[string]string, error) {
+	var m map[string]string
+	stale, err := s.read(s.instancesPath(), &m)
+	if err == nil && len(m) > 0 && !stale {
+		return s.withSeed(m), nil
+	}
+	if !s.Offline {
+		fresh, ferr := s.fetchInstances(ctx)
+		if ferr == nil {
+			s.write(s.instancesPath(), fresh)
+			return s.withSeed(fresh), nil
+		}
+		err = ferr
+	}
+	if len(m) > 0 {
+		return s.withSeed(m), nil
+	}
+	if seed := s.seedBundle(); len(seed.Instances) > 0 {
+		return seed.Instances, nil
+	}
+	if s.Offline {
+		return nil, ErrOffline
+	}
+	return nil, err
+}
+
+// Instance 返回 URN（含版本后缀）对应的完整 instance JSON。
+func (s *Store) Instance(urn string) (map[string]interface{}, error) {
+	return s.InstanceContext(context.Background(), urn)
+}
+
+// InstanceContext 同 Instance，联网获取时支持 ctx 取消。
+func (s *Store) InstanceContext(ctx context.Context, urn string) (map[string]interface{}, error) {
+	m, ok, err := cached(s, s.instancePath(urn), func() (map[string]interface{}, error) { return s.fetchInstance(ctx, urn) })
+	if ok {
+		return m, nil
+	}
+	if seed := s.seedBundle().Specs[urn]; seed != nil {
+		return seed, nil
+	}
+	if errors.Is(err, ErrOffline) {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, urn)
+	}
+	return nil, err
+}
+
+// cached 按 未过期缓存 → fetch（Offline 时跳过）→ 过期缓存 的顺序取值，ok 为 false 时均不可用。
+func cached[T any](s *Store, path string, fetch func() (T, error)) (v T, ok bool, err error) {
+	stale, err := s.read(path, &v)
+	if err == nil && !stale {
+		return v, true, nil
+	}
+	have := err == nil
+	if !s.Offline {
+		fresh, ferr := fetch()
+		if ferr == nil {
+			s.write(path, fresh)
+			return fresh, true, nil
+		}
+		err = ferr
+	} else {
+		err = ErrOffline
+	}
+	return v, have, err
+}
+
+// Versions 返回缓存中与 urn 去掉版本后缀后相同的全部 URN，按版本号数值升序（…:2 在 …:10 之前），
+// 如 …:xiaomi-oh2:1、…:xiaomi-oh2:2。
+func (s *Store) Versions(urn string) []string {
+	base := urnBase(urn)
+	files, _ := filepath.Glob(filepath.Join(s.Dir, "instance", "*.json"))
+	var out []string
+	for _, f := range files {
+		var m map[string]interface{}
+		if _, err := s.read(f, &m); err != nil {
+			continue
+		}
+		if t, _ := m["type"].(string); t != "" && urnBase(t) == base {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if vi, vj := urnVersion(out[i]), urnVersion(out[j]); vi != vj {
+			return vi < vj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// urnVersion 返回 URN 的版本号（第 7 段），无法解析时为 -1。
+func urnVersion(urn string) int {
+	parts := strings.Split(urn, ":")
+	if len(parts) < 7 {
+		return -1
+	}
+	v, err := strconv.Atoi(parts[6])
+	if err != nil {
+		return -1
+	}
+	return v
+}
+
+// urnBase 去掉版本及之后的部分：urn:miot-spec-v2:device:<type>:<id>:<vendor-model>:<version>[:…]
+func urnBase(urn string) string {
+	parts := strings.Split(urn, ":")
+	if len(parts) > 6 {
+		parts = parts[:6]
+	}
+	return strings.Join(parts, ":")
+}
+
+// Import 将 Bundle 写入缓存，返回导入的 instance 数量。
+func (s *Store) Import(r io.Reader) (int, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return 0, fmt.Errorf("specs: invalid bundle: %w", err)
+	}
+	if len(b.Instances) > 0 {
+		var m map[string]string
+		s.read(s.instancesPath(), &m)
+		if m == nil {
+			m = make(map[string]string)
+		}
+		for k, v := range b.Instances {
+			m[k] = v
+		}
+		if err := s.write(s.instancesPath(), m); err != nil {
+			return 0, err
+		}
+	}
+	for urn, spec := range b.Specs {
+		if err := s.write(s.instancePath(urn), spec); err != nil {
+			return 0, err
+		}
+	}
+	for urn, t := range b.Translations {
+		if err := s.write(s.translationPath(urn), t); err != nil {
+			return 0, err
+		}
+	}
+	return len(b.Specs), nil
+}
+
+// Export 将缓存写为 Bundle；models 非空时只导出这些型号及其 instance。
+func (s *Store) Export(w io.Writer, models ...string) error {
+	all, err := s.Instances()
+	if err != nil {
+		return err
+	}
+	b := Bundle{Instances: make(map[string]string), Specs: make(map[string]map[string]interface{}), Translations: make(map[string]Translation)}
+	if len(models) == 0 {
+		b.Instances = all
+		files, _ := filepath.Glob(filepath.Join(s.Dir, "instance", "*.json"))
+		for _, f := range files {
+			var m map[string]interface{}
+			if _, err := s.read(f, &m); err == nil {
+				if t, _ := m["type"].(string); t != "" {
+					b.Specs[t] = m
+				}
+			}
+		}
+	}
+	for _, model := range models {
+		urn, ok := all[model]
+		if !ok {
+			return fmt.Errorf("specs: model not found: %s", model)
+		}
+		spec, err := s.Instance(urn)
+		if err != nil {
+			return err
+		}
+		b.Instances[model] = urn
+		b.Specs[urn] = spec
+		// 指定型号时按需获取翻译表，用于生成种子
+		if t, err := s.Translation(urn); err == nil && len(t) > 0 {
+			b.Translations[urn] = t
+		}
+	}
+	// 全部导出时翻译表只导出已缓存的
+	for urn := range b.Specs {
+		if b.Translations[urn] != nil {
+			continue
+		}
+		var t Translation
+		if _, err := s.read(s.translationPath(urn), &t); err == nil && len(t) > 0 {
+			b.Translations[urn] = t
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// read 读取 JSON 文件到 v，stale 表示超过 TTL。
+func (s *Store) read(path string, v interface{}) (stale bool, err error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return s.TTL > 0 && time.Since(fi.ModTime()) > s.TTL, nil
+}
+
+func (s *Store) write(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// withSeed 将种子中有而 m 中没有的型号加入 m。
+func (s *Store) withSeed(m map[string]string) map[string]string {
+	for model, urn := range s.seedBundle().Instances {
+		if _, ok := m[model]; !ok {
+			m[model] = urn
+		}
+	}
+	return m
+}
+
+func (s *Store) seedBundle() *Bundle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seed == nil {
+		s.seed = &Bundle{}
+		json.Unmarshal(seedJSON, s.seed)
+	}
+	return s.seed
+}
+
+func (s *Store) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// get 发送带 ctx 的 GET 请求。
+func (s *Store) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.client().Do(req)
+}
+
+func (s *Store) fetchInstances(ctx context.Context) (map[string]string, error) {
+	resp, err := s.get(ctx, InstancesURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("specs: instances: %s", resp.Status)
+	}
+	var inst struct {
+		Instances []struct {
+			Model string `json:"model"`
+			Type  string `json:"type"`
+		} `json:"instances"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inst); err != nil {
+		return nil, err
+	}
+	m := make(map[string]string)
+	for _, i := range inst.Instances {
+		m[i.Model] = i.Type
+	}
+	if len(m) == 0 {
+		return nil, errors.New("specs: empty instances list")
+	}
+	return m, nil
+}
+
+func (s *Store) fetchInstance(ctx context.Context, urn string) (map[string]interface{}, error) {
+	resp, err := s.get(ctx, InstanceURL+"?type="+url.QueryEscape(urn))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("specs: %s: %s", urn, resp.Status)
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,191 @@
+package specs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSite 模拟 miot-spec.org，记录请求次数。
+type fakeSite struct {
+	calls int
+	down  bool
+}
+
+func (f *fakeSite) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.calls++
+	if f.down {
+		return nil, errors.New("network down")
+	}
+	body := `{"instances":[{"model":"test.light.v1","type":"urn:miot-spec-v2:device:light:0000A001:test-v1:2"}]}`
+	switch r.URL.Path {
+	case "/miot-spec-v2/instance":
+		body = `{"type":"` + r.URL.Query().Get("type") + `","description":"Light","services":[]}`
+	case "/instance/v2/multiLanguage":
+		body = `{"data":{"zh_cn":{"service:002":"灯","service:002:property:001":"开关"},"en":{"service:002":"Light"}}}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func newTestStore(t *testing.T) (*Store, *fakeSite) {
+	site := &fakeSite{}
+	s := NewStore(t.TempDir())
+	s.Client = &http.Client{Transport: site}
+	return s, site
+}
+
+func TestStoreCacheAndTTL(t *testing.T) {
+	s, site := newTestStore(t)
+	urn := "urn:miot-spec-v2:device:light:0000A001:test-v1:2"
+	if m, err := s.Instances(); err != nil || m["test.light.v1"] != urn {
+		t.Fatalf("Instances: %v, %v", m, err)
+	}
+	if _, err := s.Instance(urn); err != nil {
+		t.Fatal(err)
+	}
+	site.calls = 0
+	s.Instances()
+	s.Instance(urn)
+	if site.calls != 0 {
+		t.Errorf("fresh cache refetched: %d calls", site.calls)
+	}
+
+	// 过期后刷新；刷新失败仍返回旧数据
+	old := time.Now().Add(-2 * DefaultTTL)
+	os.Chtimes(s.instancePath(urn), old, old)
+	site.down = true
+	got, err := s.Instance(urn)
+	if err != nil || got["type"] != urn || site.calls != 1 {
+		t.Errorf("stale fallback: %v, %v, calls=%d", got, err, site.calls)
+	}
+
+	s.Offline = true
+	site.calls = 0
+	if _, err := s.Instance(urn); err != nil || site.calls != 0 {
+		t.Errorf("offline: %v, calls=%d", err, site.calls)
+	}
+	if _, err := s.Instance("urn:miot-spec-v2:device:light:0000A001:test-v2:1"); !errors.Is(err, ErrOffline) {
+		t.Errorf("offline miss: %v, want ErrOffline", err)
+	}
+}
+
+func TestStoreSeed(t *testing.T) {
+	s, site := newTestStore(t)
+	s.Offline = true
+	m, err := s.Instances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(m["xiaomi.wifispeaker.oh2"], "urn:miot-spec-v2:device:speaker:") {
+		t.Errorf("seed missing xiaomi.wifispeaker.oh2: %v", m)
+	}
+	if site.calls != 0 {
+		t.Errorf("offline store hit network: %d calls", site.calls)
+	}
+}
+
+func TestStoreImportExport(t *testing.T) {
+	src, _ := newTestStore(t)
+	v1 := "urn:miot-spec-v2:device:light:0000A001:test-v1:1"
+	v2 := "urn:miot-spec-v2:device:light:0000A001:test-v1:2"
+	src.Instance(v1)
+	src.Instance(v2)
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewStore(t.TempDir())
+	dst.Offline = true
+	n, err := dst.Import(&buf)
+	if err != nil || n != 2 {
+		t.Fatalf("Import: %d, %v", n, err)
+	}
+	if m, err := dst.Instances(); err != nil || m["test.light.v1"] != v2 {
+		t.Errorf("imported instances: %v, %v", m, err)
+	}
+	if got, err := dst.Instance(v1); err != nil || got["type"] != v1 {
+		t.Errorf("imported instance: %v, %v", got, err)
+	}
+	if vs := dst.Versions(v2); len(vs) != 2 || vs[0] != v1 || vs[1] != v2 {
+		t.Errorf("Versions = %v", vs)
+	}
+
+	buf.Reset()
+	if err := dst.Export(&buf, "test.light.v1"); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); !strings.Contains(s, v2) || strings.Contains(s, v1) {
+		t.Errorf("export by model should contain only current version:\n%s", s)
+	}
+	if err := dst.Export(&buf, "no.such.model"); err == nil {
+		t.Error("unknown model should fail")
+	}
+}
+
+// blockingSite 阻塞到请求的 ctx 取消，模拟未响应的 miot-spec.org。
+type blockingSite struct{}
+
+func (blockingSite) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestStoreInstanceCancel(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Client = &http.Client{Transport: blockingSite{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := s.InstanceContext(ctx, "urn:miot-spec-v2:device:light:0000A001:test-blocked:1")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("InstanceContext err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("InstanceContext returned after %v, want prompt abort", d)
+	}
+}
+
+// statusSite 以固定状态码返回一个可解析的 instances 列表。
+type statusSite int
+
+func (c statusSite) RoundTrip(r *http.Request) (*http.Response, error) {
+	body := `{"instances":[{"model":"test.light.v1","type":"urn:miot-spec-v2:device:light:0000A001:test-v1:1"}]}`
+	return &http.Response{StatusCode: int(c), Status: http.StatusText(int(c)), Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestStoreFetchInstancesErrors(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Client = &http.Client{Transport: statusSite(http.StatusServiceUnavailable)}
+	if m, err := s.fetchInstances(context.Background()); err == nil || !strings.Contains(err.Error(), "Service Unavailable") {
+		t.Errorf("fetchInstances on 503 = %v, %v", m, err)
+	}
+
+	s.Client = &http.Client{Transport: blockingSite{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := s.fetchInstances(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("fetchInstances err = %v, want context.Canceled", err)
+	}
+}
+
+func TestStoreVersionsNumericOrder(t *testing.T) {
+	s, _ := newTestStore(t)
+	base := "urn:miot-spec-v2:device:light:0000A001:test-v1:"
+	for _, v := range []string{"10", "2", "1"} {
+		if _, err := s.Instance(base + v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{base + "1", base + "2", base + "10"}
+	if vs := s.Versions(base + "1"); !reflect.DeepEqual(vs, want) {
+		t.Errorf("Versions = %v, want %v", vs, want)
+	}
+}
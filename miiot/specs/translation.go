@@ -0,0 +1,166 @@
+package specs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// MultiLanguageURL 为 miot-spec.org 按 URN 发布的描述翻译表。
+const MultiLanguageURL = "https://miot-spec.org/instance/v2/multiLanguage"
+
+// Translation 为 SPEC 描述翻译表：locale（zh_cn、zh_tw、en）→ 键 → 文本。
+// 键形如 service:002、service:002:property:001、service:002:action:001、service:002:event:001，
+// 枚举值为 service:002:property:001:valuelist:000（按 value-list 中的序号）。
+type Translation map[string]map[string]string
+
+// NormalizeLocale 统一 locale 写法：zh_CN、zh-CN、zh-cn → zh_cn；空为 en。
+func NormalizeLocale(locale string) string {
+	l := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "-", "_"))
+	switch l {
+	case "":
+		return "en"
+	case "zh", "zh_hans":
+		return "zh_cn"
+	case "zh_hant", "zh_hk":
+		return "zh_tw"
+	}
+	return l
+}
+
+// Lookup 返回 key 在 locale 下的文本，缺失时为空（调用方回退到 SPEC 自带的英文描述）。
+func (t Translation) Lookup(locale, key string) string {
+	return t[NormalizeLocale(locale)][key]
+}
+
+// ServiceKey 返回服务描述在翻译表中的键。
+func ServiceKey(siid int) string { return fmt.Sprintf("service:%03d", siid) }
+
+// PropertyKey 返回属性描述的键。
+func PropertyKey(siid, piid int) string {
+	return ServiceKey(siid) + fmt.Sprintf(":property:%03d", piid)
+}
+
+// ActionKey 返回动作描述的键。
+func ActionKey(siid, aiid int) string { return ServiceKey(siid) + fmt.Sprintf(":action:%03d", aiid) }
+
+// EventKey 返回事件描述的键。
+func EventKey(siid, eiid int) string { return ServiceKey(siid) + fmt.Sprintf(":event:%03d", eiid) }
+
+// ValueKey 返回 value-list 第 index 项描述的键。
+func ValueKey(siid, piid, index int) string {
+	return PropertyKey(siid, piid) + fmt.Sprintf(":valuelist:%03d", index)
+}
+
+// Translate 经 Default 缓存获取 urn 的翻译表。
+func Translate(urn string) (Translation, error) {
+	return Default().Translation(urn)
+}
+
+// TranslateContext 同 Translate，支持 ctx 取消。
+func TranslateContext(ctx context.Context, urn string) (Translation, error) {
+	return Default().TranslationContext(ctx, urn)
+}
+
+// Translation 返回 urn 的翻译表，缓存规则同 Instance。
+func (s *Store) Translation(urn string) (Translation, error) {
+	return s.TranslationContext(context.Background(), urn)
+}
+
+// TranslationContext 同 Translation，联网获取时支持 ctx 取消。
+func (s *Store) TranslationContext(ctx context.Context, urn string) (Translation, error) {
+	t, ok, err := cached(s, s.translationPath(urn), func() (Translation, error) { return s.fetchTranslation(ctx, urn) })
+	if ok {
+		return t, nil
+	}
+	if seed := s.seedBundle().Translations[urn]; seed != nil {
+		return seed, nil
+	}
+	return nil, fmt.Errorf("specs: translation of %s: %w", urn, err)
+}
+
+func (s *Store) translationPath(urn string) string {
+	return filepath.Join(s.Dir, "translation", filepath.Base(s.instancePath(urn)))
+}
+
+func (s *Store) fetchTranslation(ctx context.Context, urn string) (Translation, error) {
+	resp, err := s.get(ctx, MultiLanguageURL+"?urn="+url.QueryEscape(urn))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("specs: %s: %s", urn, resp.Status)
+	}
+	var body struct {
+		Data Translation `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	t := make(Translation, len(body.Data))
+	for l, m := range body.Data {
+		t[NormalizeLocale(l)] = m
+	}
+	return t, nil
+}
+
+// Apply 返回 instance JSON 的副本：服务、属性、动作、事件与 value-list 的 description 换为 locale 下的翻译，
+// 原英文保存在 description_en。locale 为 en 或没有翻译时原样返回 raw。
+func (t Translation) Apply(raw map[string]interface{}, locale string) map[string]interface{} {
+	texts := t[NormalizeLocale(locale)]
+	if len(texts) == 0 {
+		return raw
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return raw
+	}
+	var out map[string]interface{}
+	if json.Unmarshal(data, &out) != nil {
+		return raw
+	}
+	set := func(m map[string]interface{}, key string) {
+		if tr := texts[key]; tr != "" && m != nil {
+			m["description_en"] = m["description"]
+			m["description"] = tr
+		}
+	}
+	for _, s := range asSlice(out["services"]) {
+		svc, _ := s.(map[string]interface{})
+		siid := asInt(svc["iid"])
+		set(svc, ServiceKey(siid))
+		for _, p := range asSlice(svc["properties"]) {
+			prop, _ := p.(map[string]interface{})
+			piid := asInt(prop["iid"])
+			set(prop, PropertyKey(siid, piid))
+			for i, v := range asSlice(prop["value-list"]) {
+				item, _ := v.(map[string]interface{})
+				set(item, ValueKey(siid, piid, i))
+			}
+		}
+		for _, a := range asSlice(svc["actions"]) {
+			act, _ := a.(map[string]interface{})
+			set(act, ActionKey(siid, asInt(act["iid"])))
+		}
+		for _, e := range asSlice(svc["events"]) {
+			ev, _ := e.(map[string]interface{})
+			set(ev, EventKey(siid, asInt(ev["iid"])))
+		}
+	}
+	return out
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func asInt(v interface{}) int {
+	f, _ := v.(float64)
+	return int(f)
+}
@@ -0,0 +1,75 @@
+package specs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	for in, want := range map[string]string{"": "en", "zh_CN": "zh_cn", "zh-TW": "zh_tw", "zh": "zh_cn", "EN": "en"} {
+		if got := NormalizeLocale(in); got != want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if k := ValueKey(2, 1, 0); k != "service:002:property:001:valuelist:000" {
+		t.Errorf("ValueKey = %s", k)
+	}
+}
+
+func TestStoreTranslation(t *testing.T) {
+	s, site := newTestStore(t)
+	urn := "urn:miot-spec-v2:device:light:0000A001:test-v1:1"
+	tr, err := s.Translation(urn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Lookup("zh_CN", PropertyKey(2, 1)); got != "开关" {
+		t.Errorf("Lookup = %q", got)
+	}
+	site.calls = 0
+	s.Offline = true
+	if _, err := s.Translation(urn); err != nil || site.calls != 0 {
+		t.Errorf("cached: %v, calls=%d", err, site.calls)
+	}
+	if _, err := s.Translation("urn:miot-spec-v2:device:light:0000A001:test-v2:1"); !errors.Is(err, ErrOffline) {
+		t.Errorf("offline miss: %v", err)
+	}
+}
+
+func TestTranslationApply(t *testing.T) {
+	tr := Translation{"zh_cn": {
+		ServiceKey(2):        "灯",
+		PropertyKey(2, 2):    "模式",
+		ValueKey(2, 2, 1):    "夜间",
+		ActionKey(2, 1):      "切换",
+		"service:009:unused": "x",
+	}}
+	raw := map[string]interface{}{"services": []interface{}{map[string]interface{}{
+		"iid": 2.0, "description": "Light",
+		"properties": []interface{}{map[string]interface{}{
+			"iid": 2.0, "description": "Mode",
+			"value-list": []interface{}{
+				map[string]interface{}{"value": 0.0, "description": "Day"},
+				map[string]interface{}{"value": 1.0, "description": "Night"},
+			},
+		}},
+		"actions": []interface{}{map[string]interface{}{"iid": 1.0, "description": "Toggle"}},
+	}}}
+	out := tr.Apply(raw, "zh_CN")
+	svc := out["services"].([]interface{})[0].(map[string]interface{})
+	prop := svc["properties"].([]interface{})[0].(map[string]interface{})
+	day := prop["value-list"].([]interface{})[0].(map[string]interface{})
+	night := prop["value-list"].([]interface{})[1].(map[string]interface{})
+	act := svc["actions"].([]interface{})[0].(map[string]interface{})
+	if svc["description"] != "灯" || svc["description_en"] != "Light" || prop["description"] != "模式" ||
+		night["description"] != "夜间" || day["description"] != "Day" || act["description"] != "切换" {
+		t.Errorf("Apply: %v", out)
+	}
+	// 原 JSON 不变
+	if raw["services"].([]interface{})[0].(map[string]interface{})["description"] != "Light" {
+		t.Error("Apply modified its input")
+	}
+	if got := tr.Apply(raw, "en"); got["services"].([]interface{})[0].(map[string]interface{})["description"] != "Light" {
+		t.Error("en should keep English")
+	}
+}
@@ -0,0 +1,9 @@
+package miiot
+
+// Siid、Piid、Aiid、Eiid 为 miiot-gen 生成的规格常量类型，调用 device API 时用 int(x) 转换。
+type (
+	Siid int
+	Piid int
+	Aiid int
+	Eiid int
+)
@@ -0,0 +1,65 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:air-purifier:0000A007:zhimi-ma4:1
+
+package airpurifier
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelMa4 为 zhimi.airpurifier.ma4 的型号。
+const ModelMa4 = "zhimi.airpurifier.ma4"
+
+// URNMa4 为生成时使用的 SPEC 类型。
+const URNMa4 = "urn:miot-spec-v2:device:air-purifier:0000A007:zhimi-ma4:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationMa4                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerMa4     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelMa4            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberMa4     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionMa4 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Air Purifier（air-purifier，siid=2）
+const (
+	SiidAirPurifierMa4         miiot.Siid = 2
+	PiidAirPurifierFaultMa4    miiot.Piid = 1 // Device Fault，uint8，read/notify
+	PiidAirPurifierOnMa4       miiot.Piid = 2 // Switch Status，bool，read/write/notify
+	PiidAirPurifierFanLevelMa4 miiot.Piid = 4 // Fan Level，uint8，read/write/notify
+	PiidAirPurifierModeMa4     miiot.Piid = 5 // Mode，uint8，read/write/notify
+)
+
+// AirPurifierFaultMa4 的取值（value-list）。
+const (
+	AirPurifierFaultNoFaultsMa4 = 0 // No Faults
+)
+
+// AirPurifierFanLevelMa4 的取值（value-list）。
+const (
+	AirPurifierFanLevelLevel1Ma4 = 1 // Level1
+	AirPurifierFanLevelLevel2Ma4 = 2 // Level2
+	AirPurifierFanLevelLevel3Ma4 = 3 // Level3
+)
+
+// AirPurifierModeMa4 的取值（value-list）。
+const (
+	AirPurifierModeAutoMa4     = 0 // Auto
+	AirPurifierModeSleepMa4    = 1 // Sleep
+	AirPurifierModeFavoriteMa4 = 2 // Favorite
+	AirPurifierModeNoneMa4     = 3 // None
+)
+
+// Environment（environment，siid=3）
+const (
+	SiidEnvironmentMa4                 miiot.Siid = 3
+	PiidEnvironmentPm25DensityMa4      miiot.Piid = 6 // PM2.5 Density，float，read/notify，[0, 600] step 1，μg/m3
+	PiidEnvironmentRelativeHumidityMa4 miiot.Piid = 7 // Relative Humidity，uint8，read/notify，[0, 100] step 1，percentage
+	PiidEnvironmentTemperatureMa4      miiot.Piid = 8 // Temperature，float，read/notify，[-40, 125] step 0.1，celsius
+)
+
+// Filter（filter，siid=4）
+const (
+	SiidFilterMa4                miiot.Siid = 4
+	PiidFilterFilterLifeLevelMa4 miiot.Piid = 3 // Filter Life Level，uint8，read/notify，[0, 100] step 1，percentage
+	PiidFilterFilterUsedTimeMa4  miiot.Piid = 5 // Filter Used Time，uint16，read/notify，[0, 10000] step 1，hours
+)
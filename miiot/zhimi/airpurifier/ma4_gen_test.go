@@ -0,0 +1,36 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package airpurifier
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedMa4(t *testing.T) {
+	s, ok := ctrl.Specs[ModelMa4]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelMa4)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidClimate", s.SiidClimate, int(SiidAirPurifierMa4)},
+		{"PiidClimateMode", s.PiidClimateMode, int(PiidAirPurifierModeMa4)},
+		{"SiidFanControl", s.SiidFanControl, int(SiidAirPurifierMa4)},
+		{"PiidFanLevel", s.PiidFanLevel, int(PiidAirPurifierFanLevelMa4)},
+		{"SiidFilter", s.SiidFilter, int(SiidFilterMa4)},
+		{"PiidFilterLife", s.PiidFilterLife, int(PiidFilterFilterLifeLevelMa4)},
+		{"SiidEnvironment", s.SiidEnvironment, int(SiidEnvironmentMa4)},
+		{"PiidTemperature", s.PiidTemperature, int(PiidEnvironmentTemperatureMa4)},
+		{"PiidHumidity", s.PiidHumidity, int(PiidEnvironmentRelativeHumidityMa4)},
+		{"PiidPM25", s.PiidPM25, int(PiidEnvironmentPm25DensityMa4)},
+		{"PiidOn", s.PiidOn, int(PiidAirPurifierOnMa4)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:heater:0000A01A:zhimi-mc2:1
+
+package heater
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelMc2 为 zhimi.heater.mc2 的型号。
+const ModelMc2 = "zhimi.heater.mc2"
+
+// URNMc2 为生成时使用的 SPEC 类型。
+const URNMc2 = "urn:miot-spec-v2:device:heater:0000A01A:zhimi-mc2:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationMc2                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerMc2     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelMc2            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberMc2     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionMc2 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Heater（heater，siid=2）
+const (
+	SiidHeaterMc2                  miiot.Siid = 2
+	PiidHeaterOnMc2                miiot.Piid = 1 // Switch Status，bool，read/write/notify
+	PiidHeaterFaultMc2             miiot.Piid = 2 // Device Fault，uint8，read/notify
+	PiidHeaterTargetTemperatureMc2 miiot.Piid = 5 // Target Temperature，uint8，read/write/notify，[18, 28] step 1，celsius
+)
+
+// HeaterFaultMc2 的取值（value-list）。
+const (
+	HeaterFaultNoFaultsMc2 = 0 // No Faults
+)
+
+// Environment（environment，siid=4）
+const (
+	SiidEnvironmentMc2            miiot.Siid = 4
+	PiidEnvironmentTemperatureMc2 miiot.Piid = 7 // Temperature，float，read/notify，[-40, 125] step 0.1，celsius
+)
@@ -0,0 +1,30 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package heater
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedMc2(t *testing.T) {
+	s, ok := ctrl.Specs[ModelMc2]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelMc2)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidClimate", s.SiidClimate, int(SiidHeaterMc2)},
+		{"PiidTargetTemperature", s.PiidTargetTemperature, int(PiidHeaterTargetTemperatureMc2)},
+		{"SiidEnvironment", s.SiidEnvironment, int(SiidEnvironmentMc2)},
+		{"PiidTemperature", s.PiidTemperature, int(PiidEnvironmentTemperatureMc2)},
+		{"PiidOn", s.PiidOn, int(PiidHeaterOnMc2)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+// Source: urn:miot-spec-v2:device:humidifier:0000A00E:zhimi-ca4:1
+
+package humidifier
+
+import "github.com/zeusro/miflow/miiot"
+
+// ModelCa4 为 zhimi.humidifier.ca4 的型号。
+const ModelCa4 = "zhimi.humidifier.ca4"
+
+// URNCa4 为生成时使用的 SPEC 类型。
+const URNCa4 = "urn:miot-spec-v2:device:humidifier:0000A00E:zhimi-ca4:1"
+
+// Device Information（device-information，siid=1）
+const (
+	SiidDeviceInformationCa4                 miiot.Siid = 1
+	PiidDeviceInformationManufacturerCa4     miiot.Piid = 1 // Device Manufacturer，string，read
+	PiidDeviceInformationModelCa4            miiot.Piid = 2 // Device Model，string，read
+	PiidDeviceInformationSerialNumberCa4     miiot.Piid = 3 // Device Serial Number，string，read
+	PiidDeviceInformationFirmwareRevisionCa4 miiot.Piid = 4 // Current Firmware Version，string，read
+)
+
+// Humidifier（humidifier，siid=2）
+const (
+	SiidHumidifierCa4               miiot.Siid = 2
+	PiidHumidifierOnCa4             miiot.Piid = 1 // Switch Status，bool，read/write/notify
+	PiidHumidifierFaultCa4          miiot.Piid = 2 // Device Fault，uint8，read/notify
+	PiidHumidifierFanLevelCa4       miiot.Piid = 5 // Fan Level，uint8，read/write/notify
+	PiidHumidifierTargetHumidityCa4 miiot.Piid = 6 // Target Humidity，uint8，read/write/notify，[30, 80] step 10，percentage
+)
+
+// HumidifierFaultCa4 的取值（value-list）。
+const (
+	HumidifierFaultNoFaultsCa4 = 0 // No Faults
+)
+
+// HumidifierFanLevelCa4 的取值（value-list）。
+const (
+	HumidifierFanLevelAutoCa4   = 0 // Auto
+	HumidifierFanLevelLevel1Ca4 = 1 // Level1
+	HumidifierFanLevelLevel2Ca4 = 2 // Level2
+	HumidifierFanLevelLevel3Ca4 = 3 // Level3
+)
+
+// Environment（environment，siid=3）
+const (
+	SiidEnvironmentCa4                 miiot.Siid = 3
+	PiidEnvironmentTemperatureCa4      miiot.Piid = 7 // Temperature，float，read/notify，[-40, 125] step 0.1，celsius
+	PiidEnvironmentRelativeHumidityCa4 miiot.Piid = 9 // Relative Humidity，uint8，read/notify，[0, 100] step 1，percentage
+)
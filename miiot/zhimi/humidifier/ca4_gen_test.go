@@ -0,0 +1,33 @@
+// Code generated by miiot-gen; DO NOT EDIT.
+
+package humidifier
+
+import (
+	"testing"
+
+	"github.com/zeusro/miflow/miiot/ctrl"
+)
+
+func TestGeneratedCa4(t *testing.T) {
+	s, ok := ctrl.Specs[ModelCa4]
+	if !ok {
+		t.Fatalf("%s not in ctrl.Specs", ModelCa4)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want int
+	}{
+		{"SiidClimate", s.SiidClimate, int(SiidHumidifierCa4)},
+		{"PiidTargetHumidity", s.PiidTargetHumidity, int(PiidHumidifierTargetHumidityCa4)},
+		{"SiidFanControl", s.SiidFanControl, int(SiidHumidifierCa4)},
+		{"PiidFanLevel", s.PiidFanLevel, int(PiidHumidifierFanLevelCa4)},
+		{"SiidEnvironment", s.SiidEnvironment, int(SiidEnvironmentCa4)},
+		{"PiidTemperature", s.PiidTemperature, int(PiidEnvironmentTemperatureCa4)},
+		{"PiidHumidity", s.PiidHumidity, int(PiidEnvironmentRelativeHumidityCa4)},
+		{"PiidOn", s.PiidOn, int(PiidHumidifierOnCa4)},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: ctrl.Specs=%d, spec=%d", tc.name, tc.got, tc.want)
+		}
+	}
+}
@@ -3,17 +3,22 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miaccount"
 	"github.com/zeusro/miflow/internal/miiocommand"
 	"github.com/zeusro/miflow/internal/miioservice"
 	"github.com/zeusro/miflow/internal/minaservice"
+	"github.com/zeusro/miflow/miiot/specs"
+	"github.com/zeusro/miflow/pkg/cmd/discover"
 	"github.com/zeusro/miflow/pkg/cmd/login"
 	"github.com/zeusro/miflow/pkg/cmd/mina"
+	"github.com/zeusro/miflow/pkg/cmd/subscribe"
 	"github.com/zeusro/miflow/pkg/cmd/util"
 )
 
@@ -34,11 +39,20 @@ func FullHelp() string {
 	return `m - XiaoMi MIoT + Mina CLI (OAuth 2.0)
 
 USAGE
-  m <command> [args...]
+  m [--offline] <command> [args...]
+                    --offline 时 SPEC 只从本地缓存与内置种子读取，不访问 miot-spec.org
 
 AUTH
   login              首次使用需执行 OAuth 2.0 登录，在浏览器中完成授权后保存 token
 
+LAN
+  discover [seconds] 广播 miIO hello 并查询 mDNS _miio._udp，列出局域网设备的 IP 与最后发现时间
+                    已登录时按 did 关联云端设备列表的名称与型号
+
+PUSH
+  subscribe <did|name> [siid-piid ...]
+                    通过云端推送 MQTT 订阅属性变化（不指定时含全部属性、事件与上下线），逐行输出 JSON
+
 DEVICE
   通过 config 的 default_did 或环境变量 MI_DID 指定设备（device_id 或设备名称）
   mina 命令（除 mina 外）均需指定设备
@@ -57,15 +71,40 @@ MINA（小爱音箱 / 语音设备）
 MIoT / MiIO（设备属性与控制）
   list [name] [getVirtualModel] [getHuamiDevices]
                     列出设备，可选按名称筛选、是否含虚拟设备、华米设备数量
+  list --room <房间> [--home <家庭>] [--model <型号关键字>] [--online]
+                    按家庭、房间、型号、在线状态筛选设备，如 m list --room 客厅
+  homes             列出家庭、房间与设备分配
+  group [list|show <名称>|set <名称> [成员...] [--room R] [--home H] [--model P] [--cap C]|rm <名称>]
+                    设备群组；MI_DID=@群组名 时命令对每个成员并发执行
+  alias [list|set <别名> <did|name>|rm <别名>]
+                    设备别名；名称按 did > 名称 > 房间+名称 > 别名 > 模糊 解析，多个命中时报错
+  registry [list|refresh|history [did|name]]
+                    本地设备注册表（miflow.db）及新增/移除/改名/型号变化记录
+  scene list | scene run <名称|ID>
+                    列出或执行米家手动场景，如 m scene run 回家
+  vacuum [status|start|stop|pause|dock|fan [档位]|rooms <id,...>]
+                    扫地机器人：状态、吸力与电量，启停、回充，按房间清扫
   spec [model] [format]
-                    查询 MIoT 规格，format 可选 text|python|json
+                    查询 MIoT 规格，format 可选 text|python|json；MI_LOCALE=zh_CN 时注释附加中文描述
   spec_all           获取 m list 中所有型号的 SPEC
+  spec export <file> [model ...] | spec import <file>
+                    导出/导入本地 SPEC 缓存（model→URN 与各版本 instance），供离线环境使用
+  spec versions <model|urn>
+                    列出缓存中同一型号的各 SPEC 版本
+  spec diff <urnA|model> [urnB] [json]
+                    比较两个 SPEC 版本的服务/属性/动作/事件增删、重新编号与格式/范围变化
+  spec check        按当前 SPEC 检查 m list 中各型号的 ctrl.Specs 常量是否仍然有效
+  spec resolve <model|urn>
+                    按服务/属性/动作类型 URN 解析开关、灯、音箱等能力，列出每项由哪条规则匹配
   decode <ssecurity> <nonce> <data> [gzip]
                     解码 MIoT 加密数据
 
   siid-piid          获取属性，如 m 1,1-2,1-3,2-1
+  siid-piid --decode 获取属性并按 SPEC 解读名称、单位与枚举描述，如 m 2-1 --decode
   siid-piid=value    设置属性，如 m 2=#60,2-2=#false,3=test
   siid-aiid args     执行动作，如 m 5 Hello 或 m 5-4 Hello #1
+  服务.属性[=值]     按 SPEC 名称读写属性，如 m light.on=true,light.brightness=60
+  服务.动作 args     按 SPEC 名称执行动作，如 m play-control.pause、m intelligent-speaker.play-text "你好"
 
   prop/get|prop/set|action <params>
                     原始 MIoT 调用，params 为 JSON
@@ -73,17 +112,22 @@ MIoT / MiIO（设备属性与控制）
 
 EXAMPLES
   m login
+  m discover
   m mina
   m message 你好世界
   m play https://example.com/audio.mp3
   m list Light true 0
+  m list --room 客厅 --model light
   m 2=#60
 `
 }
 
 // Run executes the m command with given args.
 func Run(args []string) {
-	for len(args) > 0 && strings.HasPrefix(args[0], "-v") {
+	for len(args) > 0 && (strings.HasPrefix(args[0], "-v") || args[0] == "--offline") {
+		if args[0] == "--offline" {
+			specs.SetOffline(true)
+		}
 		args = args[1:]
 	}
 	if len(args) == 0 {
@@ -104,6 +148,10 @@ func Run(args []string) {
 		login.Login{TokenPath: tokenPath}.Run()
 		return
 	}
+	if cmd == "discover" {
+		discover.Discover{TokenPath: tokenPath, Args: args[1:]}.Run()
+		return
+	}
 
 	token := (&miaccount.TokenStore{Path: tokenPath}).LoadOAuth()
 	if token == nil || !token.IsValid() {
@@ -135,10 +183,26 @@ func Run(args []string) {
 
 	// MiIO/MIoT
 	text := strings.Join(args, " ")
-	result, err := miiocommand.Run(ioSvc, did, text, prefix)
+	api, err := device.NewAPIFromConfig(ioSvc)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if cmd == "subscribe" {
+		subscribe.Subscribe{IO: ioSvc, API: api, Args: args[1:]}.Run()
+		return
+	}
+	result, err := miiocommand.Run(api, did, text, prefix)
+	if err != nil {
+		var ge *device.GroupError
+		if errors.As(err, &ge) {
+			util.PrintResult(ge.Results)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if r, ok := api.RecentRoute(); ok && cfg.Debug {
+		fmt.Fprintf(os.Stderr, "[route] %s %s via %s (fallback=%v)\n", r.DID, r.Op, r.Transport, r.Fallback)
+	}
 	util.PrintResult(result)
 }
@@ -0,0 +1,56 @@
+// Package discover implements the m discover subcommand (LAN device discovery).
+package discover
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/discovery"
+	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/miioservice"
+	"github.com/zeusro/miflow/pkg/cmd/util"
+)
+
+// Discover scans the LAN and correlates results with the cloud device list when logged in.
+type Discover struct {
+	TokenPath string
+	Args      []string // optional: timeout in seconds
+}
+
+// Run executes the discover command.
+func (d Discover) Run() {
+	s := discovery.NewScanner()
+	s.Table = discovery.NewTable()
+	if ms := config.Get().MiIO.DiscoveryTimeoutMS; ms > 0 {
+		s.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if len(d.Args) > 0 {
+		sec, err := strconv.ParseFloat(d.Args[0], 64)
+		if err != nil || sec <= 0 {
+			fmt.Fprintln(os.Stderr, "Usage: m discover [timeout_seconds]")
+			os.Exit(1)
+		}
+		s.Timeout = time.Duration(sec * float64(time.Second))
+	}
+	fmt.Fprintf(os.Stderr, "Scanning LAN for %s (miIO hello + mDNS)...\n", s.Timeout)
+	if _, err := s.Scan(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	token := (&miaccount.TokenStore{Path: d.TokenPath}).LoadOAuth()
+	if token == nil || !token.IsValid() {
+		fmt.Fprintln(os.Stderr, "(not logged in, skip matching with cloud device list; run 'm login')")
+	} else if ioSvc, err := miioservice.New(token, d.TokenPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else if list, err := ioSvc.DeviceList("", false, 0); err != nil {
+		fmt.Fprintln(os.Stderr, "cloud device list:", err)
+	} else {
+		s.Table.Correlate(list)
+	}
+	util.PrintResult(s.Table.List())
+}
@@ -0,0 +1,89 @@
+// Package subscribe implements the m subscribe subcommand (cloud push of property changes, events and online state).
+package subscribe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/internal/miioservice"
+	push "github.com/zeusro/miflow/internal/subscribe"
+)
+
+// Subscribe prints pushed messages as JSON lines until interrupted.
+type Subscribe struct {
+	IO   *miioservice.Service
+	API  *device.API
+	Args []string // <did|name> [siid-piid ...]
+}
+
+// Run executes the subscribe command.
+func (s Subscribe) Run() {
+	if len(s.Args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: m subscribe <did|name> [siid-piid ...]")
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	did, err := s.API.ResolveDIDContext(ctx, s.Args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sub := push.NewFromHAClient(s.IO.HAClient())
+	out := make(chan interface{}, 64)
+	if len(s.Args) == 1 {
+		go pipe("property", sub.Properties(ctx, did, 0, 0), out)
+		go pipe("event", sub.Events(ctx, did, 0, 0), out)
+		go pipe("online", sub.Online(ctx, did), out)
+	}
+	for _, a := range s.Args[1:] {
+		siid, piid, ok := parseIID(a)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid siid-piid: %s\n", a)
+			os.Exit(1)
+		}
+		go pipe("property", sub.Properties(ctx, did, siid, piid), out)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- sub.Run(ctx) }()
+	fmt.Fprintf(os.Stderr, "Subscribed to %s via %s, Ctrl-C to stop\n", did, sub.Addr)
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case m := <-out:
+			_ = enc.Encode(m)
+		case err := <-errc:
+			if err != nil && ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+}
+
+// pipe 将订阅消息标注类型后转发到 out。
+func pipe[T any](kind string, in <-chan T, out chan<- interface{}) {
+	for m := range in {
+		out <- map[string]interface{}{"type": kind, "message": m}
+	}
+}
+
+// parseIID 解析 siid-piid，如 2-1。
+func parseIID(s string) (siid, piid int, ok bool) {
+	a, b, found := strings.Cut(s, "-")
+	if !found {
+		return 0, 0, false
+	}
+	siid, err1 := strconv.Atoi(a)
+	piid, err2 := strconv.Atoi(b)
+	return siid, piid, err1 == nil && err2 == nil && siid > 0 && piid > 0
+}
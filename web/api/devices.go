@@ -2,11 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miiocommand"
+	"github.com/zeusro/miflow/miiot/ctrl"
+	"github.com/zeusro/miflow/miiot/specs"
 	"github.com/zeusro/miflow/web"
 )
 
@@ -18,7 +24,26 @@ func DevicesList(a *web.App, r *ghttp.Request) {
 	name := r.Get("name").String()
 	getVirtual := r.Get("getVirtual").Bool()
 	getHuami := r.Get("getHuami").Int()
-	list, err := a.DeviceAPI().List(name, getVirtual, getHuami)
+	f := device.Filter{
+		Name:  name,
+		Home:  r.Get("home").String(),
+		Room:  r.Get("room").String(),
+		Model: r.Get("model").String(),
+	}
+	if r.Get("online").String() != "" {
+		online := r.Get("online").Bool()
+		f.Online = &online
+	}
+	if f.Home != "" || f.Room != "" || f.Model != "" || f.Online != nil {
+		list, err := a.DeviceAPI().FindContext(r.Context(), f)
+		if err != nil {
+			Err(r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		JSON(r, http.StatusOK, list)
+		return
+	}
+	list, err := a.DeviceAPI().ListContext(r.Context(), name, getVirtual, getHuami)
 	if err != nil {
 		Err(r, http.StatusInternalServerError, err.Error())
 		return
@@ -26,6 +51,19 @@ func DevicesList(a *web.App, r *ghttp.Request) {
 	JSON(r, http.StatusOK, list)
 }
 
+// HomesList handles GET /api/homes - homes with rooms and device assignment
+func HomesList(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	homes, err := a.DeviceAPI().HomesContext(r.Context())
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, homes)
+}
+
 // DeviceGet handles GET /api/devices/:id - get device detail
 func DeviceGet(a *web.App, r *ghttp.Request) {
 	if !RequireAuth(a, r) {
@@ -36,7 +74,7 @@ func DeviceGet(a *web.App, r *ghttp.Request) {
 		Err(r, http.StatusBadRequest, "device id required")
 		return
 	}
-	d, err := a.DeviceAPI().Get(id)
+	d, err := a.DeviceAPI().GetContext(r.Context(), id)
 	if err != nil {
 		Err(r, http.StatusNotFound, err.Error())
 		return
@@ -44,7 +82,7 @@ func DeviceGet(a *web.App, r *ghttp.Request) {
 	JSON(r, http.StatusOK, d)
 }
 
-// DeviceControl handles POST /api/devices/:id/control - control device (miot command)
+// DeviceControl handles POST /api/devices/:id/control - control device (miot command); id may be @group
 func DeviceControl(a *web.App, r *ghttp.Request) {
 	if !RequireAuth(a, r) {
 		return
@@ -66,15 +104,104 @@ func DeviceControl(a *web.App, r *ghttp.Request) {
 		Err(r, http.StatusBadRequest, "command required")
 		return
 	}
-	_, err := miiocommand.Run(a.Miio(), id, cmd, "web ")
+	api := a.DeviceAPI()
+	result, err := miiocommand.RunContext(r.Context(), api, id, cmd, "web ")
+	var ge *device.GroupError
+	if errors.As(err, &ge) {
+		// 群组部分成员失败：返回每个成员的结果
+		JSON(r, http.StatusMultiStatus, map[string]interface{}{"status": "partial", "error": err.Error(), "results": ge.Results})
+		return
+	}
 	if err != nil {
 		Err(r, http.StatusInternalServerError, err.Error())
 		return
 	}
-	JSON(r, http.StatusOK, map[string]string{"status": "ok"})
+	resp := map[string]interface{}{"status": "ok"}
+	if _, ok := device.GroupRef(id); ok {
+		resp["results"] = result
+	} else if did, err := api.ResolveDIDContext(r.Context(), id); err == nil {
+		// 按 did 取路由，并发请求中不会取到其他设备的通道
+		if route, ok := api.LastRoute(did); ok {
+			resp["route"] = route
+		}
+	}
+	JSON(r, http.StatusOK, resp)
 }
 
-// DeviceSpec handles GET /api/devices/:id/spec - get device MIoT spec (for control UI)
+// DeviceProps handles GET /api/devices/:id/props?props=2-1,light.on - read properties decoded with the
+// device spec (name, unit, enum label); all readable properties when props is empty
+func DeviceProps(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	id := r.GetRouter("id").String()
+	if id == "" {
+		Err(r, http.StatusBadRequest, "device id required")
+		return
+	}
+	api := a.DeviceAPI()
+	d, err := api.GetContext(r.Context(), id)
+	if err != nil {
+		Err(r, http.StatusNotFound, err.Error())
+		return
+	}
+	var iids [][2]int
+	var spec *device.ModelSpec
+	query := strings.TrimSpace(r.GetQuery("props").String())
+	for _, tok := range strings.Split(query, ",") {
+		if tok = strings.TrimSpace(tok); tok == "" {
+			continue
+		}
+		if siid, piid, ok := parseIID(tok); ok {
+			iids = append(iids, [2]int{siid, piid})
+			continue
+		}
+		if spec == nil {
+			if spec, err = api.ModelSpecContext(r.Context(), d.DID); err != nil {
+				Err(r, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		svc, p, err := spec.LookupProperty(tok)
+		if err != nil {
+			Err(r, http.StatusBadRequest, err.Error())
+			return
+		}
+		iids = append(iids, [2]int{svc.IID, p.IID})
+	}
+	if query == "" {
+		if spec, err = api.ModelSpecContext(r.Context(), d.DID); err != nil {
+			Err(r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		iids = spec.ReadableProps()
+	}
+	readings, err := api.ReadPropsContext(r.Context(), d.DID, iids)
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, readings)
+}
+
+// parseIID parses siid-piid (piid defaults to 1).
+func parseIID(s string) (int, int, bool) {
+	a, b, found := strings.Cut(s, "-")
+	siid, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, 0, false
+	}
+	piid := 1
+	if found {
+		if piid, err = strconv.Atoi(b); err != nil {
+			return 0, 0, false
+		}
+	}
+	return siid, piid, true
+}
+
+// DeviceSpec handles GET /api/devices/:id/spec - get device MIoT spec (for control UI).
+// Descriptions are translated to ?locale= (zh_CN, zh_TW, en), defaulting to miio.spec_locale.
 func DeviceSpec(a *web.App, r *ghttp.Request) {
 	if !RequireAuth(a, r) {
 		return
@@ -84,15 +211,107 @@ func DeviceSpec(a *web.App, r *ghttp.Request) {
 		Err(r, http.StatusBadRequest, "device id required")
 		return
 	}
-	d, err := a.DeviceAPI().Get(id)
+	d, err := a.DeviceAPI().GetContext(r.Context(), id)
 	if err != nil {
 		Err(r, http.StatusNotFound, err.Error())
 		return
 	}
-	spec, err := a.DeviceAPI().SpecForDevice(d, "json")
+	spec, err := a.DeviceAPI().SpecForDeviceContext(r.Context(), d, "json")
 	if err != nil {
 		Err(r, http.StatusInternalServerError, err.Error())
 		return
 	}
+	locale := r.Get("locale").String()
+	if locale == "" {
+		locale = config.Get().MiIO.SpecLocale
+	}
+	if raw, ok := spec.(map[string]interface{}); ok && specs.NormalizeLocale(locale) != "en" {
+		urn, _ := raw["type"].(string)
+		if tr, err := specs.TranslateContext(r.Context(), urn); err == nil {
+			spec = tr.Apply(raw, locale)
+		}
+	}
 	JSON(r, http.StatusOK, spec)
 }
+
+// DeviceCapabilities handles GET /api/devices/:id/capabilities - list what the device supports
+// (switchable, dimmable, color-temperature, media-player, ...) as resolved from its model spec
+func DeviceCapabilities(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	id := r.GetRouter("id").String()
+	if id == "" {
+		Err(r, http.StatusBadRequest, "device id required")
+		return
+	}
+	d, err := ctrl.New(a.DeviceAPI()).DeviceContext(r.Context(), id)
+	if err != nil {
+		Err(r, http.StatusNotFound, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, map[string]interface{}{
+		"did":          d.DID,
+		"name":         d.Name,
+		"model":        d.Model,
+		"capabilities": d.Names(),
+	})
+}
+
+// DeviceVacuum handles GET /api/devices/:id/vacuum - vacuum status, fan level and battery, and
+// POST /api/devices/:id/vacuum {"command":"start|stop|pause|dock|fan 2|rooms 10,11"}
+func DeviceVacuum(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	id := r.GetRouter("id").String()
+	if id == "" {
+		Err(r, http.StatusBadRequest, "device id required")
+		return
+	}
+	cmd := "status"
+	if r.Method == http.MethodPost {
+		var body struct {
+			Command string `json:"command"`
+		}
+		if err := json.NewDecoder(r.Request.Body).Decode(&body); err != nil {
+			Err(r, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+		if cmd = strings.TrimSpace(body.Command); cmd == "" {
+			Err(r, http.StatusBadRequest, "command required")
+			return
+		}
+	}
+	api := a.DeviceAPI()
+	if _, ok := device.GroupRef(id); !ok {
+		did, err := api.ResolveDIDContext(r.Context(), id)
+		if err != nil {
+			Err(r, http.StatusNotFound, err.Error())
+			return
+		}
+		id = did
+	}
+	result, err := miiocommand.RunContext(r.Context(), api, id, "vacuum "+cmd, "web ")
+	if err != nil {
+		Err(r, commandStatus(err), err.Error())
+		return
+	}
+	if r.Method == http.MethodPost {
+		JSON(r, http.StatusOK, map[string]interface{}{"status": "ok", "result": result})
+		return
+	}
+	JSON(r, http.StatusOK, result)
+}
+
+// commandStatus maps a command error to an HTTP status: 400 when the request itself is wrong
+// (bad arguments, unsupported capability, value rejected by SPEC), otherwise 500.
+func commandStatus(err error) int {
+	var usage *miiocommand.UsageError
+	var unsupported *ctrl.UnsupportedError
+	var spec *device.SpecError
+	if errors.As(err, &usage) || errors.As(err, &unsupported) || errors.As(err, &spec) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
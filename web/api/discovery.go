@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/zeusro/miflow/internal/config"
+	"github.com/zeusro/miflow/internal/discovery"
+	"github.com/zeusro/miflow/web"
+)
+
+// DiscoveryList handles GET /api/discovery - LAN discovery table (ip / last_seen by did)
+func DiscoveryList(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	tbl := a.DeviceAPI().Discovery()
+	if tbl == nil {
+		JSON(r, http.StatusOK, []discovery.Entry{})
+		return
+	}
+	JSON(r, http.StatusOK, tbl.List())
+}
+
+// DiscoveryScan handles POST /api/discovery/scan - broadcast miIO hello + mDNS and match cloud devices by did
+func DiscoveryScan(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	api := a.DeviceAPI()
+	tbl := api.EnsureDiscovery()
+	s := discovery.NewScanner()
+	s.Table = tbl
+	if ms := config.Get().MiIO.DiscoveryTimeoutMS; ms > 0 {
+		s.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	if _, err := s.Scan(r.Context()); err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if cloud := api.Cloud(); cloud != nil {
+		if list, err := cloud.DeviceListContext(r.Context(), "", false, 0); err == nil {
+			tbl.Correlate(list)
+		}
+	}
+	JSON(r, http.StatusOK, tbl.List())
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/zeusro/miflow/internal/device"
+	"github.com/zeusro/miflow/web"
+)
+
+// GroupsList handles GET /api/groups - device groups
+func GroupsList(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	groups, err := a.DeviceAPI().Groups()
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if groups == nil {
+		groups = []device.Group{}
+	}
+	JSON(r, http.StatusOK, groups)
+}
+
+// GroupSave handles POST /api/groups - create or replace a group
+func GroupSave(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	var g device.Group
+	if err := json.NewDecoder(r.Request.Body).Decode(&g); err != nil {
+		Err(r, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if err := a.DeviceAPI().SaveGroup(&g); err != nil {
+		Err(r, http.StatusBadRequest, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, g)
+}
+
+// GroupMembers handles GET /api/groups/:name/members - current members of a group
+func GroupMembers(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	list, err := a.DeviceAPI().GroupMembersContext(r.Context(), r.GetRouter("name").String())
+	if err != nil {
+		Err(r, http.StatusNotFound, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, list)
+}
+
+// GroupDelete handles DELETE /api/groups/:name - delete a group
+func GroupDelete(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	if err := a.DeviceAPI().DeleteGroup(r.GetRouter("name").String()); err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, map[string]string{"status": "ok"})
+}
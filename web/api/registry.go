@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/zeusro/miflow/web"
+)
+
+// RegistryList handles GET /api/registry - devices persisted in miflow.db
+func RegistryList(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	reg := a.DeviceAPI().Registry()
+	if reg == nil {
+		Err(r, http.StatusNotFound, "device registry disabled")
+		return
+	}
+	list, err := reg.List()
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, map[string]interface{}{"refreshed_at": reg.RefreshedAt(), "devices": list})
+}
+
+// RegistryRefresh handles POST /api/registry/refresh - re-fetch device list and return changes
+func RegistryRefresh(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	changes, err := a.DeviceAPI().RefreshRegistryContext(r.Context())
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, map[string]interface{}{"status": "ok", "changes": changes})
+}
+
+// RegistryHistory handles GET /api/registry/history?did=&limit= - device add/remove/rename/model changes
+func RegistryHistory(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	reg := a.DeviceAPI().Registry()
+	if reg == nil {
+		Err(r, http.StatusNotFound, "device registry disabled")
+		return
+	}
+	limit := r.Get("limit").Int()
+	if limit <= 0 {
+		limit = 100
+	}
+	changes, err := reg.History(r.Get("did").String(), limit)
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, changes)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/zeusro/miflow/web"
+)
+
+// ScenesList handles GET /api/scenes - manual scenes of all homes
+func ScenesList(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	scenes, err := a.DeviceAPI().ScenesContext(r.Context())
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, scenes)
+}
+
+// SceneRun handles POST /api/scenes/:id/run - run scene by id or name
+func SceneRun(a *web.App, r *ghttp.Request) {
+	if !RequireAuth(a, r) {
+		return
+	}
+	id := r.GetRouter("id").String()
+	if id == "" {
+		Err(r, http.StatusBadRequest, "scene id required")
+		return
+	}
+	sc, err := a.DeviceAPI().RunSceneContext(r.Context(), id)
+	if err != nil {
+		Err(r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	JSON(r, http.StatusOK, map[string]interface{}{"status": "ok", "scene": sc})
+}
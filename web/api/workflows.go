@@ -109,3 +109,17 @@ func WorkflowRun(a *web.App, r *ghttp.Request) {
 	go a.RunWorkflow(w)
 	JSON(r, http.StatusAccepted, map[string]string{"status": "started", "id": id})
 }
+
+// WorkflowCancel handles POST /api/workflows/:id/cancel - cancel running workflow
+func WorkflowCancel(a *web.App, r *ghttp.Request) {
+	id := r.GetRouter("id").String()
+	if id == "" {
+		Err(r, http.StatusBadRequest, "workflow id required")
+		return
+	}
+	if !a.CancelWorkflow(id) {
+		Err(r, http.StatusNotFound, "workflow not running")
+		return
+	}
+	JSON(r, http.StatusOK, map[string]string{"status": "cancelled", "id": id})
+}
@@ -2,16 +2,19 @@
 package web
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zeusro/miflow/internal/config"
 	"github.com/zeusro/miflow/internal/device"
 	"github.com/zeusro/miflow/internal/miaccount"
+	"github.com/zeusro/miflow/internal/miiocommand"
 	"github.com/zeusro/miflow/internal/miioservice"
 	"github.com/zeusro/miflow/internal/minaservice"
-	"github.com/zeusro/miflow/internal/miiocommand"
 	"github.com/zeusro/miflow/internal/web/workflow"
 )
 
@@ -27,6 +30,14 @@ type App struct {
 	miio          *miioservice.Service
 	mina          *minaservice.Service
 	defaultDID    string
+
+	runsMu sync.Mutex
+	runs   map[string]*workflowRun
+}
+
+// workflowRun 记录一次运行中的工作流，用于取消。
+type workflowRun struct {
+	cancel context.CancelFunc
 }
 
 // DeviceAPI returns the device API (nil if not logged in).
@@ -40,9 +51,49 @@ func (a *App) Miio() *miioservice.Service { return a.miio }
 
 // RunWorkflow executes a workflow asynchronously.
 func (a *App) RunWorkflow(w *workflow.Workflow) {
+	_ = a.RunWorkflowContext(context.Background(), w)
+}
+
+// RunWorkflowContext executes workflow steps in order until ctx is done or
+// CancelWorkflow is called; in-flight cloud calls are cancelled as well.
+func (a *App) RunWorkflowContext(ctx context.Context, w *workflow.Workflow) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	run := &workflowRun{cancel: cancel}
+	a.runsMu.Lock()
+	if a.runs == nil {
+		a.runs = make(map[string]*workflowRun)
+	}
+	a.runs[w.ID] = run
+	a.runsMu.Unlock()
+	defer func() {
+		a.runsMu.Lock()
+		if a.runs[w.ID] == run {
+			delete(a.runs, w.ID)
+		}
+		a.runsMu.Unlock()
+	}()
+
 	for _, step := range w.Steps {
-		_ = a.runStep(step)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_ = a.runStep(ctx, step)
 	}
+	return ctx.Err()
+}
+
+// CancelWorkflow cancels the running workflow with the given id.
+// Returns false if it is not running.
+func (a *App) CancelWorkflow(id string) bool {
+	a.runsMu.Lock()
+	run, ok := a.runs[id]
+	a.runsMu.Unlock()
+	if !ok {
+		return false
+	}
+	run.cancel()
+	return true
 }
 
 // NewApp creates a new App instance.
@@ -69,7 +120,10 @@ func NewApp() (*App, error) {
 	if token != nil && token.IsValid() {
 		miio, err = miioservice.New(token, tokenPath)
 		if err == nil {
-			deviceAPI = device.NewAPI(miio)
+			deviceAPI, err = device.NewAPIFromConfig(miio)
+			if err != nil {
+				return nil, err
+			}
 			mina = minaservice.NewWithMinaAPI(miio, token, tokenPath)
 		}
 	}
@@ -90,14 +144,23 @@ func (a *App) resolveDID(step workflow.Step) string {
 	return a.defaultDID
 }
 
-func (a *App) runStep(step workflow.Step) error {
+func (a *App) runStep(ctx context.Context, step workflow.Step) error {
+	if name, ok := device.GroupRef(a.resolveDID(step)); ok && (step.Type == workflow.StepTypeTTS || step.Type == workflow.StepTypePlayURL) {
+		return a.runGroupStep(ctx, name, step)
+	}
 	switch step.Type {
 	case workflow.StepTypeDelay:
 		if step.DurationMS <= 0 {
 			return nil
 		}
-		time.Sleep(time.Duration(step.DurationMS) * time.Millisecond)
-		return nil
+		t := time.NewTimer(time.Duration(step.DurationMS) * time.Millisecond)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	case workflow.StepTypeTTS:
 		if a.mina == nil {
 			return errNoToken
@@ -106,11 +169,11 @@ func (a *App) runStep(step workflow.Step) error {
 		if did == "" {
 			return errNoDevice
 		}
-		deviceID, err := a.mina.GetMinaDeviceID(did)
+		deviceID, err := a.mina.GetMinaDeviceIDContext(ctx, did)
 		if err != nil {
 			return err
 		}
-		_, err = a.mina.TextToSpeech(deviceID, step.Text)
+		_, err = a.mina.TextToSpeechContext(ctx, deviceID, step.Text)
 		return err
 	case workflow.StepTypePlayURL:
 		if a.mina == nil {
@@ -120,24 +183,67 @@ func (a *App) runStep(step workflow.Step) error {
 		if did == "" {
 			return errNoDevice
 		}
-		deviceID, err := a.mina.GetMinaDeviceID(did)
+		deviceID, err := a.mina.GetMinaDeviceIDContext(ctx, did)
 		if err != nil {
 			return err
 		}
-		_, err = a.mina.PlayByURL(deviceID, step.URL, 2)
+		_, err = a.mina.PlayByURLContext(ctx, deviceID, step.URL, 2)
 		return err
 	case workflow.StepTypeMiIO:
-		if a.miio == nil {
+		if a.deviceAPI == nil {
 			return errNoToken
 		}
 		text := strings.TrimSpace(step.MiIOText)
 		if text == "" {
 			return nil
 		}
+		if step.Room != "" || step.Model != "" {
+			return a.runMiIOEach(ctx, step, text)
+		}
 		did := a.resolveDID(step)
-		_, err := miiocommand.Run(a.miio, did, text, "web ")
+		_, err := miiocommand.RunContext(ctx, a.deviceAPI, did, text, "web ")
+		return err
+	case workflow.StepTypeScene:
+		if a.deviceAPI == nil {
+			return errNoToken
+		}
+		if strings.TrimSpace(step.Scene) == "" {
+			return fmt.Errorf("scene step requires scene id or name")
+		}
+		_, err := a.deviceAPI.RunSceneContext(ctx, step.Scene)
 		return err
 	default:
 		return nil
 	}
 }
+
+// runGroupStep 对群组成员并发执行同一步骤（Device 替换为成员 did）；miio 步骤由 miiocommand 处理群组。
+func (a *App) runGroupStep(ctx context.Context, name string, step workflow.Step) error {
+	if a.deviceAPI == nil {
+		return errNoToken
+	}
+	_, err := a.deviceAPI.FanOutContext(ctx, name, func(ctx context.Context, d *device.Device) (interface{}, error) {
+		s := step
+		s.Device = d.DID
+		return nil, a.runStep(ctx, s)
+	})
+	return err
+}
+
+// runMiIOEach 对 step.Room/step.Model 匹配的每个设备执行 miio 命令，如“客厅所有灯”。
+func (a *App) runMiIOEach(ctx context.Context, step workflow.Step, text string) error {
+	list, err := a.deviceAPI.FindContext(ctx, device.Filter{Room: step.Room, Model: step.Model})
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return fmt.Errorf("no device matches room=%q model=%q", step.Room, step.Model)
+	}
+	var errs []error
+	for _, d := range list {
+		if _, err := miiocommand.RunContext(ctx, a.deviceAPI, d.DID, text, "web "); err != nil {
+			errs = append(errs, fmt.Errorf("%s(%s): %w", d.Name, d.DID, err))
+		}
+	}
+	return errors.Join(errs...)
+}